@@ -98,7 +98,7 @@ func main() {
 	ipProvider := &ip.DynamicProvider{Config: safeCfg}
 
 	// 创建 DNS 更新器（传入数据库）
-	dnsUpdater := &dns.CloudflareUpdater{Config: safeCfg, DB: database}
+	dnsUpdater := &dns.Manager{Config: safeCfg, DB: database}
 
 	// 创建 Web 服务器（传入数据库、DNS 更新器、IP 提供者和启动时间）
 	srv := server.New(safeCfg, database, dnsUpdater, ipProvider, startTime)
@@ -106,6 +106,9 @@ func main() {
 	// 启动 IP 监控协程
 	go monitorIP(ipProvider, dnsUpdater, srv, database, safeCfg)
 
+	// 启动 DNS 定期校准协程
+	go reconcileDNS(dnsUpdater, srv, database, safeCfg)
+
 	// 使用错误通道来同步服务器启动失败
 	serverErr := make(chan error, 1)
 
@@ -252,8 +255,11 @@ networks:
 	log.Println("   2. 执行: docker compose up -d")
 }
 
+// natRefreshInterval 定期重新探测 NAT 行为的间隔（运营商可能在 IP 不变时调整 NAT）
+const natRefreshInterval = time.Hour
+
 // monitorIP 定期检查 IP 变化并更新 DNS
-func monitorIP(provider ip.Provider, updater *dns.CloudflareUpdater, srv *server.Server, database *db.DB, safeCfg *config.SafeConfig) {
+func monitorIP(provider ip.Provider, updater *dns.Manager, srv *server.Server, database *db.DB, safeCfg *config.SafeConfig) {
 	// 从数据库获取最后记录的 IP，避免每次重启都触发 IP 变化
 	var lastIP string
 	if history, err := database.GetRecentIPHistory(1); err == nil && len(history) > 0 {
@@ -261,6 +267,9 @@ func monitorIP(provider ip.Provider, updater *dns.CloudflareUpdater, srv *server
 		log.Printf("📜 从历史记录恢复上次 IP: %s", lastIP)
 	}
 
+	// 上次探测 NAT 行为的时间，零值表示需要探测（启动时、配置变更后）
+	var lastNATCheck time.Time
+
 	for {
 		cfg := safeCfg.Get()
 		
@@ -276,8 +285,8 @@ func monitorIP(provider ip.Provider, updater *dns.CloudflareUpdater, srv *server
 				// 检查当前启用的 provider 类型
 				for _, p := range cfg.IPProviders {
 					if p.Enabled {
-						// Router SSH 和本地接口可以更频繁检查（最小 1 秒）
-						if p.Type == "router_ssh" || p.Type == "interface" {
+						// Router SSH、本地接口和设备推送可以更频繁检查（最小 1 秒）
+						if p.Type == "router_ssh" || p.Type == "interface" || p.Type == "dyndns_push" {
 							minInterval = 1 * time.Second
 							break
 						}
@@ -316,6 +325,23 @@ func monitorIP(provider ip.Provider, updater *dns.CloudflareUpdater, srv *server
 		srv.SetCurrentIP(currentIP)
 		srv.SetCurrentSource(source)
 
+		// rrset 模式的记录需要所有线路的地址：其他线路的 IP 变化时同样触发更新
+		ipsChanged := false
+		if multi, ok := provider.(ip.MultiProvider); ok && cfg.HasRRSetRecords() {
+			if ips, err := multi.GetAllIPs(); err != nil {
+				log.Printf("⚠️  获取所有线路 IP 失败: %v", err)
+			} else {
+				ipsChanged = updater.SetIPs(ips)
+			}
+		}
+
+		if currentIP == lastIP && ipsChanged {
+			log.Printf("🔄 检测到线路 IP 组变化，更新 rrset 记录")
+			if _, err := updater.UpdateIP(currentIP); err != nil {
+				log.Printf("❌ DNS 更新失败: %v", err)
+			}
+		}
+
 		if currentIP != lastIP {
 			log.Printf("🔄 检测到 IP 变化: %s -> %s (Source: %s)", lastIP, currentIP, source)
 			
@@ -324,9 +350,9 @@ func monitorIP(provider ip.Provider, updater *dns.CloudflareUpdater, srv *server
 				log.Printf("⚠️  记录 IP 历史失败: %v", err)
 			}
 			
-			if err := updater.UpdateIP(currentIP); err != nil {
+			if _, err := updater.UpdateIP(currentIP); err != nil {
 				log.Printf("❌ DNS 更新失败: %v", err)
-				// DNS 更新失败已在 CloudflareUpdater 中记录
+				// 每条记录的结果已在 dns.Manager 中记录
 			} else {
 				log.Printf("✅ DNS 记录已更新为: %s", currentIP)
 			}
@@ -336,6 +362,13 @@ func monitorIP(provider ip.Provider, updater *dns.CloudflareUpdater, srv *server
 			log.Printf("📡 已广播 IP 变化到 %d 个客户端", srv.Hub.ClientCount())
 
 			lastIP = currentIP
+			lastNATCheck = time.Time{} // IP 变化时重新探测
+		}
+
+		// 启动、配置变更、IP 变化后以及每隔 natRefreshInterval 探测 NAT 行为（未启用时立即返回）
+		if time.Since(lastNATCheck) >= natRefreshInterval {
+			lastNATCheck = time.Now()
+			go srv.RefreshNATBehavior()
 		}
 
 		// 使用 timer 和 select 实现可中断的 sleep
@@ -352,7 +385,34 @@ func monitorIP(provider ip.Provider, updater *dns.CloudflareUpdater, srv *server
 				default:
 				}
 			}
+			lastNATCheck = time.Time{} // 可能启用了 nat_discovery 或更换了 STUN 服务器
 			log.Println("⚡ 收到配置更新，立即触发重新检查...")
 		}
 	}
 }
+
+// reconcileDNS 按 dns_update 间隔定期将 DNS 记录与当前 IP 和记录配置比对，修复在服务商控制台被修改或删除的记录
+func reconcileDNS(updater *dns.Manager, srv *server.Server, database *db.DB, safeCfg *config.SafeConfig) {
+	for {
+		interval := time.Minute
+		if d, err := config.ParseExtendedDuration(safeCfg.Get().Intervals.DNSUpdate); err == nil && d >= 10*time.Second {
+			interval = d
+		}
+		time.Sleep(interval)
+
+		// 尚未获取到 IP 时跳过，由 IP 监控在首次获取后完成同步
+		currentIP := srv.GetCurrentIP()
+		if currentIP == "" {
+			continue
+		}
+
+		report, err := updater.Reconcile(currentIP)
+		duration := int(report.Duration)
+		if err != nil {
+			log.Printf("❌ DNS 校准失败: %v (%s)", err, report.Summary())
+			database.AddCheckLog("dns", false, report.Summary(), duration, err.Error())
+			continue
+		}
+		database.AddCheckLog("dns", true, report.Summary(), duration, "")
+	}
+}
@@ -7,19 +7,96 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"time"
 
 	"idrd/db"
 )
 
 // AppConfig 应用程序总配置
 type AppConfig struct {
-	Server             ServerConfig        `yaml:"server" json:"server"`
-	IPProviders        []IPProviderConfig  `yaml:"ip_providers" json:"ip_providers"`
-	CloudflareAccounts []CloudflareAccount `yaml:"cloudflare_accounts" json:"cloudflare_accounts"`
-	Intervals          IntervalsConfig     `yaml:"intervals" json:"intervals"`
-	IPv6               IPv6Config          `yaml:"ipv6" json:"ipv6"`
-	
+	Server       ServerConfig       `yaml:"server" json:"server"`
+	IPProviders  []IPProviderConfig `yaml:"ip_providers" json:"ip_providers"`
+	// DNSAccounts 沿用 cloudflare_accounts 键名，兼容已有前端和导出的配置文件
+	DNSAccounts  []DNSAccount       `yaml:"cloudflare_accounts" json:"cloudflare_accounts"`
+	Intervals    IntervalsConfig    `yaml:"intervals" json:"intervals"`
+	IPv6         IPv6Config         `yaml:"ipv6" json:"ipv6"`
+	DynDNSServer DynDNSServerConfig `yaml:"dyndns_server" json:"dyndns_server"`
+	// DryRun 演练模式：只计算并记录计划的 DNS 变更，不调用服务商写接口
+	DryRun       bool               `yaml:"dry_run" json:"dry_run"`
+	Ownership    OwnershipConfig    `yaml:"ownership" json:"ownership"`
+	// OrphanPolicy 记录从配置中移除后如何处理 idrd 创建或管理过的记录，为空时等同 ask
+	OrphanPolicy string             `yaml:"orphan_policy,omitempty" json:"orphan_policy,omitempty"`
+	Propagation  PropagationConfig  `yaml:"propagation" json:"propagation"`
+}
+
+// PropagationConfig 更新后的传播检查配置
+// 启用后 idrd 直接查询 zone 的权威服务器，直到所有服务器都返回新地址，并检查公共解析器是否仍缓存旧地址
+type PropagationConfig struct {
+	Enabled   bool     `yaml:"enabled" json:"enabled"`
+	Resolvers []string `yaml:"resolvers,omitempty" json:"resolvers,omitempty"` // 检查缓存的公共解析器（IP 或 IP:端口），为空时使用默认值
+	Timeout   string   `yaml:"timeout,omitempty" json:"timeout,omitempty"`     // 等待所有权威服务器一致的最长时间，为空时使用默认值
+}
+
+// DefaultPropagationResolvers 未配置 resolvers 时检查的公共解析器
+var DefaultPropagationResolvers = []string{"1.1.1.1", "8.8.8.8"}
+
+// DefaultPropagationTimeout 未配置 timeout 时等待权威服务器一致的时间
+const DefaultPropagationTimeout = 5 * time.Minute
+
+// ResolverList 返回要检查的公共解析器（为空时返回默认值）
+func (p PropagationConfig) ResolverList() []string {
+	if len(p.Resolvers) == 0 {
+		return DefaultPropagationResolvers
+	}
+	return p.Resolvers
+}
+
+// TimeoutDuration 返回等待权威服务器一致的时间（为空或无效时返回默认值）
+func (p PropagationConfig) TimeoutDuration() time.Duration {
+	if d, err := ParseExtendedDuration(p.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultPropagationTimeout
+}
+
+// 孤儿记录处理策略
+const (
+	OrphanPolicyAsk    = "ask"    // 列出孤儿记录，由用户确认后删除
+	OrphanPolicyDelete = "delete" // 完整同步后自动删除
+	OrphanPolicyKeep   = "keep"   // 保留服务商中的记录，只停止跟踪
+)
 
+// OwnershipConfig 记录所有权配置
+// 启用后 idrd 用 Cloudflare 记录备注或伴随的 _idrd TXT 记录标记自己创建的记录，
+// 不修改没有标记的记录（除非记录或 zone 设置了 adopt）
+type OwnershipConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	OwnerID string `yaml:"owner_id,omitempty" json:"owner_id,omitempty"` // 区分共用 zone 的多个 idrd 实例，为空时使用 idrd
+}
+
+// HasRRSetRecords 是否有记录使用 rrset 模式（需要获取所有 IP 提供者的地址）
+func (c *AppConfig) HasRRSetRecords() bool {
+	for _, account := range c.DNSAccounts {
+		for _, zone := range account.Zones {
+			for _, record := range zone.Records {
+				if record.RRSet {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// DefaultOwnerID 未配置 owner_id 时使用的所有者标识
+const DefaultOwnerID = "idrd"
+
+// Owner 返回所有者标识（为空时返回默认值）
+func (o OwnershipConfig) Owner() string {
+	if o.OwnerID == "" {
+		return DefaultOwnerID
+	}
+	return o.OwnerID
 }
 
 // ServerConfig 服务器配置
@@ -36,17 +113,58 @@ type IPProviderConfig struct {
 	Properties    map[string]string `yaml:"properties" json:"properties"` // 存储特定类型的配置
 }
 
-// CloudflareAccount Cloudflare 账户配置
-type CloudflareAccount struct {
-	Name     string `yaml:"name" json:"name"`
-	APIToken string `yaml:"api_token" json:"api_token"`
-	Zones    []Zone `yaml:"zones" json:"zones"`
+// DNSAccount DNS 服务商账户配置
+type DNSAccount struct {
+	Name       string            `yaml:"name" json:"name"`
+	Provider   string            `yaml:"provider" json:"provider"` // DNS 服务商类型，为空时视为 cloudflare
+	APIToken   string            `yaml:"api_token" json:"api_token"`
+	Properties map[string]string `yaml:"properties,omitempty" json:"properties,omitempty"` // 服务商特定配置
+	Zones      []Zone            `yaml:"zones" json:"zones"`
+	// Concurrency 同步时并行处理的 zone 数，0 表示默认值（实际并发不超过服务商限制）
+	Concurrency int `yaml:"concurrency,omitempty" json:"concurrency,omitempty"`
+}
+
+// DefaultAccountConcurrency 未设置 concurrency 的账户并行处理的 zone 数
+const DefaultAccountConcurrency = 4
+
+// MaxAccountConcurrency 单个账户允许设置的最大并发数
+const MaxAccountConcurrency = 16
+
+// DefaultDNSProvider 未指定 provider 的账户使用的服务商
+const DefaultDNSProvider = "cloudflare"
+
+// ProviderName 返回账户的服务商类型（为空时返回默认值）
+func (a DNSAccount) ProviderName() string {
+	if a.Provider == "" {
+		return DefaultDNSProvider
+	}
+	return a.Provider
 }
 
 // Zone 域名区域配置
+// Proxied、TTL、Comment、Tags 为 zone 内记录的默认值，记录未设置时继承
 type Zone struct {
-	ZoneName string   `yaml:"zone_name" json:"zone_name"`
-	Records  []string `yaml:"records" json:"records"`
+	ZoneName string         `yaml:"zone_name" json:"zone_name"`
+	Records  []RecordConfig `yaml:"records" json:"records"`
+	Proxied  *bool          `yaml:"proxied,omitempty" json:"proxied,omitempty"`
+	TTL      int            `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+	Comment  string         `yaml:"comment,omitempty" json:"comment,omitempty"`
+	Tags     []string       `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Adopt    bool           `yaml:"adopt,omitempty" json:"adopt,omitempty"` // 接管 zone 内没有所有权标记的已有记录
+}
+
+// DynDNSServerConfig dyndns2 推送服务端配置（路由器通过 /nic/update 推送 IP）
+type DynDNSServerConfig struct {
+	Enabled bool           `yaml:"enabled" json:"enabled"`
+	Devices []DynDNSDevice `yaml:"devices" json:"devices"`
+}
+
+// DynDNSDevice 推送设备，凭据独立于管理 API Key
+type DynDNSDevice struct {
+	Name      string   `yaml:"name" json:"name"`
+	Username  string   `yaml:"username" json:"username"`
+	Password  string   `yaml:"password" json:"password"`
+	Hostnames []string `yaml:"hostnames" json:"hostnames"` // 允许更新的主机名，为空表示所有已配置的记录
 }
 
 // IntervalsConfig 时间间隔配置
@@ -111,6 +229,29 @@ func SaveConfig(cfg *AppConfig, database *db.DB) error {
 		return err
 	}
 
+	dyndnsJSON, _ := json.Marshal(cfg.DynDNSServer)
+	if err := database.SetSetting(db.SettingKeyDynDNSServer, string(dyndnsJSON)); err != nil {
+		return err
+	}
+
+	if err := database.SetSetting(db.SettingKeyDryRun, strconv.FormatBool(cfg.DryRun)); err != nil {
+		return err
+	}
+
+	ownershipJSON, _ := json.Marshal(cfg.Ownership)
+	if err := database.SetSetting(db.SettingKeyOwnership, string(ownershipJSON)); err != nil {
+		return err
+	}
+
+	if err := database.SetSetting(db.SettingKeyOrphanPolicy, cfg.OrphanPolicy); err != nil {
+		return err
+	}
+
+	propagationJSON, _ := json.Marshal(cfg.Propagation)
+	if err := database.SetSetting(db.SettingKeyPropagation, string(propagationJSON)); err != nil {
+		return err
+	}
+
 	// 2. 保存 IP Providers
 	var dbProviders []db.IPProviderConfig
 	for _, p := range cfg.IPProviders {
@@ -125,17 +266,21 @@ func SaveConfig(cfg *AppConfig, database *db.DB) error {
 		return err
 	}
 
-	// 3. 保存 Cloudflare Accounts
-	var dbAccounts []db.CloudflareAccountConfig
-	for _, acc := range cfg.CloudflareAccounts {
+	// 3. 保存 DNS Accounts
+	var dbAccounts []db.DNSAccountConfig
+	for _, acc := range cfg.DNSAccounts {
 		zonesJSON, _ := json.Marshal(acc.Zones)
-		dbAccounts = append(dbAccounts, db.CloudflareAccountConfig{
-			Name:     acc.Name,
-			APIToken: acc.APIToken,
-			Zones:    string(zonesJSON),
+		propsJSON, _ := json.Marshal(acc.Properties)
+		dbAccounts = append(dbAccounts, db.DNSAccountConfig{
+			Name:        acc.Name,
+			Provider:    acc.ProviderName(),
+			APIToken:    acc.APIToken,
+			Properties:  string(propsJSON),
+			Zones:       string(zonesJSON),
+			Concurrency: acc.Concurrency,
 		})
 	}
-	if err := database.SaveCloudflareAccounts(dbAccounts); err != nil {
+	if err := database.SaveDNSAccounts(dbAccounts); err != nil {
 		return err
 	}
 
@@ -180,6 +325,27 @@ func LoadConfig(database *db.DB) (*AppConfig, error) {
 	updateAAAAStr, _ := database.GetSetting(db.SettingKeyUpdateAAAA)
 	cfg.IPv6.UpdateAAAARecords, _ = strconv.ParseBool(updateAAAAStr)
 
+	dyndnsJSON, _ := database.GetSetting(db.SettingKeyDynDNSServer)
+	if dyndnsJSON != "" {
+		json.Unmarshal([]byte(dyndnsJSON), &cfg.DynDNSServer)
+	}
+
+	dryRunStr, _ := database.GetSetting(db.SettingKeyDryRun)
+	cfg.DryRun, _ = strconv.ParseBool(dryRunStr)
+
+	ownershipJSON, _ := database.GetSetting(db.SettingKeyOwnership)
+	if ownershipJSON != "" {
+		json.Unmarshal([]byte(ownershipJSON), &cfg.Ownership)
+	}
+
+	cfg.OrphanPolicy, _ = database.GetSetting(db.SettingKeyOrphanPolicy)
+
+	// 传播检查需要显式启用（会向权威服务器和公共解析器发送查询）
+	propagationJSON, _ := database.GetSetting(db.SettingKeyPropagation)
+	if propagationJSON != "" {
+		json.Unmarshal([]byte(propagationJSON), &cfg.Propagation)
+	}
+
 	// 2. 加载 IP Providers
 	cfg.IPProviders = []IPProviderConfig{} // 初始化为空切片，避免 JSON 输出 null
 	dbProviders, err := database.GetAllIPProviders()
@@ -196,19 +362,24 @@ func LoadConfig(database *db.DB) (*AppConfig, error) {
 		})
 	}
 
-	// 3. 加载 Cloudflare Accounts
-	cfg.CloudflareAccounts = []CloudflareAccount{} // 初始化为空切片，避免 JSON 输出 null
-	dbAccounts, err := database.GetAllCloudflareAccounts()
+	// 3. 加载 DNS Accounts
+	cfg.DNSAccounts = []DNSAccount{} // 初始化为空切片，避免 JSON 输出 null
+	dbAccounts, err := database.GetAllDNSAccounts()
 	if err != nil {
 		return nil, err
 	}
 	for _, acc := range dbAccounts {
 		var zones []Zone
+		var props map[string]string
 		json.Unmarshal([]byte(acc.Zones), &zones)
-		cfg.CloudflareAccounts = append(cfg.CloudflareAccounts, CloudflareAccount{
-			Name:     acc.Name,
-			APIToken: acc.APIToken,
-			Zones:    zones,
+		json.Unmarshal([]byte(acc.Properties), &props)
+		cfg.DNSAccounts = append(cfg.DNSAccounts, DNSAccount{
+			Name:        acc.Name,
+			Provider:    acc.Provider,
+			APIToken:    acc.APIToken,
+			Properties:  props,
+			Zones:       zones,
+			Concurrency: acc.Concurrency,
 		})
 	}
 
@@ -263,7 +434,7 @@ func DefaultConfig() *AppConfig {
 				},
 			},
 		},
-		CloudflareAccounts: []CloudflareAccount{},
+		DNSAccounts: []DNSAccount{},
 		Intervals: IntervalsConfig{
 			IPCheck:          "5m",
 			DNSUpdate:        "1m",
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RecordConfig zone 内的一条记录配置
+// 配置中既可以写成字符串（仅记录名，如 "www"），也可以写成对象以设置记录级属性
+type RecordConfig struct {
+	Name    string   `yaml:"name" json:"name"`                           // 记录名，@ 表示根域名
+	Proxied *bool    `yaml:"proxied,omitempty" json:"proxied,omitempty"` // 是否经 Cloudflare 代理，nil 表示继承 zone 设置
+	TTL     int      `yaml:"ttl,omitempty" json:"ttl,omitempty"`         // 0 表示继承 zone 设置，Cloudflare 中 1 表示 Auto
+	Comment string   `yaml:"comment,omitempty" json:"comment,omitempty"`
+	Tags    []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Adopt   bool     `yaml:"adopt,omitempty" json:"adopt,omitempty"` // 启用所有权时接管没有标记的已有记录
+
+	// Select 同名存在多条记录（如轮询）时选择要管理的一条，为空时管理第一条
+	Select *RecordSelector `yaml:"select,omitempty" json:"select,omitempty"`
+	// RRSet 管理同名同类型的整组记录：使其内容与当前所有 IP（每个启用的 IP 提供者）完全一致
+	RRSet bool `yaml:"rrset,omitempty" json:"rrset,omitempty"`
+}
+
+// RecordSelector 按记录 ID、备注或标签选择记录，设置多个字段时需同时满足
+type RecordSelector struct {
+	ID      string `yaml:"id,omitempty" json:"id,omitempty"`           // 服务商记录 ID（如 Cloudflare record ID）
+	Comment string `yaml:"comment,omitempty" json:"comment,omitempty"` // 记录备注，新建时写入该备注
+	Tag     string `yaml:"tag,omitempty" json:"tag,omitempty"`         // 记录标签，新建时写入该标签
+}
+
+// recordConfigFields 用于编解码对象形式，避免递归调用自定义方法
+type recordConfigFields RecordConfig
+
+// plain 记录是否只有名称（可以写回为字符串形式）
+func (r RecordConfig) plain() bool {
+	return r.Proxied == nil && r.TTL == 0 && r.Comment == "" && len(r.Tags) == 0 && !r.Adopt && r.Select == nil && !r.RRSet
+}
+
+// UnmarshalJSON 同时接受字符串和对象形式
+func (r *RecordConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*r = RecordConfig{Name: name}
+		return nil
+	}
+	var fields recordConfigFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return fmt.Errorf("record must be a string or an object: %w", err)
+	}
+	*r = RecordConfig(fields)
+	return nil
+}
+
+// MarshalJSON 未设置记录级属性时输出字符串形式，保持旧配置格式不变
+func (r RecordConfig) MarshalJSON() ([]byte, error) {
+	if r.plain() {
+		return json.Marshal(r.Name)
+	}
+	return json.Marshal(recordConfigFields(r))
+}
+
+// UnmarshalYAML 同时接受字符串和对象形式
+func (r *RecordConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*r = RecordConfig{Name: node.Value}
+		return nil
+	}
+	var fields recordConfigFields
+	if err := node.Decode(&fields); err != nil {
+		return fmt.Errorf("record must be a string or a mapping: %w", err)
+	}
+	*r = RecordConfig(fields)
+	return nil
+}
+
+// MarshalYAML 未设置记录级属性时输出字符串形式
+func (r RecordConfig) MarshalYAML() (interface{}, error) {
+	if r.plain() {
+		return r.Name, nil
+	}
+	return recordConfigFields(r), nil
+}
+
+// Effective 返回继承 zone 默认值后的记录配置
+func (z Zone) Effective(r RecordConfig) RecordConfig {
+	if r.Proxied == nil {
+		r.Proxied = z.Proxied
+	}
+	if r.TTL == 0 {
+		r.TTL = z.TTL
+	}
+	if r.Comment == "" {
+		r.Comment = z.Comment
+	}
+	if r.Tags == nil {
+		r.Tags = z.Tags
+	}
+	r.Adopt = r.Adopt || z.Adopt
+	return r
+}
@@ -9,10 +9,10 @@ import (
 	"time"
 )
 
-// parseExtendedDuration 解析扩展的时间间隔格式
+// ParseExtendedDuration 解析扩展的时间间隔格式
 // Go 标准库的 time.ParseDuration 不支持天(d)和周(w)，此函数添加支持
 // 支持格式：30s, 5m, 1h, 24h, 7d, 2w
-func parseExtendedDuration(s string) (time.Duration, error) {
+func ParseExtendedDuration(s string) (time.Duration, error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
 		return 0, fmt.Errorf("empty duration")
@@ -58,19 +58,59 @@ func ValidateConfig(cfg *AppConfig) error {
 	}
 
 	// 验证 IP 提供者
-	for i, provider := range cfg.IPProviders {
-		if err := validateIPProvider(&provider); err != nil {
+	for i := range cfg.IPProviders {
+		if err := validateIPProvider(&cfg.IPProviders[i]); err != nil {
 			return fmt.Errorf("ip_provider[%d]: %w", i, err)
 		}
 	}
 
-	// 验证 Cloudflare 账户（允许为空，用户可能只想监控 IP 不更新 DNS）
-	for i, account := range cfg.CloudflareAccounts {
-		if err := validateCloudflareAccount(&account); err != nil {
-			return fmt.Errorf("cloudflare_account[%d]: %w", i, err)
+	// 验证 DNS 账户（允许为空，用户可能只想监控 IP 不更新 DNS）
+	for i := range cfg.DNSAccounts {
+		if err := validateDNSAccount(&cfg.DNSAccounts[i]); err != nil {
+			return fmt.Errorf("dns_account[%d] (%s): %w", i, cfg.DNSAccounts[i].ProviderName(), err)
 		}
 	}
 
+	// 验证 dyndns2 推送设备
+	if err := validateDynDNSServer(&cfg.DynDNSServer); err != nil {
+		return fmt.Errorf("dyndns_server: %w", err)
+	}
+
+	// 验证所有者标识（写入 TXT 记录和备注，只允许简单字符）
+	if cfg.Ownership.OwnerID != "" && !ownerIDRegex.MatchString(cfg.Ownership.OwnerID) {
+		return fmt.Errorf("ownership: invalid owner_id %s (letters, digits, '-' and '_', up to 32 characters)", cfg.Ownership.OwnerID)
+	}
+
+	switch cfg.OrphanPolicy {
+	case "", OrphanPolicyAsk, OrphanPolicyDelete, OrphanPolicyKeep:
+	default:
+		return fmt.Errorf("invalid orphan_policy %s (must be ask, delete or keep)", cfg.OrphanPolicy)
+	}
+
+	if err := validatePropagation(&cfg.Propagation); err != nil {
+		return fmt.Errorf("propagation: %w", err)
+	}
+
+	return nil
+}
+
+func validateDynDNSServer(d *DynDNSServerConfig) error {
+	usernames := make(map[string]bool)
+	for i, dev := range d.Devices {
+		if dev.Name == "" {
+			return fmt.Errorf("device[%d]: name required", i)
+		}
+		if dev.Username == "" || dev.Password == "" {
+			return fmt.Errorf("device[%d]: username and password required", i)
+		}
+		if len(dev.Password) < 8 {
+			return fmt.Errorf("device[%d]: password too short (minimum 8 characters)", i)
+		}
+		if usernames[dev.Username] {
+			return fmt.Errorf("device[%d]: duplicate username %s", i, dev.Username)
+		}
+		usernames[dev.Username] = true
+	}
 	return nil
 }
 
@@ -95,7 +135,7 @@ func validateServerConfig(s *ServerConfig) error {
 func validateIntervalsConfig(i *IntervalsConfig) error {
 	// 验证 IP 检查间隔
 	if i.IPCheck != "" {
-		d, err := parseExtendedDuration(i.IPCheck)
+		d, err := ParseExtendedDuration(i.IPCheck)
 		if err != nil {
 			return fmt.Errorf("invalid ip_check interval %s: %w", i.IPCheck, err)
 		}
@@ -106,7 +146,7 @@ func validateIntervalsConfig(i *IntervalsConfig) error {
 
 	// 验证 DNS 更新间隔
 	if i.DNSUpdate != "" {
-		d, err := parseExtendedDuration(i.DNSUpdate)
+		d, err := ParseExtendedDuration(i.DNSUpdate)
 		if err != nil {
 			return fmt.Errorf("invalid dns_update interval %s: %w", i.DNSUpdate, err)
 		}
@@ -117,7 +157,7 @@ func validateIntervalsConfig(i *IntervalsConfig) error {
 
 	// 验证历史保留时间
 	if i.HistoryRetention != "" {
-		d, err := parseExtendedDuration(i.HistoryRetention)
+		d, err := ParseExtendedDuration(i.HistoryRetention)
 		if err != nil {
 			return fmt.Errorf("invalid history_retention %s: %w", i.HistoryRetention, err)
 		}
@@ -129,6 +169,17 @@ func validateIntervalsConfig(i *IntervalsConfig) error {
 	return nil
 }
 
+// IPProviderValidator 校验某一类型 IP 提供者的属性（可修改 props 以填充默认值）
+type IPProviderValidator func(props map[string]string) error
+
+// ipProviderValidators 由 ip 包在注册提供者类型时填充，避免 config 依赖 ip 包
+var ipProviderValidators = map[string]IPProviderValidator{}
+
+// RegisterIPProviderValidator 注册 IP 提供者类型的校验函数
+func RegisterIPProviderValidator(providerType string, v IPProviderValidator) {
+	ipProviderValidators[providerType] = v
+}
+
 func validateIPProvider(p *IPProviderConfig) error {
 	if p.Type == "" {
 		return fmt.Errorf("provider type cannot be empty")
@@ -139,95 +190,76 @@ func validateIPProvider(p *IPProviderConfig) error {
 		return nil
 	}
 
-	switch p.Type {
-	case "stun":
-		server := p.Properties["server"]
-		if server == "" {
-			// 使用默认值
-			p.Properties["server"] = "stun.l.google.com:19302"
-			return nil
-		}
-		// 验证 server 格式 (host:port)
-		if _, _, err := net.SplitHostPort(server); err != nil {
-			return fmt.Errorf("invalid STUN server address %s: %w", server, err)
-		}
+	validate, ok := ipProviderValidators[p.Type]
+	if !ok {
+		return fmt.Errorf("unknown provider type: %s", p.Type)
+	}
 
-	case "router_ssh":
-		// 验证必需字段
-		host := p.Properties["host"]
-		if host == "" {
-			return fmt.Errorf("router host required")
-		}
-		// 只验证格式，不做 DNS 查询（网络可能未就绪）
-		// 接受 IP 地址或域名格式
-		if net.ParseIP(host) == nil {
-			// 不是 IP，验证是否是合理的域名格式（包含字母或点）
-			if !strings.Contains(host, ".") && !strings.ContainsAny(host, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
-				return fmt.Errorf("invalid router host %s (must be IP or domain)", host)
-			}
-		}
+	if p.Properties == nil {
+		p.Properties = map[string]string{}
+	}
+	return validate(p.Properties)
+}
 
-		user := p.Properties["user"]
-		if user == "" {
-			return fmt.Errorf("router user required")
-		}
+var domainRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
+var ownerIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+var subdomainRegex = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
 
-		// 验证端口
-		port := p.Properties["port"]
-		if port != "" {
-			var portNum int
-			if _, err := fmt.Sscanf(port, "%d", &portNum); err != nil {
-				return fmt.Errorf("invalid port %s: %w", port, err)
-			}
-			if portNum < 1 || portNum > 65535 {
-				return fmt.Errorf("invalid port %d (must be 1-65535)", portNum)
+// validatePropagation 校验传播检查配置：解析器必须是 IP 或 IP:端口，超时 10 秒到 1 小时
+func validatePropagation(p *PropagationConfig) error {
+	for _, r := range p.Resolvers {
+		host := r
+		if h, port, err := net.SplitHostPort(r); err == nil {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return fmt.Errorf("invalid resolver %s", r)
 			}
+			host = h
 		}
-
-		// 验证路由器类型（必填）
-		routerType := p.Properties["type"]
-		if routerType == "" {
-			return fmt.Errorf("router type required (must be 'routeros' or 'openwrt')")
-		}
-		if routerType != "routeros" && routerType != "openwrt" {
-			return fmt.Errorf("unsupported router type: %s (must be 'routeros' or 'openwrt')", routerType)
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("invalid resolver %s (must be an IP address)", r)
 		}
-
-		// 验证接口名称（必填）
-		iface := p.Properties["interface"]
-		if iface == "" {
-			return fmt.Errorf("router interface required (e.g., 'wan' for OpenWrt, 'ether1' for RouterOS)")
+	}
+	if p.Timeout != "" {
+		d, err := ParseExtendedDuration(p.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
 		}
-
-		// 验证认证方式：必须有密码或密钥之一
-		hasPassword := p.Properties["password"] != ""
-		hasKey := p.Properties["key"] != "" || p.Properties["key_path"] != ""
-		if !hasPassword && !hasKey {
-			return fmt.Errorf("router authentication required (password or key)")
+		if d < 10*time.Second || d > time.Hour {
+			return fmt.Errorf("timeout must be between 10s and 1h")
 		}
-
-	case "http", "interface":
-		// 预留给未来实现
-		return fmt.Errorf("provider type %s not yet implemented", p.Type)
-
-	default:
-		return fmt.Errorf("unknown provider type: %s", p.Type)
 	}
-
 	return nil
 }
 
-var domainRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}$`)
-var subdomainRegex = regexp.MustCompile(`^(\*\.)?([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
+// DNSAccountValidator 校验某一服务商账户的特定字段（凭据、properties 等）
+type DNSAccountValidator func(a *DNSAccount) error
+
+// dnsAccountValidators 由 dns 包在注册服务商时填充，避免 config 依赖 dns 包
+var dnsAccountValidators = map[string]DNSAccountValidator{}
+
+// RegisterDNSAccountValidator 注册 DNS 服务商账户的校验函数
+func RegisterDNSAccountValidator(provider string, v DNSAccountValidator) {
+	dnsAccountValidators[provider] = v
+}
 
-func validateCloudflareAccount(a *CloudflareAccount) error {
+func validateDNSAccount(a *DNSAccount) error {
 	if a.Name == "" {
 		return fmt.Errorf("account name cannot be empty")
 	}
 
-	// 如果 Token 是脱敏值，跳过长度检查（将在保存时回填）
-	if a.APIToken != "***" && a.APIToken != "" && len(a.APIToken) < 20 {
-		return fmt.Errorf("API token too short for account %s (minimum 20 characters)", a.Name)
+	if a.Provider == "" {
+		a.Provider = DefaultDNSProvider
+	}
+	validate, ok := dnsAccountValidators[a.Provider]
+	if !ok {
+		return fmt.Errorf("account %s: unknown DNS provider: %s", a.Name, a.Provider)
+	}
+	if err := validate(a); err != nil {
+		return fmt.Errorf("account %s: %w", a.Name, err)
+	}
+
+	if a.Concurrency < 0 || a.Concurrency > MaxAccountConcurrency {
+		return fmt.Errorf("account %s: concurrency must be between 0 and %d", a.Name, MaxAccountConcurrency)
 	}
 
 	if len(a.Zones) == 0 {
@@ -256,11 +288,31 @@ func validateZone(z *Zone, index int) error {
 		return fmt.Errorf("zone %s has no records configured", z.ZoneName)
 	}
 
-	for _, record := range z.Records {
+	if err := validateRecordSettings(z.TTL, z.Tags); err != nil {
+		return fmt.Errorf("zone %s: %w", z.ZoneName, err)
+	}
+
+	for _, rc := range z.Records {
+		record := rc.Name
 		if record == "" {
 			return fmt.Errorf("zone %s has empty record name", z.ZoneName)
 		}
 
+		if err := validateRecordSettings(rc.TTL, rc.Tags); err != nil {
+			return fmt.Errorf("zone %s, record %s: %w", z.ZoneName, record, err)
+		}
+		if rc.Select != nil {
+			if rc.RRSet {
+				return fmt.Errorf("zone %s, record %s: select and rrset cannot be used together", z.ZoneName, record)
+			}
+			if rc.Select.ID == "" && rc.Select.Comment == "" && rc.Select.Tag == "" {
+				return fmt.Errorf("zone %s, record %s: select requires id, comment or tag", z.ZoneName, record)
+			}
+			if rc.Select.Comment != "" && rc.Comment != "" && rc.Comment != rc.Select.Comment {
+				return fmt.Errorf("zone %s, record %s: comment conflicts with select.comment", z.ZoneName, record)
+			}
+		}
+
 		// @ 表示根域名，直接通过
 		if record == "@" {
 			continue
@@ -275,3 +327,15 @@ func validateZone(z *Zone, index int) error {
 	return nil
 }
 
+// validateRecordSettings 验证记录级属性（TTL 为 0 表示继承，1 表示 Cloudflare Auto）
+func validateRecordSettings(ttl int, tags []string) error {
+	if ttl < 0 || ttl > 86400 {
+		return fmt.Errorf("invalid ttl %d (must be between 0 and 86400, 0 uses the default)", ttl)
+	}
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return fmt.Errorf("tags cannot be empty")
+		}
+	}
+	return nil
+}
@@ -15,6 +15,11 @@ const (
 	SettingKeyHistoryRetention = "history_retention"  // 历史保留时间
 	SettingKeyIPv6Enabled      = "ipv6_enabled"       // IPv6 启用
 	SettingKeyUpdateAAAA       = "update_aaaa"        // 更新 AAAA 记录
+	SettingKeyDynDNSServer     = "dyndns_server"      // dyndns2 推送服务端配置 JSON
+	SettingKeyDryRun           = "dry_run"            // DNS 演练模式
+	SettingKeyOwnership        = "ownership"          // 记录所有权配置 JSON
+	SettingKeyOrphanPolicy     = "orphan_policy"      // 孤儿记录处理策略
+	SettingKeyPropagation      = "propagation"        // 传播检查配置 JSON
 )
 
 // IPProviderConfig 数据库中的 IP 提供商配置结构
@@ -25,12 +30,15 @@ type IPProviderConfig struct {
 	Properties string `json:"properties"` // JSON 字符串
 }
 
-// CloudflareAccountConfig 数据库中的 Cloudflare 账户配置结构
-type CloudflareAccountConfig struct {
-	ID       int64  `json:"id"`
-	Name     string `json:"name"`
-	APIToken string `json:"api_token"`
-	Zones    string `json:"zones"` // JSON 字符串
+// DNSAccountConfig 数据库中的 DNS 账户配置结构
+type DNSAccountConfig struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Provider    string `json:"provider"`
+	APIToken    string `json:"api_token"`
+	Properties  string `json:"properties"`  // JSON 字符串
+	Zones       string `json:"zones"`       // JSON 字符串
+	Concurrency int    `json:"concurrency"` // 并行同步的 zone 数，0 表示默认值
 }
 
 // -----------------------------------------------------------------------------
@@ -127,21 +135,21 @@ func (db *DB) SaveIPProviders(providers []IPProviderConfig) error {
 }
 
 // -----------------------------------------------------------------------------
-// Cloudflare Accounts Operations
+// DNS Accounts Operations
 // -----------------------------------------------------------------------------
 
-// GetAllCloudflareAccounts 获取所有 Cloudflare 账户配置
-func (db *DB) GetAllCloudflareAccounts() ([]CloudflareAccountConfig, error) {
-	rows, err := db.conn.Query("SELECT id, name, api_token, zones FROM cloudflare_accounts")
+// GetAllDNSAccounts 获取所有 DNS 账户配置
+func (db *DB) GetAllDNSAccounts() ([]DNSAccountConfig, error) {
+	rows, err := db.conn.Query("SELECT id, name, provider, api_token, properties, zones, concurrency FROM dns_accounts ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var accounts []CloudflareAccountConfig
+	var accounts []DNSAccountConfig
 	for rows.Next() {
-		var acc CloudflareAccountConfig
-		if err := rows.Scan(&acc.ID, &acc.Name, &acc.APIToken, &acc.Zones); err != nil {
+		var acc DNSAccountConfig
+		if err := rows.Scan(&acc.ID, &acc.Name, &acc.Provider, &acc.APIToken, &acc.Properties, &acc.Zones, &acc.Concurrency); err != nil {
 			return nil, err
 		}
 		accounts = append(accounts, acc)
@@ -149,8 +157,8 @@ func (db *DB) GetAllCloudflareAccounts() ([]CloudflareAccountConfig, error) {
 	return accounts, nil
 }
 
-// SaveCloudflareAccounts 清空并保存所有 Cloudflare 账户配置 (全量替换模式)
-func (db *DB) SaveCloudflareAccounts(accounts []CloudflareAccountConfig) error {
+// SaveDNSAccounts 清空并保存所有 DNS 账户配置 (全量替换模式)
+func (db *DB) SaveDNSAccounts(accounts []DNSAccountConfig) error {
 	tx, err := db.conn.Begin()
 	if err != nil {
 		return err
@@ -158,19 +166,19 @@ func (db *DB) SaveCloudflareAccounts(accounts []CloudflareAccountConfig) error {
 	defer tx.Rollback()
 
 	// 1. 清空旧数据
-	if _, err := tx.Exec("DELETE FROM cloudflare_accounts"); err != nil {
+	if _, err := tx.Exec("DELETE FROM dns_accounts"); err != nil {
 		return err
 	}
 
 	// 2. 插入新数据
-	stmt, err := tx.Prepare("INSERT INTO cloudflare_accounts (name, api_token, zones) VALUES (?, ?, ?)")
+	stmt, err := tx.Prepare("INSERT INTO dns_accounts (name, provider, api_token, properties, zones, concurrency) VALUES (?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
 	for _, acc := range accounts {
-		if _, err := stmt.Exec(acc.Name, acc.APIToken, acc.Zones); err != nil {
+		if _, err := stmt.Exec(acc.Name, acc.Provider, acc.APIToken, acc.Properties, acc.Zones, acc.Concurrency); err != nil {
 			return err
 		}
 	}
@@ -178,6 +186,31 @@ func (db *DB) SaveCloudflareAccounts(accounts []CloudflareAccountConfig) error {
 	return tx.Commit()
 }
 
+// migrateCloudflareAccounts 将旧版 cloudflare_accounts 表迁移到通用的 dns_accounts 表
+// 迁移成功后删除旧表，避免清空账户后被重复导入
+func (db *DB) migrateCloudflareAccounts() error {
+	var count int
+	err := db.conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'cloudflare_accounts'").Scan(&count)
+	if err != nil || count == 0 {
+		return err
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT OR IGNORE INTO dns_accounts (name, provider, api_token, properties, zones)
+		SELECT name, 'cloudflare', api_token, '{}', zones FROM cloudflare_accounts`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DROP TABLE cloudflare_accounts"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // -----------------------------------------------------------------------------
 // Helper: Helper to convert JSON
 // -----------------------------------------------------------------------------
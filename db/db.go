@@ -22,13 +22,22 @@ type DNSUpdate struct {
 	ID          int64     `json:"id"`
 	AccountName string    `json:"account_name"`
 	IP          string    `json:"ip"`
-	RecordType  string    `json:"record_type"` // "A" or "AAAA"
+	OldContent  string    `json:"old_content,omitempty"` // 修改前的内容
+	RecordType  string    `json:"record_type"`           // "A" or "AAAA"
 	Domain      string    `json:"domain"`
 	Success     bool      `json:"success"`
 	Error       string    `json:"error,omitempty"`
+	ErrorCode   string    `json:"error_code,omitempty"` // 服务商返回的错误码
+	Action      string    `json:"action,omitempty"`     // create, update, noop, conflict, error
+	DryRun      bool      `json:"dry_run"`              // 演练模式下的计划变更（未实际执行）
+	DurationMs  int64     `json:"duration_ms"`          // 同步耗时（毫秒）
+	Attempts    int       `json:"attempts"`             // 调用服务商的次数（含重试）
 	Timestamp   time.Time `json:"timestamp"`
 }
 
+// dnsUpdateColumns dns_updates 查询的列，与 scanDNSUpdates 对应
+const dnsUpdateColumns = "id, account_name, ip, old_content, record_type, domain, success, error, error_code, action, dry_run, duration_ms, attempts, timestamp"
+
 // ErrorLog 错误日志
 type ErrorLog struct {
 	ID        int64     `json:"id"`
@@ -102,6 +111,12 @@ func (db *DB) initialize() error {
 		domain TEXT NOT NULL,
 		success BOOLEAN NOT NULL,
 		error TEXT,
+		error_code TEXT DEFAULT '',
+		action TEXT DEFAULT '',
+		dry_run BOOLEAN DEFAULT 0,
+		old_content TEXT DEFAULT '',
+		duration_ms INTEGER DEFAULT 0,
+		attempts INTEGER DEFAULT 0,
 		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
@@ -127,6 +142,27 @@ func (db *DB) initialize() error {
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_check_logs_timestamp ON check_logs(timestamp DESC);
+
+	CREATE TABLE IF NOT EXISTS managed_records (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		account_name TEXT NOT NULL,
+		zone TEXT NOT NULL,
+		domain TEXT NOT NULL,
+		record_type TEXT NOT NULL,
+		record_ids TEXT NOT NULL DEFAULT '', -- 逗号分隔
+		content TEXT NOT NULL DEFAULT '',    -- 逗号分隔
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(account_name, zone, domain, record_type)
+	);
+
+	CREATE TABLE IF NOT EXISTS zone_ids (
+		provider TEXT NOT NULL,
+		account_name TEXT NOT NULL,
+		zone TEXT NOT NULL,
+		zone_id TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (provider, account_name, zone)
+	);
 	`
 
 	// 新增配置表
@@ -145,11 +181,14 @@ func (db *DB) initialize() error {
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 
-	CREATE TABLE IF NOT EXISTS cloudflare_accounts (
+	CREATE TABLE IF NOT EXISTS dns_accounts (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		name TEXT NOT NULL UNIQUE,
-		api_token TEXT NOT NULL,
+		provider TEXT NOT NULL DEFAULT 'cloudflare',
+		api_token TEXT NOT NULL DEFAULT '',
+		properties TEXT NOT NULL DEFAULT '{}', -- JSON
 		zones TEXT NOT NULL, -- JSON
+		concurrency INTEGER NOT NULL DEFAULT 0,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 	`
@@ -164,7 +203,19 @@ func (db *DB) initialize() error {
 	db.conn.Exec("ALTER TABLE ip_history ADD COLUMN ip_version TEXT DEFAULT 'v4'")
 	db.conn.Exec("ALTER TABLE dns_updates ADD COLUMN account_name TEXT DEFAULT ''")
 	db.conn.Exec("ALTER TABLE dns_updates ADD COLUMN record_type TEXT DEFAULT 'A'")
-	
+	db.conn.Exec("ALTER TABLE dns_updates ADD COLUMN error_code TEXT DEFAULT ''")
+	db.conn.Exec("ALTER TABLE dns_updates ADD COLUMN action TEXT DEFAULT ''")
+	db.conn.Exec("ALTER TABLE dns_updates ADD COLUMN dry_run BOOLEAN DEFAULT 0")
+	db.conn.Exec("ALTER TABLE dns_updates ADD COLUMN old_content TEXT DEFAULT ''")
+	db.conn.Exec("ALTER TABLE dns_updates ADD COLUMN duration_ms INTEGER DEFAULT 0")
+	db.conn.Exec("ALTER TABLE dns_updates ADD COLUMN attempts INTEGER DEFAULT 0")
+	db.conn.Exec("ALTER TABLE dns_accounts ADD COLUMN concurrency INTEGER NOT NULL DEFAULT 0")
+
+	// 迁移旧版 Cloudflare 账户表
+	if err := db.migrateCloudflareAccounts(); err != nil {
+		return fmt.Errorf("迁移 cloudflare_accounts 失败: %w", err)
+	}
+
 	return nil
 }
 
@@ -200,19 +251,42 @@ func (db *DB) GetRecentIPHistory(limit int) ([]IPHistory, error) {
 	return history, nil
 }
 
-// AddDNSUpdate 添加 DNS 更新记录
-func (db *DB) AddDNSUpdate(accountName, ip, recordType, domain string, success bool, errorMsg string) error {
+// AddDNSUpdate 添加 DNS 更新记录（ID 和 Timestamp 自动生成）
+func (db *DB) AddDNSUpdate(u DNSUpdate) error {
 	_, err := db.conn.Exec(
-		"INSERT INTO dns_updates (account_name, ip, record_type, domain, success, error, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?)",
-		accountName, ip, recordType, domain, success, errorMsg, time.Now(),
+		"INSERT INTO dns_updates (account_name, ip, old_content, record_type, domain, success, error, error_code, action, dry_run, duration_ms, attempts, timestamp) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		u.AccountName, u.IP, u.OldContent, u.RecordType, u.Domain, u.Success, u.Error, u.ErrorCode, u.Action, u.DryRun, u.DurationMs, u.Attempts, time.Now(),
 	)
 	return err
 }
 
+// scanDNSUpdates 读取 dnsUpdateColumns 查询的结果
+func scanDNSUpdates(rows *sql.Rows) ([]DNSUpdate, error) {
+	var updates []DNSUpdate
+	for rows.Next() {
+		var u DNSUpdate
+		var oldContent, errMsg, errCode, action sql.NullString
+		var dryRun sql.NullBool
+		var durationMs, attempts sql.NullInt64
+		if err := rows.Scan(&u.ID, &u.AccountName, &u.IP, &oldContent, &u.RecordType, &u.Domain, &u.Success, &errMsg, &errCode, &action, &dryRun, &durationMs, &attempts, &u.Timestamp); err != nil {
+			return nil, err
+		}
+		u.OldContent = oldContent.String
+		u.DurationMs = durationMs.Int64
+		u.Attempts = int(attempts.Int64)
+		u.Error = errMsg.String
+		u.ErrorCode = errCode.String
+		u.Action = action.String
+		u.DryRun = dryRun.Bool
+		updates = append(updates, u)
+	}
+	return updates, rows.Err()
+}
+
 // GetRecentDNSUpdates 获取最近的 DNS 更新记录
 func (db *DB) GetRecentDNSUpdates(limit int) ([]DNSUpdate, error) {
 	rows, err := db.conn.Query(
-		"SELECT id, account_name, ip, record_type, domain, success, error, timestamp FROM dns_updates ORDER BY timestamp DESC LIMIT ?",
+		"SELECT "+dnsUpdateColumns+" FROM dns_updates ORDER BY timestamp DESC LIMIT ?",
 		limit,
 	)
 	if err != nil {
@@ -220,20 +294,7 @@ func (db *DB) GetRecentDNSUpdates(limit int) ([]DNSUpdate, error) {
 	}
 	defer rows.Close()
 
-	var updates []DNSUpdate
-	for rows.Next() {
-		var u DNSUpdate
-		var errMsg sql.NullString
-		if err := rows.Scan(&u.ID, &u.AccountName, &u.IP, &u.RecordType, &u.Domain, &u.Success, &errMsg, &u.Timestamp); err != nil {
-			return nil, err
-		}
-		if errMsg.Valid {
-			u.Error = errMsg.String
-		}
-		updates = append(updates, u)
-	}
-
-	return updates, nil
+	return scanDNSUpdates(rows)
 }
 
 // AddErrorLog 添加错误日志
@@ -379,7 +440,7 @@ func (db *DB) GetDNSFailureStats(start time.Time, groupBy string) ([]IPHistorySt
 // GetDNSFailures 获取详细的 DNS 失败记录
 func (db *DB) GetDNSFailures(start time.Time) ([]DNSUpdate, error) {
 	rows, err := db.conn.Query(
-		"SELECT id, account_name, ip, record_type, domain, success, error, timestamp FROM dns_updates WHERE timestamp >= ? AND success = 0 ORDER BY timestamp DESC",
+		"SELECT "+dnsUpdateColumns+" FROM dns_updates WHERE timestamp >= ? AND success = 0 ORDER BY timestamp DESC",
 		start,
 	)
 	if err != nil {
@@ -387,19 +448,7 @@ func (db *DB) GetDNSFailures(start time.Time) ([]DNSUpdate, error) {
 	}
 	defer rows.Close()
 
-	var updates []DNSUpdate
-	for rows.Next() {
-		var u DNSUpdate
-		var errMsg sql.NullString
-		if err := rows.Scan(&u.ID, &u.AccountName, &u.IP, &u.RecordType, &u.Domain, &u.Success, &errMsg, &u.Timestamp); err != nil {
-			return nil, err
-		}
-		if errMsg.Valid {
-			u.Error = errMsg.String
-		}
-		updates = append(updates, u)
-	}
-	return updates, nil
+	return scanDNSUpdates(rows)
 }
 
 // GetErrorLogs 获取详细的错误日志
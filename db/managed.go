@@ -0,0 +1,64 @@
+package db
+
+import (
+	"strings"
+	"time"
+)
+
+// ManagedRecord idrd 创建或管理过的 DNS 记录，用于发现已从配置中移除的记录（孤儿记录）
+type ManagedRecord struct {
+	ID          int64     `json:"id"`
+	AccountName string    `json:"account_name"`
+	Zone        string    `json:"zone"`
+	Domain      string    `json:"domain"`
+	RecordType  string    `json:"record_type"`
+	RecordIDs   []string  `json:"record_ids"` // 服务商记录 ID，新建后尚未查询到时为空
+	Content     []string  `json:"content"`    // idrd 最后写入的内容（rrset 模式为多个地址）
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// UpsertManagedRecord 记录或更新一条受管记录（按账户、zone、域名和类型唯一）
+func (db *DB) UpsertManagedRecord(r ManagedRecord) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO managed_records (account_name, zone, domain, record_type, record_ids, content, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(account_name, zone, domain, record_type) DO UPDATE SET record_ids = excluded.record_ids, content = excluded.content, updated_at = excluded.updated_at`,
+		r.AccountName, r.Zone, r.Domain, r.RecordType, strings.Join(r.RecordIDs, ","), strings.Join(r.Content, ","), time.Now(),
+	)
+	return err
+}
+
+// GetManagedRecords 获取所有受管记录
+func (db *DB) GetManagedRecords() ([]ManagedRecord, error) {
+	rows, err := db.conn.Query("SELECT id, account_name, zone, domain, record_type, record_ids, content, updated_at FROM managed_records ORDER BY domain, record_type")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []ManagedRecord
+	for rows.Next() {
+		var r ManagedRecord
+		var ids, content string
+		if err := rows.Scan(&r.ID, &r.AccountName, &r.Zone, &r.Domain, &r.RecordType, &ids, &content, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		r.RecordIDs = splitList(ids)
+		r.Content = splitList(content)
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// DeleteManagedRecord 删除受管记录（不再跟踪，不影响服务商中的记录）
+func (db *DB) DeleteManagedRecord(id int64) error {
+	_, err := db.conn.Exec("DELETE FROM managed_records WHERE id = ?", id)
+	return err
+}
+
+// splitList 拆分逗号分隔的列表，空字符串返回空切片
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,45 @@
+package db
+
+import "time"
+
+// ZoneID 缓存的服务商 Zone ID
+type ZoneID struct {
+	Provider    string
+	AccountName string
+	Zone        string
+	ZoneID      string
+}
+
+// GetZoneIDs 获取所有缓存的 Zone ID
+func (db *DB) GetZoneIDs() ([]ZoneID, error) {
+	rows, err := db.conn.Query("SELECT provider, account_name, zone, zone_id FROM zone_ids")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []ZoneID
+	for rows.Next() {
+		var z ZoneID
+		if err := rows.Scan(&z.Provider, &z.AccountName, &z.Zone, &z.ZoneID); err != nil {
+			return nil, err
+		}
+		ids = append(ids, z)
+	}
+	return ids, rows.Err()
+}
+
+// SetZoneID 保存 Zone ID
+func (db *DB) SetZoneID(z ZoneID) error {
+	_, err := db.conn.Exec(
+		"INSERT OR REPLACE INTO zone_ids (provider, account_name, zone, zone_id, updated_at) VALUES (?, ?, ?, ?, ?)",
+		z.Provider, z.AccountName, z.Zone, z.ZoneID, time.Now(),
+	)
+	return err
+}
+
+// DeleteZoneID 删除失效的 Zone ID
+func (db *DB) DeleteZoneID(provider, accountName, zone string) error {
+	_, err := db.conn.Exec("DELETE FROM zone_ids WHERE provider = ? AND account_name = ? AND zone = ?", provider, accountName, zone)
+	return err
+}
@@ -0,0 +1,214 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"idrd/config"
+	"net"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AdoptQuery 在 zone 中查找可加入配置的已有记录的条件，IP 和 Pattern 同时设置时满足任一即可
+type AdoptQuery struct {
+	Account string `json:"account"`
+	Zone    string `json:"zone"`
+	IP      string `json:"ip,omitempty"`      // 指向该地址的记录
+	Pattern string `json:"pattern,omitempty"` // 记录名通配符（如 *.home、vpn*），匹配 zone 内的记录名或完整域名
+}
+
+// AdoptCandidate zone 中可以加入配置的已有 A/AAAA 记录
+type AdoptCandidate struct {
+	ID         string   `json:"id"`
+	Domain     string   `json:"domain"`
+	Record     string   `json:"record"` // zone 内的记录名（根域名为 @）
+	Type       string   `json:"type"`
+	Content    string   `json:"content"`
+	TTL        int      `json:"ttl,omitempty"`
+	Proxied    *bool    `json:"proxied,omitempty"`
+	Comment    string   `json:"comment,omitempty"` // 去掉 idrd 所有权标记后的备注
+	Tags       []string `json:"tags,omitempty"`
+	MatchesIP  bool     `json:"matches_ip"`
+	Configured bool     `json:"configured"`      // 记录名已在 zone 配置中
+	Owner      string   `json:"owner,omitempty"` // 备注中其他 idrd 实例的所有者标识
+	// Config 加入配置时使用的记录配置：保留记录现有的 proxied、TTL、备注和标签（与 zone 默认值相同的省略）
+	Config config.RecordConfig `json:"config"`
+}
+
+// Validate 检查查询条件
+func (q AdoptQuery) Validate() error {
+	if q.Account == "" || q.Zone == "" {
+		return fmt.Errorf("account and zone required")
+	}
+	if q.IP != "" && net.ParseIP(q.IP) == nil {
+		return fmt.Errorf("invalid ip: %s", q.IP)
+	}
+	if q.Pattern != "" {
+		if _, err := path.Match(q.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", q.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// AdoptCandidates 列出 zone 中指向 q.IP 或名称匹配 q.Pattern 的 A/AAAA 记录（只读）
+// zone 可以尚未出现在账户配置中，账户必须存在于 cfg
+func (m *Manager) AdoptCandidates(cfg *config.AppConfig, q AdoptQuery) ([]AdoptCandidate, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(cfg.DNSAccounts, func(a config.DNSAccount) bool { return a.Name == q.Account })
+	if i < 0 {
+		return nil, fmt.Errorf("账户 %s 不存在", q.Account)
+	}
+	account := cfg.DNSAccounts[i]
+	zone := config.Zone{ZoneName: q.Zone}
+	if j := slices.IndexFunc(account.Zones, func(z config.Zone) bool { return strings.EqualFold(z.ZoneName, q.Zone) }); j >= 0 {
+		zone = account.Zones[j]
+	}
+
+	m.loadCaches()
+	u, err := NewUpdater(account)
+	if err != nil {
+		return nil, fmt.Errorf("创建 DNS 客户端失败: %w", err)
+	}
+	lister, ok := u.(ZoneLister)
+	if !ok {
+		return nil, fmt.Errorf("%s 不支持列出 zone 中的全部记录", account.ProviderName())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	records, err := lister.ListZoneRecords(ctx, zone.ZoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同名同类型的记录数，多条时用记录 ID 选择
+	counts := map[string]int{}
+	for _, r := range records {
+		counts[r.Type+"|"+strings.ToLower(r.Name)]++
+	}
+	own := ownershipFor(cfg, account)
+
+	candidates := []AdoptCandidate{}
+	for _, r := range records {
+		if r.Type != "A" && r.Type != "AAAA" {
+			continue
+		}
+		name := RelativeName(r.Name, zone.ZoneName)
+		matchesIP := q.IP != "" && sameIP(r.Content, q.IP)
+		matchesName := q.Pattern != "" && (matchName(q.Pattern, name) || matchName(q.Pattern, r.Name))
+		if !matchesIP && !matchesName && (q.IP != "" || q.Pattern != "") {
+			continue
+		}
+
+		c := AdoptCandidate{
+			ID:         r.ID,
+			Domain:     r.Name,
+			Record:     name,
+			Type:       r.Type,
+			Content:    r.Content,
+			TTL:        r.TTL,
+			Proxied:    r.Proxied,
+			Tags:       r.Tags,
+			MatchesIP:  matchesIP,
+			Configured: slices.ContainsFunc(zone.Records, func(rc config.RecordConfig) bool { return strings.EqualFold(rc.Name, name) }),
+		}
+		if r.Comment != nil {
+			c.Comment = stripOwnerTags(*r.Comment)
+			c.Owner = commentOwner(*r.Comment)
+			if own != nil && c.Owner == own.owner {
+				c.Owner = ""
+			}
+		}
+		c.Config = adoptedConfig(zone, c, own != nil)
+		if counts[r.Type+"|"+strings.ToLower(r.Name)] > 1 {
+			c.Config.Select = &config.RecordSelector{ID: r.ID}
+		}
+		candidates = append(candidates, c)
+	}
+	slices.SortFunc(candidates, func(a, b AdoptCandidate) int {
+		if c := strings.Compare(a.Domain, b.Domain); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.Type, b.Type); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return candidates, nil
+}
+
+// adoptedConfig 返回保留记录现有设置的记录配置，与 zone 默认值相同的属性省略
+// 启用所有权时设置 adopt，使下次同步为记录写入标记
+func adoptedConfig(zone config.Zone, c AdoptCandidate, ownership bool) config.RecordConfig {
+	rc := config.RecordConfig{Name: c.Record, Adopt: ownership && !zone.Adopt}
+	if c.Proxied != nil && (zone.Proxied == nil || *zone.Proxied != *c.Proxied) {
+		proxied := *c.Proxied
+		rc.Proxied = &proxied
+	}
+	// Cloudflare 代理记录的 TTL 固定为 Auto，不写入
+	if c.TTL > 0 && c.TTL != zone.TTL && (c.Proxied == nil || !*c.Proxied) {
+		rc.TTL = c.TTL
+	}
+	if c.Comment != zone.Comment {
+		rc.Comment = c.Comment
+	}
+	if len(c.Tags) > 0 && !slices.Equal(c.Tags, zone.Tags) {
+		rc.Tags = append([]string{}, c.Tags...)
+	}
+	return rc
+}
+
+// AdoptRecords 将候选记录加入账户的 zone 配置（zone 不存在时创建），返回加入的记录名
+// 已在配置中的记录名跳过；同名多条候选只加入第一条
+func AdoptRecords(cfg *config.AppConfig, account, zoneName string, candidates []AdoptCandidate) ([]string, error) {
+	i := slices.IndexFunc(cfg.DNSAccounts, func(a config.DNSAccount) bool { return a.Name == account })
+	if i < 0 {
+		return nil, fmt.Errorf("账户 %s 不存在", account)
+	}
+	// cfg 通常是 SafeConfig.Get 返回的浅拷贝，修改前复制切片，避免改动正在使用的配置
+	cfg.DNSAccounts = slices.Clone(cfg.DNSAccounts)
+	acc := &cfg.DNSAccounts[i]
+	acc.Zones = slices.Clone(acc.Zones)
+	j := slices.IndexFunc(acc.Zones, func(z config.Zone) bool { return strings.EqualFold(z.ZoneName, zoneName) })
+	if j < 0 {
+		acc.Zones = append(acc.Zones, config.Zone{ZoneName: zoneName})
+		j = len(acc.Zones) - 1
+	}
+	zone := &acc.Zones[j]
+	zone.Records = slices.Clone(zone.Records)
+
+	adopted := []string{}
+	for _, c := range candidates {
+		if slices.ContainsFunc(zone.Records, func(rc config.RecordConfig) bool { return strings.EqualFold(rc.Name, c.Record) }) {
+			continue
+		}
+		zone.Records = append(zone.Records, c.Config)
+		adopted = append(adopted, c.Record)
+	}
+	return adopted, nil
+}
+
+// matchName 不区分大小写地匹配通配符
+func matchName(pattern, name string) bool {
+	ok, _ := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return ok
+}
+
+// stripOwnerTags 去掉备注中的 idrd 所有权标记
+func stripOwnerTags(comment string) string {
+	for {
+		start := strings.Index(comment, "[idrd:")
+		if start < 0 {
+			return strings.TrimSpace(comment)
+		}
+		end := strings.Index(comment[start:], "]")
+		if end < 0 {
+			return strings.TrimSpace(comment[:start])
+		}
+		comment = comment[:start] + comment[start+end+1:]
+	}
+}
@@ -0,0 +1,282 @@
+package dns
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"idrd/config"
+	"idrd/ip"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 阿里云解析 API 常量
+const (
+	defaultAliDNSEndpoint = "https://alidns.aliyuncs.com/"
+	aliDNSAPIVersion      = "2015-01-09"
+	defaultAliDNSLine     = "default"
+	defaultAliDNSTTL      = 600 // 免费版最小 TTL
+)
+
+// AliDNSUpdater 通过阿里云解析 (Alibaba Cloud DNS) RPC API 更新记录
+type AliDNSUpdater struct {
+	endpoint        string
+	accessKeyID     string
+	accessKeySecret string
+	line            string // 解析线路，只管理该线路上的记录
+	ttl             int    // 新记录的 TTL
+	client          *http.Client
+}
+
+// aliDNSRecord DescribeDomainRecords 返回的记录
+type aliDNSRecord struct {
+	RecordID string `json:"RecordId"`
+	RR       string `json:"RR"`
+	Type     string `json:"Type"`
+	Value    string `json:"Value"`
+	TTL      int    `json:"TTL"`
+	Line     string `json:"Line"`
+}
+
+func init() {
+	Register(Backend{
+		Provider:   "alidns",
+		Name:       "阿里云解析 (Alibaba Cloud DNS)",
+		Ownership:  OwnershipTXT,
+		TokenLabel: "AccessKey Secret",
+		Properties: []ip.PropertySchema{
+			{Name: "access_key_id", Label: "AccessKey ID", Type: "string", Required: true},
+			{Name: "line", Label: "Line (线路)", Type: "string", Default: defaultAliDNSLine, Help: "解析线路，如 default、telecom、unicom、mobile、oversea"},
+			{Name: "ttl", Label: "TTL", Type: "number", Default: strconv.Itoa(defaultAliDNSTTL), Help: "新建记录的 TTL（秒），已有记录保留原 TTL"},
+			{Name: "endpoint", Label: "Endpoint", Type: "string", Default: defaultAliDNSEndpoint, Help: "API 地址，国际站可使用 https://alidns.ap-southeast-1.aliyuncs.com/"},
+		},
+		New: newAliDNSUpdater,
+		Validate: func(a *config.DNSAccount) error {
+			if a.APIToken == "" {
+				return fmt.Errorf("AccessKey secret required")
+			}
+			if ttl := a.Properties["ttl"]; ttl != "" {
+				if n, err := strconv.Atoi(ttl); err != nil || n <= 0 {
+					return fmt.Errorf("invalid ttl %s", ttl)
+				}
+			}
+			if endpoint := a.Properties["endpoint"]; endpoint != "" {
+				if u, err := url.Parse(endpoint); err != nil || u.Host == "" {
+					return fmt.Errorf("invalid endpoint %s", endpoint)
+				}
+			}
+			return nil
+		},
+		MaxConcurrency: 2, // 阿里云解析 API 默认限制每秒 20 次左右，且按账户计算
+	})
+}
+
+// newAliDNSUpdater 根据账户配置创建阿里云解析客户端
+func newAliDNSUpdater(account config.DNSAccount) (Updater, error) {
+	props := account.Properties
+	if props["access_key_id"] == "" || account.APIToken == "" {
+		return nil, fmt.Errorf("AccessKey ID or secret is empty")
+	}
+
+	u := &AliDNSUpdater{
+		endpoint:        props["endpoint"],
+		accessKeyID:     props["access_key_id"],
+		accessKeySecret: account.APIToken,
+		line:            props["line"],
+		ttl:             defaultAliDNSTTL,
+		client:          &http.Client{Timeout: 30 * time.Second},
+	}
+	if u.endpoint == "" {
+		u.endpoint = defaultAliDNSEndpoint
+	}
+	if u.line == "" {
+		u.line = defaultAliDNSLine
+	}
+	if ttl, err := strconv.Atoi(props["ttl"]); err == nil && ttl > 0 {
+		u.ttl = ttl
+	}
+	return u, nil
+}
+
+// call 调用 RPC API：添加公共参数并签名，解析 JSON 响应到 out
+func (u *AliDNSUpdater) call(ctx context.Context, action string, params map[string]string, out any) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	query.Set("Action", action)
+	query.Set("Format", "JSON")
+	query.Set("Version", aliDNSAPIVersion)
+	query.Set("AccessKeyId", u.accessKeyID)
+	query.Set("SignatureMethod", "HMAC-SHA1")
+	query.Set("SignatureVersion", "1.0")
+	query.Set("SignatureNonce", hex.EncodeToString(nonce))
+	query.Set("Timestamp", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	query.Set("Signature", aliDNSSign(http.MethodGet, query, u.accessKeySecret))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		}
+		if json.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+			return &ProviderError{Provider: "alidns", Code: apiErr.Code, Message: apiErr.Message, Class: aliDNSErrorClass(resp.StatusCode, apiErr.Code)}
+		}
+		return httpStatusError("alidns", action, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+// aliDNSErrorClass 按错误码和 HTTP 状态码分类
+// AccessKey 无效（HTTP 404）和签名错误（HTTP 400）属于凭据问题，按状态码会被误分为 not_found 和 validation
+func aliDNSErrorClass(status int, code string) string {
+	switch {
+	case strings.HasPrefix(code, "Throttling"):
+		return ErrorClassRateLimit
+	case strings.HasPrefix(code, "InvalidAccessKeyId"), strings.HasPrefix(code, "SignatureDoesNotMatch"),
+		strings.HasPrefix(code, "Forbidden"), strings.HasPrefix(code, "InvalidSecurityToken"):
+		return ErrorClassAuth
+	}
+	return httpErrorClass(status)
+}
+
+// aliDNSSign 计算 RPC 签名（签名版本 1.0）
+func aliDNSSign(method string, query url.Values, secret string) string {
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(aliDNSStringToSign(method, query)))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliDNSStringToSign StringToSign = Method & %2F & percentEncode(按键排序的规范化查询串)
+func aliDNSStringToSign(method string, query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, aliPercentEncode(k)+"="+aliPercentEncode(query.Get(k)))
+	}
+	return method + "&" + aliPercentEncode("/") + "&" + aliPercentEncode(strings.Join(pairs, "&"))
+}
+
+// aliPercentEncode 按 RFC 3986 编码（空格为 %20，保留 ~）
+func aliPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+// ListRecords 列出 zone 中指定名称、类型且位于配置线路上的记录
+func (u *AliDNSUpdater) ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error) {
+	rr := RelativeName(name, zone)
+
+	var resp struct {
+		DomainRecords struct {
+			Record []aliDNSRecord `json:"Record"`
+		} `json:"DomainRecords"`
+	}
+	err := u.call(ctx, "DescribeDomainRecords", map[string]string{
+		"DomainName": zone,
+		"RRKeyWord":  rr,
+		"Type":       recordType,
+		"Line":       u.line,
+		"SearchMode": "EXACT",
+		"PageSize":   "100",
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, r := range resp.DomainRecords.Record {
+		// RRKeyWord 在部分区域为模糊匹配，这里再精确过滤一次
+		if r.RR != rr || r.Type != recordType || r.Line != u.line {
+			continue
+		}
+		records = append(records, Record{
+			ID:      r.RecordID,
+			Type:    r.Type,
+			Name:    FQDN(r.RR, zone),
+			Content: r.Value,
+			TTL:     r.TTL,
+		})
+	}
+	return records, nil
+}
+
+// CreateRecord 在配置线路上创建记录
+func (u *AliDNSUpdater) CreateRecord(ctx context.Context, zone string, rec Record) error {
+	ttl := rec.TTL
+	if ttl <= 0 {
+		ttl = u.ttl
+	}
+	return u.call(ctx, "AddDomainRecord", map[string]string{
+		"DomainName": zone,
+		"RR":         RelativeName(rec.Name, zone),
+		"Type":       rec.Type,
+		"Value":      rec.Content,
+		"TTL":        strconv.Itoa(ttl),
+		"Line":       u.line,
+	}, nil)
+}
+
+// UpdateRecord 更新记录，保留原有 TTL
+func (u *AliDNSUpdater) UpdateRecord(ctx context.Context, zone string, rec Record) error {
+	params := map[string]string{
+		"RecordId": rec.ID,
+		"RR":       RelativeName(rec.Name, zone),
+		"Type":     rec.Type,
+		"Value":    rec.Content,
+		"Line":     u.line,
+	}
+	if rec.TTL > 0 {
+		params["TTL"] = strconv.Itoa(rec.TTL)
+	}
+	return u.call(ctx, "UpdateDomainRecord", params, nil)
+}
+
+// DeleteRecord 删除记录
+func (u *AliDNSUpdater) DeleteRecord(ctx context.Context, zone string, rec Record) error {
+	return u.call(ctx, "DeleteDomainRecord", map[string]string{"RecordId": rec.ID}, nil)
+}
+
+// Verify 验证 AccessKey 是否可用（列出账户下的域名）
+func (u *AliDNSUpdater) Verify(ctx context.Context) error {
+	return u.call(ctx, "DescribeDomains", map[string]string{"PageSize": "1"}, nil)
+}
@@ -0,0 +1,120 @@
+package dns
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"idrd/config"
+)
+
+// 阿里云解析 API 文档（签名机制）中的示例请求
+func TestAliDNSSignature(t *testing.T) {
+	query := url.Values{}
+	for k, v := range map[string]string{
+		"Format":           "XML",
+		"AccessKeyId":      "testid",
+		"Action":           "DescribeDomainRecords",
+		"SignatureMethod":  "HMAC-SHA1",
+		"DomainName":       "example.com",
+		"SignatureNonce":   "f59ed6a9-83fc-473b-9cc6-99c95df3856e",
+		"SignatureVersion": "1.0",
+		"Version":          "2015-01-09",
+		"Timestamp":        "2016-03-24T16:41:54Z",
+	} {
+		query.Set(k, v)
+	}
+
+	wantString := "GET&%2F&AccessKeyId%3Dtestid%26Action%3DDescribeDomainRecords%26DomainName%3Dexample.com" +
+		"%26Format%3DXML%26SignatureMethod%3DHMAC-SHA1%26SignatureNonce%3Df59ed6a9-83fc-473b-9cc6-99c95df3856e" +
+		"%26SignatureVersion%3D1.0%26Timestamp%3D2016-03-24T16%253A41%253A54Z%26Version%3D2015-01-09"
+	if got := aliDNSStringToSign(http.MethodGet, query); got != wantString {
+		t.Errorf("string to sign =\n%s\nwant\n%s", got, wantString)
+	}
+	if got, want := aliDNSSign(http.MethodGet, query, "testsecret"), "uRpHwaSEt3J+6KQD//svCh/x+pI="; got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+}
+
+func TestAliPercentEncode(t *testing.T) {
+	for in, want := range map[string]string{"a b": "a%20b", "a*b": "a%2Ab", "a~b": "a~b", "默认": "%E9%BB%98%E8%AE%A4", "a/b": "a%2Fb"} {
+		if got := aliPercentEncode(in); got != want {
+			t.Errorf("aliPercentEncode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// newTestAliDNS 启动测试服务器，handler 收到的请求已通过签名校验
+func newTestAliDNS(t *testing.T, handler func(w http.ResponseWriter, query url.Values)) *AliDNSUpdater {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		signature := query.Get("Signature")
+		query.Del("Signature")
+		if want := aliDNSSign(http.MethodGet, query, "secret"); signature != want {
+			t.Errorf("Signature = %q, want %q", signature, want)
+		}
+		for _, k := range []string{"AccessKeyId", "SignatureNonce", "Timestamp", "Version"} {
+			if query.Get(k) == "" {
+				t.Errorf("missing %s", k)
+			}
+		}
+		handler(w, query)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := newAliDNSUpdater(config.DNSAccount{
+		APIToken:   "secret",
+		Properties: map[string]string{"access_key_id": "LTAItest", "endpoint": srv.URL + "/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.(*AliDNSUpdater)
+}
+
+func TestAliDNSListRecords(t *testing.T) {
+	u := newTestAliDNS(t, func(w http.ResponseWriter, q url.Values) {
+		if q.Get("Action") != "DescribeDomainRecords" || q.Get("RRKeyWord") != "home" || q.Get("DomainName") != "example.com" {
+			t.Errorf("unexpected request %v", q)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"DomainRecords": map[string]any{"Record": []aliDNSRecord{
+			{RecordID: "1", RR: "home", Type: "A", Value: "192.0.2.1", TTL: 600, Line: "default"},
+			{RecordID: "2", RR: "home2", Type: "A", Value: "192.0.2.2", TTL: 600, Line: "default"},
+		}}})
+	})
+	got, err := u.ListRecords(t.Context(), "example.com", "home.example.com", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "1" || got[0].Name != "home.example.com" {
+		t.Errorf("ListRecords = %+v, want only home", got)
+	}
+}
+
+func TestAliDNSErrorClass(t *testing.T) {
+	tests := []struct {
+		status      int
+		code, class string
+	}{
+		{http.StatusNotFound, "InvalidAccessKeyId.NotFound", ErrorClassAuth},
+		{http.StatusBadRequest, "SignatureDoesNotMatch", ErrorClassAuth},
+		{http.StatusForbidden, "Forbidden.RAM", ErrorClassAuth},
+		{http.StatusBadRequest, "InvalidDomainName.NoExist", ErrorClassValidation},
+		{http.StatusNotFound, "DomainRecordNotBelongToUser", ErrorClassNotFound},
+		{http.StatusBadRequest, "Throttling.User", ErrorClassRateLimit},
+		{http.StatusServiceUnavailable, "ServiceUnavailable", ErrorClassTransient},
+	}
+	for _, tt := range tests {
+		u := newTestAliDNS(t, func(w http.ResponseWriter, _ url.Values) {
+			w.WriteHeader(tt.status)
+			json.NewEncoder(w).Encode(map[string]string{"Code": tt.code, "Message": "test", "RequestId": "1"})
+		})
+		err := u.Verify(t.Context())
+		if ErrorCode(err) != tt.code || ErrorClass(err) != tt.class {
+			t.Errorf("%s: code %q class %q, want class %q", tt.code, ErrorCode(err), ErrorClass(err), tt.class)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package dns
+
+import (
+	"context"
+	"idrd/db"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ZoneLister 可选接口：支持一次列出 zone 中全部记录的服务商
+// 实现该接口的 Updater 在一次同步中每个 zone 只列出一次，之后的 ListRecords 从快照中过滤
+type ZoneLister interface {
+	Updater
+	ListZoneRecords(ctx context.Context, zone string) ([]Record, error)
+}
+
+// zoneIDCache 缓存 zone 名称到服务商 Zone ID 的映射（Cloudflare Zone ID、Route 53 Hosted Zone ID）
+// Updater 按同步创建，缓存放在包级别以跨同步保留；设置数据库后持久化，重启后无需重新查询
+type zoneIDCache struct {
+	mu  sync.Mutex
+	ids map[string]string // 服务商|账户|zone -> Zone ID
+	db  *db.DB
+}
+
+var zoneIDs = &zoneIDCache{ids: map[string]string{}}
+
+func zoneIDKey(provider, account, zone string) string {
+	return provider + "|" + account + "|" + strings.ToLower(zone)
+}
+
+// useDB 从数据库加载已缓存的 Zone ID，之后的变更同时写入数据库
+func (c *zoneIDCache) useDB(database *db.DB) {
+	cached, err := database.GetZoneIDs()
+	if err != nil {
+		log.Printf("⚠️  加载 Zone ID 缓存失败: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.db = database
+	for _, z := range cached {
+		c.ids[zoneIDKey(z.Provider, z.AccountName, z.Zone)] = z.ZoneID
+	}
+}
+
+// resolve 返回缓存的 Zone ID，未缓存时调用 lookup 查询并缓存
+func (c *zoneIDCache) resolve(provider, account, zone string, lookup func() (string, error)) (string, error) {
+	key := zoneIDKey(provider, account, zone)
+	c.mu.Lock()
+	id, ok := c.ids[key]
+	c.mu.Unlock()
+	if ok {
+		return id, nil
+	}
+
+	id, err := lookup()
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ids[key] = id
+	if c.db != nil {
+		if err := c.db.SetZoneID(db.ZoneID{Provider: provider, AccountName: account, Zone: strings.ToLower(zone), ZoneID: id}); err != nil {
+			log.Printf("⚠️  保存 Zone ID 缓存失败 (%s): %v", zone, err)
+		}
+	}
+	return id, nil
+}
+
+// invalidate 丢弃缓存的 Zone ID（zone 已删除或转移到其他账户），下次使用时重新查询
+func (c *zoneIDCache) invalidate(provider, account, zone string) {
+	key := zoneIDKey(provider, account, zone)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.ids[key]; !ok {
+		return
+	}
+	delete(c.ids, key)
+	if c.db != nil {
+		c.db.DeleteZoneID(provider, account, strings.ToLower(zone))
+	}
+	log.Printf("ℹ️  Zone ID 缓存已失效: %s (%s)", zone, account)
+}
+
+// loadCaches 首次使用时从数据库加载 Zone ID 缓存
+func (m *Manager) loadCaches() {
+	m.cacheOnce.Do(func() {
+		if m.DB != nil {
+			zoneIDs.useDB(m.DB)
+		}
+	})
+}
+
+// recordSnapshot 一次同步内缓存整个 zone 的记录列表，零值可用
+// Updater 实例按同步创建，快照随实例丢弃；写操作成功后更新快照，失败时丢弃该 zone 的快照（记录可能已被删除）
+type recordSnapshot struct {
+	mu    sync.Mutex
+	zones map[string][]Record
+}
+
+// filter 返回 zone 中指定名称和类型的记录，zone 尚未列出时调用 list 列出全部记录
+func (s *recordSnapshot) filter(ctx context.Context, zone, name, recordType string, list func(ctx context.Context, zone string) ([]Record, error)) ([]Record, error) {
+	s.mu.Lock()
+	records, ok := s.zones[zone]
+	s.mu.Unlock()
+	if !ok {
+		var err error
+		if records, err = list(ctx, zone); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if s.zones == nil {
+			s.zones = map[string][]Record{}
+		}
+		s.zones[zone] = records
+		s.mu.Unlock()
+	}
+
+	var result []Record
+	for _, r := range records {
+		if strings.EqualFold(r.Name, name) && r.Type == recordType {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+// find 按 ID 查找快照中的记录
+func (s *recordSnapshot) find(zone, id string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.zones[zone], func(r Record) bool { return r.ID == id })
+	if i < 0 {
+		return Record{}, false
+	}
+	return s.zones[zone][i], true
+}
+
+// created 将新建的记录加入快照
+func (s *recordSnapshot) created(zone string, rec Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if records, ok := s.zones[zone]; ok {
+		s.zones[zone] = append(records, rec)
+	}
+}
+
+// updated 用更新后的记录替换快照中的同 ID 记录
+func (s *recordSnapshot) updated(zone string, rec Record) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if i := slices.IndexFunc(s.zones[zone], func(r Record) bool { return r.ID == rec.ID }); i >= 0 {
+		s.zones[zone][i] = rec
+	}
+}
+
+// deleted 从快照中移除记录
+func (s *recordSnapshot) deleted(zone, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if records, ok := s.zones[zone]; ok {
+		s.zones[zone] = slices.DeleteFunc(records, func(r Record) bool { return r.ID == id })
+	}
+}
+
+// invalidate 丢弃 zone 的快照，下次查询时重新列出
+func (s *recordSnapshot) invalidate(zone string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.zones, zone)
+}
@@ -2,194 +2,538 @@ package dns
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"idrd/config"
-	"idrd/db"
+	"idrd/ip"
 	"log"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudflare/cloudflare-go"
 )
 
-// 重试配置常量
+// CloudflareUpdater 负责更新 Cloudflare DNS 记录
+type CloudflareUpdater struct {
+	api       *cloudflare.API
+	account   string         // 账户名称，用于 Zone ID 缓存
+	authType  string         // 认证方式（cloudflareAuth*）
+	accountID string         // account_token 认证时 Token 所属的 Cloudflare 账户 ID
+	records   recordSnapshot // 本次同步中已列出的 zone 记录
+	// discovered Zones 列出的 zone（小写）-> ID；设置后只使用它查找 Zone ID，不读写共享的 Zone ID 缓存
+	// 验证尚未保存的账户时保持只读
+	discovered map[string]string
+}
+
+// Cloudflare 认证方式（账户属性 auth_type）
 const (
-	maxRetries     = 3               // 最大重试次数
-	initialBackoff = 1 * time.Second // 初始退避时间
-	maxBackoff     = 10 * time.Second // 最大退避时间
+	cloudflareAuthAPIToken     = "api_token"     // 用户 API Token
+	cloudflareAuthAPIKey       = "api_key"       // Global API Key + 账户邮箱
+	cloudflareAuthAccountToken = "account_token" // 账户所有的 API Token，需要账户 ID
 )
 
-// CloudflareUpdater 负责更新 Cloudflare DNS 记录
-type CloudflareUpdater struct {
-	Config *config.SafeConfig
-	DB     *db.DB
+var (
+	// cloudflareGlobalKeyPattern Global API Key 为 37 位十六进制字符
+	cloudflareGlobalKeyPattern = regexp.MustCompile(`^[0-9a-f]{37}$`)
+	// cloudflareAccountIDPattern 账户 ID 为 32 位十六进制字符
+	cloudflareAccountIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+func init() {
+	Register(Backend{
+		Provider:   "cloudflare",
+		Name:       "Cloudflare",
+		Ownership:  OwnershipComment,
+		TokenLabel: "API Token",
+		Properties: []ip.PropertySchema{
+			{Name: "auth_type", Label: "Auth Type", Type: "select", Required: true, Default: cloudflareAuthAPIToken,
+				Options: []string{cloudflareAuthAPIToken, cloudflareAuthAPIKey, cloudflareAuthAccountToken},
+				Help:    "api_token: 用户 API Token；api_key: Global API Key（需要 email）；account_token: 账户所有的 Token（需要 account_id）"},
+			{Name: "email", Label: "Email", Type: "string", Help: "api_key 认证时必填：Cloudflare 账户邮箱"},
+			{Name: "account_id", Label: "Account ID", Type: "string", Help: "account_token 认证时必填：Token 所属账户的 ID（控制台概览页右侧）"},
+		},
+		New:      newCloudflareUpdater,
+		Validate: validateCloudflareAccount,
+	})
 }
 
-// UpdateIP 更新所有配置的 DNS 记录到新 IP
-func (c *CloudflareUpdater) UpdateIP(newIP string) error {
-	cfg := c.Config.Get()
-	
-	// 遍历所有账户
-	for _, account := range cfg.CloudflareAccounts {
-		if account.APIToken == "" {
-			continue
+// validateCloudflareAccount 按认证方式校验凭据格式和必填属性
+func validateCloudflareAccount(a *config.DNSAccount) error {
+	// 如果 Token 是脱敏值，跳过格式检查（将在保存时回填）
+	token := a.APIToken
+	if token == "***" {
+		token = ""
+	}
+	switch authType := a.Properties["auth_type"]; authType {
+	case cloudflareAuthAPIToken:
+		if token != "" && len(token) < 20 {
+			return fmt.Errorf("API token too short (minimum 20 characters)")
 		}
-
-		api, err := cloudflare.NewWithAPIToken(account.APIToken)
-		if err != nil {
-			log.Printf("❌ 创建 Cloudflare 客户端失败 (账户: %s): %v", account.Name, err)
-			continue
+		if cloudflareGlobalKeyPattern.MatchString(token) {
+			return fmt.Errorf("credential looks like a Global API Key, set auth_type to api_key and provide email")
 		}
-
-		// DEBUG: 打印 Token 前缀以排查问题 (只显示前 4 位)
-		tokenPrefix := "EMPTY"
-		if len(account.APIToken) >= 4 {
-			tokenPrefix = account.APIToken[:4] + "..."
-		} else if len(account.APIToken) > 0 {
-			tokenPrefix = account.APIToken
+	case cloudflareAuthAPIKey:
+		if token != "" && !cloudflareGlobalKeyPattern.MatchString(token) {
+			return fmt.Errorf("invalid Global API Key (expected 37 hex characters)")
+		}
+		if _, err := mail.ParseAddress(a.Properties["email"]); err != nil {
+			return fmt.Errorf("email required for auth_type api_key")
+		}
+	case cloudflareAuthAccountToken:
+		if token != "" && len(token) < 20 {
+			return fmt.Errorf("API token too short (minimum 20 characters)")
+		}
+		if !cloudflareAccountIDPattern.MatchString(a.Properties["account_id"]) {
+			return fmt.Errorf("account_id required for auth_type account_token (32 hex characters)")
+		}
+	default:
+		return fmt.Errorf("invalid auth_type %q (must be api_token, api_key or account_token)", authType)
+	}
+	// Cloudflare 的 TTL 为 1（Auto）或 60 以上
+	for _, z := range a.Zones {
+		if !validCloudflareTTL(z.TTL) {
+			return fmt.Errorf("zone %s: invalid ttl %d for cloudflare (must be 1 for auto or at least 60)", z.ZoneName, z.TTL)
+		}
+		for _, r := range z.Records {
+			if !validCloudflareTTL(r.TTL) {
+				return fmt.Errorf("record %s in zone %s: invalid ttl %d for cloudflare (must be 1 for auto or at least 60)", r.Name, z.ZoneName, r.TTL)
+			}
 		}
-		log.Printf("🔍 [DEBUG] 初始化 Cloudflare 账户: %s, Token前缀: %s, Token长度: %d", account.Name, tokenPrefix, len(account.APIToken))
+	}
+	return nil
+}
 
-		// 遍历该账户下的所有 Zone
-		for _, zone := range account.Zones {
-			// 为每个 zone 创建独立的 context，确保立即释放
-			func() {
-				ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second) // 增加超时以容纳重试
-				defer cancel()
+// validCloudflareTTL 0 表示使用默认值
+func validCloudflareTTL(ttl int) bool {
+	return ttl <= 1 || ttl >= 60
+}
 
-				zoneID, err := c.getZoneIDWithRetry(ctx, api, zone.ZoneName, account.Name)
-				if err != nil {
-					log.Printf("❌ 获取 Zone ID 失败 (账户: %s, 域名: %s): %v", account.Name, zone.ZoneName, err)
-					return
-				}
+// newCloudflareUpdater 根据账户配置和认证方式创建 Cloudflare 客户端
+func newCloudflareUpdater(account config.DNSAccount) (Updater, error) {
+	if account.APIToken == "" {
+		return nil, fmt.Errorf("API token is empty")
+	}
+	authType := account.Properties["auth_type"]
+	if authType == "" {
+		authType = cloudflareAuthAPIToken
+	}
 
-				// 遍历并更新每个子域名
-				for _, record := range zone.Records {
-					fullDomain := record
-					if record != "@" && record != "" {
-						fullDomain = fmt.Sprintf("%s.%s", record, zone.ZoneName)
-					} else {
-						fullDomain = zone.ZoneName
-					}
-
-					if err := c.updateRecordWithRetry(ctx, api, zoneID, fullDomain, newIP, account.Name); err != nil {
-						// 记录失败（重试后仍失败）
-						if c.DB != nil {
-							c.DB.AddDNSUpdate(account.Name, newIP, "A", fullDomain, false, err.Error())
-						}
-						log.Printf("❌ 更新 DNS 记录失败 (%s): %v", fullDomain, err)
-						continue
-					}
-
-					// 记录成功
-					if c.DB != nil {
-						c.DB.AddDNSUpdate(account.Name, newIP, "A", fullDomain, true, "")
-					}
-				}
-			}()
-		}
+	// 重试和限流由 idrd 处理：客户端库的重试忽略 Retry-After，限流器也不跨同步保留
+	limiter := limiterFor("cloudflare", account.Name, cloudflareRateLimit, cloudflareRateWindow, cloudflareBurst)
+	opts := []cloudflare.Option{
+		cloudflare.HTTPClient(&http.Client{Timeout: 30 * time.Second, Transport: &rateLimitedTransport{limiter: limiter, base: http.DefaultTransport}}),
+		cloudflare.UsingRetryPolicy(0, 0, 0),
+		cloudflare.UsingRateLimit(1000),
+	}
+	var (
+		api *cloudflare.API
+		err error
+	)
+	switch authType {
+	case cloudflareAuthAPIKey:
+		api, err = cloudflare.New(account.APIToken, account.Properties["email"], opts...)
+	case cloudflareAuthAPIToken, cloudflareAuthAccountToken:
+		api, err = cloudflare.NewWithAPIToken(account.APIToken, opts...)
+	default:
+		return nil, fmt.Errorf("invalid auth_type %q", authType)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	logCloudflareCredentials(account, authType)
+	return &CloudflareUpdater{api: api, account: account.Name, authType: authType, accountID: account.Properties["account_id"]}, nil
 }
 
-// getZoneIDWithRetry 带重试的获取 Zone ID
-func (c *CloudflareUpdater) getZoneIDWithRetry(ctx context.Context, api *cloudflare.API, zoneName, accountName string) (string, error) {
-	var lastErr error
-	backoff := initialBackoff
+// cloudflareCredentialsLogged 已输出过诊断信息的账户凭据（账户名 -> 脱敏描述），凭据变化时重新输出
+var cloudflareCredentialsLogged sync.Map
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			log.Printf("🔄 重试获取 Zone ID (账户: %s, 域名: %s, 尝试 %d/%d)", accountName, zoneName, attempt, maxRetries)
-			select {
-			case <-ctx.Done():
-				return "", ctx.Err()
-			case <-time.After(backoff):
-			}
-			backoff = min(backoff*2, maxBackoff)
-		}
+// logCloudflareCredentials 输出账户使用的认证方式和脱敏后的凭据，便于排查凭据错误而不泄露内容
+func logCloudflareCredentials(account config.DNSAccount, authType string) {
+	desc := fmt.Sprintf("认证方式: %s, 凭据: %s", authType, redactSecret(account.APIToken))
+	switch authType {
+	case cloudflareAuthAPIKey:
+		desc += ", 邮箱: " + redactEmail(account.Properties["email"])
+	case cloudflareAuthAccountToken:
+		desc += ", 账户 ID: " + account.Properties["account_id"]
+	}
+	if previous, loaded := cloudflareCredentialsLogged.Swap(account.Name, desc); loaded && previous == desc {
+		return
+	}
+	log.Printf("ℹ️  Cloudflare 账户 %s (%s)", account.Name, desc)
+}
+
+// redactSecret 返回凭据的长度和 SHA-256 指纹（前 8 位），可用于比对凭据是否一致，不包含凭据本身的任何字符
+func redactSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return fmt.Sprintf("%d 字符, sha256:%x", len(secret), sum[:4])
+}
+
+// redactEmail 只保留邮箱用户名的首字符和域名
+func redactEmail(email string) string {
+	user, domain, ok := strings.Cut(email, "@")
+	if !ok || user == "" {
+		return "***"
+	}
+	return user[:1] + "***@" + domain
+}
 
-		zoneID, err := api.ZoneIDByName(zoneName)
-		if err == nil {
-			return zoneID, nil
+// zoneID 获取 zone 名称对应的 Zone ID（跨同步缓存）
+func (c *CloudflareUpdater) zoneID(ctx context.Context, zone string) (string, error) {
+	if c.discovered != nil {
+		if id, ok := c.discovered[strings.ToLower(zone)]; ok {
+			return id, nil
 		}
-		lastErr = err
+		return "", fmt.Errorf("获取 Zone ID 失败 (%s): %w", zone, &ProviderError{Provider: "cloudflare", Code: "zone_not_found", Message: "zone 不存在或 Token 无权访问", Class: ErrorClassNotFound})
 	}
+	id, err := zoneIDs.resolve("cloudflare", c.account, zone, func() (string, error) {
+		zones, err := c.api.ListZonesContext(ctx, cloudflare.WithZoneFilters(zone, c.accountID, ""))
+		if err != nil {
+			return "", cloudflareError(err)
+		}
+		if len(zones.Result) != 1 {
+			return "", &ProviderError{Provider: "cloudflare", Code: "zone_not_found", Message: "zone 不存在或 Token 无权访问", Class: ErrorClassNotFound}
+		}
+		return zones.Result[0].ID, nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("获取 Zone ID 失败 (%s): %w", zone, err)
+	}
+	return id, nil
+}
 
-	return "", fmt.Errorf("重试 %d 次后仍失败: %w", maxRetries, lastErr)
+// failed 写操作或查询失败时丢弃 zone 的记录快照，Zone ID 无效时使缓存失效，返回分类后的错误
+func (c *CloudflareUpdater) failed(zone string, err error) error {
+	c.records.invalidate(zone)
+	var cfErr *cloudflare.Error
+	// 7003: 无法路由到该 zone（ID 无效），1001: zone 不存在
+	if c.discovered == nil && errors.As(err, &cfErr) && (cfErr.InternalErrorCodeIs(7003) || cfErr.InternalErrorCodeIs(1001)) {
+		zoneIDs.invalidate("cloudflare", c.account, zone)
+	}
+	return cloudflareError(err)
 }
 
-// updateRecordWithRetry 带重试的更新单个 DNS 记录
-func (c *CloudflareUpdater) updateRecordWithRetry(ctx context.Context, api *cloudflare.API, zoneID, domain, ip, accountName string) error {
-	var lastErr error
-	backoff := initialBackoff
+// cloudflareError 将 Cloudflare API 错误转换为分类的 ProviderError
+// 429 已由 rateLimitedTransport 转换，网络错误等原样返回（视为 transient）
+func cloudflareError(err error) error {
+	var cfErr *cloudflare.Error
+	if !errors.As(err, &cfErr) {
+		return err
+	}
+	class := ErrorClassTransient
+	switch {
+	case cfErr.Type == cloudflare.ErrorTypeAuthentication || cfErr.Type == cloudflare.ErrorTypeAuthorization:
+		class = ErrorClassAuth
+	case cfErr.Type == cloudflare.ErrorTypeNotFound || cfErr.InternalErrorCodeIs(7003) || cfErr.InternalErrorCodeIs(1001) || cfErr.InternalErrorCodeIs(81044):
+		class = ErrorClassNotFound // 81044: 记录不存在
+	case cfErr.Type == cloudflare.ErrorTypeRateLimit:
+		class = ErrorClassRateLimit
+	case cfErr.Type == cloudflare.ErrorTypeRequest:
+		class = ErrorClassValidation
+	}
+	code := strconv.Itoa(cfErr.StatusCode)
+	if len(cfErr.ErrorCodes) > 0 {
+		code = strconv.Itoa(cfErr.ErrorCodes[0])
+	}
+	message := strings.Join(cfErr.ErrorMessages, "; ")
+	if message == "" {
+		message = cfErr.Error()
+	}
+	return &ProviderError{Provider: "cloudflare", Code: code, Message: message, Class: class}
+}
 
-	for attempt := 0; attempt <= maxRetries; attempt++ {
-		if attempt > 0 {
-			log.Printf("🔄 重试更新 DNS 记录 (%s, 尝试 %d/%d)", domain, attempt, maxRetries)
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(backoff):
-			}
-			backoff = min(backoff*2, maxBackoff)
-		}
+// ListRecords 列出 zone 中指定名称和类型的记录（每次同步每个 zone 只列出一次）
+func (c *CloudflareUpdater) ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error) {
+	return c.records.filter(ctx, zone, name, recordType, c.ListZoneRecords)
+}
 
-		err := c.updateRecord(ctx, api, zoneID, domain, ip)
-		if err == nil {
-			return nil
-		}
-		lastErr = err
+// ListZoneRecords 列出 zone 中的全部记录
+func (c *CloudflareUpdater) ListZoneRecords(ctx context.Context, zone string) ([]Record, error) {
+	zoneID, err := c.zoneID(ctx, zone)
+	if err != nil {
+		return nil, err
 	}
 
-	return fmt.Errorf("重试 %d 次后仍失败: %w", maxRetries, lastErr)
+	var result []Record
+	params := cloudflare.ListDNSRecordsParams{ResultInfo: cloudflare.ResultInfo{Page: 1, PerPage: cloudflareListPageSize}}
+	for {
+		records, info, err := c.api.ListDNSRecords(ctx, cloudflare.ZoneIdentifier(zoneID), params)
+		if err != nil {
+			return nil, c.failed(zone, err)
+		}
+		for _, r := range records {
+			result = append(result, cloudflareRecord(r))
+		}
+		if info == nil || !info.HasMorePages() || len(records) == 0 {
+			return result, nil
+		}
+		params.Page++
+	}
 }
 
-// updateRecord 更新单个 DNS 记录（无重试）
-func (c *CloudflareUpdater) updateRecord(ctx context.Context, api *cloudflare.API, zoneID, domain, ip string) error {
-	// 查找现有记录
-	records, _, err := api.ListDNSRecords(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.ListDNSRecordsParams{
-		Name: domain,
-		Type: "A",
+// CreateRecord 创建记录（TTL 为 0 时使用 Auto）
+func (c *CloudflareUpdater) CreateRecord(ctx context.Context, zone string, rec Record) error {
+	zoneID, err := c.zoneID(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	proxied := rec.Proxied
+	if proxied == nil {
+		proxied = cloudflare.BoolPtr(false)
+	}
+	var comment string
+	if rec.Comment != nil {
+		comment = *rec.Comment
+	}
+	created, err := c.api.CreateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.CreateDNSRecordParams{
+		Type:    rec.Type,
+		Name:    rec.Name,
+		Content: rec.Content,
+		TTL:     cloudflareTTL(rec.TTL),
+		Proxied: proxied,
+		Comment: comment,
+		Tags:    rec.Tags,
 	})
+	if err != nil {
+		return c.failed(zone, err)
+	}
+	c.records.created(zone, cloudflareRecord(created))
+	return nil
+}
+
+// UpdateRecord 更新已有记录（Tags 为 nil 时保留原有标签）
+func (c *CloudflareUpdater) UpdateRecord(ctx context.Context, zone string, rec Record) error {
+	zoneID, err := c.zoneID(ctx, zone)
 	if err != nil {
 		return err
 	}
 
-	if len(records) == 0 {
-		// 创建新记录
-		_, err := api.CreateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.CreateDNSRecordParams{
-			Type:    "A",
-			Name:    domain,
-			Content: ip,
-			TTL:     1, // Auto
-			Proxied: cloudflare.BoolPtr(false),
-		})
-		if err != nil {
-			return err
+	tags := rec.Tags
+	if tags == nil {
+		// UpdateDNSRecordParams 的 tags 没有 omitempty，nil 会清空已有标签
+		if current, ok := c.records.find(zone, rec.ID); ok {
+			tags = current.Tags
+		} else {
+			current, err := c.api.GetDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), rec.ID)
+			if err != nil {
+				return c.failed(zone, err)
+			}
+			tags = current.Tags
 		}
-		log.Printf("✅ 创建 DNS 记录成功: %s -> %s", domain, ip)
-		return nil
 	}
 
-	// 更新现有记录
-	record := records[0]
-	if record.Content == ip {
-		log.Printf("ℹ️  IP 未变化，跳过 Cloudflare 更新: %s -> %s", domain, ip)
-		return nil // IP 未变化
+	updated, err := c.api.UpdateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.UpdateDNSRecordParams{
+		ID:      rec.ID,
+		Type:    rec.Type,
+		Name:    rec.Name,
+		Content: rec.Content,
+		TTL:     cloudflareTTL(rec.TTL),
+		Proxied: rec.Proxied,
+		Comment: rec.Comment,
+		Tags:    tags,
+	})
+	if err != nil {
+		return c.failed(zone, err)
 	}
+	c.records.updated(zone, cloudflareRecord(updated))
+	return nil
+}
 
-	_, err = api.UpdateDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), cloudflare.UpdateDNSRecordParams{
-		ID:      record.ID,
-		Type:    "A",
-		Name:    domain,
-		Content: ip,
-		TTL:     1,
-		Proxied: record.Proxied,
-	})
+// DeleteRecord 删除记录
+func (c *CloudflareUpdater) DeleteRecord(ctx context.Context, zone string, rec Record) error {
+	zoneID, err := c.zoneID(ctx, zone)
 	if err != nil {
 		return err
 	}
-	log.Printf("✅ 更新 DNS 记录成功: %s -> %s", domain, ip)
+	if err := c.api.DeleteDNSRecord(ctx, cloudflare.ZoneIdentifier(zoneID), rec.ID); err != nil {
+		return c.failed(zone, err)
+	}
+	c.records.deleted(zone, rec.ID)
+	return nil
+}
+
+// Verify 按认证方式验证凭据是否有效
+// 用户 Token 和账户 Token 使用各自的 verify 接口，Global API Key 没有 verify 接口，改为读取用户信息
+func (c *CloudflareUpdater) Verify(ctx context.Context) error {
+	if c.authType == cloudflareAuthAPIKey {
+		if _, err := c.api.UserDetails(ctx); err != nil {
+			return cloudflareError(err)
+		}
+		return nil
+	}
+	result, err := c.verifyToken(ctx)
+	if err != nil {
+		return cloudflareError(err)
+	}
+	if result.Status != "active" {
+		return fmt.Errorf("API token status: %s", result.Status)
+	}
 	return nil
 }
 
+// tokenPath 返回 Token 接口的路径前缀（用户 Token 与账户 Token 不同）
+func (c *CloudflareUpdater) tokenPath() string {
+	if c.authType == cloudflareAuthAccountToken {
+		return "/accounts/" + c.accountID + "/tokens/"
+	}
+	return "/user/tokens/"
+}
+
+// verifyToken 调用 Token 的 verify 接口，返回 Token ID 和状态
+func (c *CloudflareUpdater) verifyToken(ctx context.Context) (cloudflare.APITokenVerifyBody, error) {
+	var result cloudflare.APITokenVerifyBody
+	raw, err := c.api.Raw(ctx, http.MethodGet, c.tokenPath()+"verify", nil, nil)
+	if err == nil {
+		err = json.Unmarshal(raw.Result, &result)
+	}
+	return result, err
+}
+
+// tokenPolicies 读取当前 Token 的权限策略
+// 需要 Token 具有读取 API Token 的权限，多数只授予 DNS 权限的 Token 会返回错误
+func (c *CloudflareUpdater) tokenPolicies(ctx context.Context) ([]cloudflare.APITokenPolicies, error) {
+	verify, err := c.verifyToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := c.api.Raw(ctx, http.MethodGet, c.tokenPath()+verify.ID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var token cloudflare.APIToken
+	if err := json.Unmarshal(raw.Result, &token); err != nil {
+		return nil, err
+	}
+	return token.Policies, nil
+}
+
+// Zones 列出 Token 可访问的 zone，按 zone 的 permissions 判断是否缺少 DNS 读写权限
+// API Token 列出的 zone 通常不带 permissions，此时改为按 Token 的权限策略判断；策略也无法读取时标记为权限未知
+// 列出的 Zone ID 只保存在本客户端中（不写入共享缓存，验证保持只读），之后列出记录时无需再次查询
+func (c *CloudflareUpdater) Zones(ctx context.Context) ([]ZoneAccess, error) {
+	zones, err := c.api.ListZonesContext(ctx, cloudflare.WithZoneFilters("", c.accountID, ""))
+	if err != nil {
+		return nil, cloudflareError(err)
+	}
+	list := make([]ZoneAccess, 0, len(zones.Result))
+	c.discovered = make(map[string]string, len(zones.Result))
+
+	var (
+		policies       []cloudflare.APITokenPolicies
+		policiesErr    error
+		policiesLoaded bool
+	)
+	for _, z := range zones.Result {
+		c.discovered[strings.ToLower(z.Name)] = z.ID
+		access := ZoneAccess{Name: z.Name, Status: z.Status}
+		switch {
+		case len(z.Permissions) > 0:
+			editable := slices.Contains(z.Permissions, "#dns_records:edit")
+			access.setPermissions(editable || slices.Contains(z.Permissions, "#dns_records:read"), editable)
+		case c.authType == cloudflareAuthAPIKey:
+			access.PermissionsUnknown = true
+		default:
+			if !policiesLoaded {
+				policies, policiesErr = c.tokenPolicies(ctx)
+				policiesLoaded = true
+				if policiesErr != nil {
+					log.Printf("ℹ️  无法读取 Cloudflare Token 的权限策略 (账户: %s)，zone 的 DNS 权限未知: %v", c.account, policiesErr)
+				}
+			}
+			if policiesErr != nil {
+				access.PermissionsUnknown = true
+				break
+			}
+			editable := policyAllows(policies, z.ID, z.Account.ID, cloudflareDNSWrite)
+			access.setPermissions(editable || policyAllows(policies, z.ID, z.Account.ID, cloudflareDNSRead), editable)
+		}
+		list = append(list, access)
+	}
+	return list, nil
+}
+
+// Cloudflare 权限组名称
+const (
+	cloudflareDNSRead  = "DNS Read"
+	cloudflareDNSWrite = "DNS Write"
+)
+
+// policyAllows 按 Token 的权限策略判断对 zone 是否拥有权限组 group（deny 策略优先）
+func policyAllows(policies []cloudflare.APITokenPolicies, zoneID, accountID, group string) bool {
+	allowed := false
+	for _, p := range policies {
+		if !policyCoversZone(p.Resources, zoneID, accountID) {
+			continue
+		}
+		if !slices.ContainsFunc(p.PermissionGroups, func(g cloudflare.APITokenPermissionGroups) bool { return g.Name == group }) {
+			continue
+		}
+		if p.Effect == "deny" {
+			return false
+		}
+		allowed = true
+	}
+	return allowed
+}
+
+// policyCoversZone 判断策略的资源是否包含 zone：指定该 zone、所有 zone，或账户（含所有账户）下的所有 zone
+func policyCoversZone(resources map[string]interface{}, zoneID, accountID string) bool {
+	zoneKeys := []string{"com.cloudflare.api.account.zone." + zoneID, "com.cloudflare.api.account.zone.*"}
+	for key, value := range resources {
+		if slices.Contains(zoneKeys, key) {
+			return true
+		}
+		if key != "com.cloudflare.api.account."+accountID && key != "com.cloudflare.api.account.*" {
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			for _, zoneKey := range zoneKeys {
+				if _, ok := nested[zoneKey]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Cloudflare API 限制每个用户/Token 5 分钟内 1200 次请求
+const (
+	cloudflareRateLimit  = 1200
+	cloudflareRateWindow = 5 * time.Minute
+	cloudflareBurst      = 50 // 令牌桶容量，允许短时间内的突发请求
+)
+
+// cloudflareListPageSize 列出 zone 记录时的每页数量
+const cloudflareListPageSize = 1000
+
+// cloudflareRecord 转换为服务商无关的记录
+func cloudflareRecord(r cloudflare.DNSRecord) Record {
+	return Record{
+		ID:      r.ID,
+		Type:    r.Type,
+		Name:    r.Name,
+		Content: r.Content,
+		TTL:     r.TTL,
+		Proxied: r.Proxied,
+		Comment: cloudflare.StringPtr(r.Comment),
+		Tags:    append([]string{}, r.Tags...),
+	}
+}
+
+// cloudflareTTL 转换 TTL：Cloudflare 使用 1 表示 Auto
+func cloudflareTTL(ttl int) int {
+	if ttl <= 0 {
+		return 1
+	}
+	return ttl
+}
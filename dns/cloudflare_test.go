@@ -0,0 +1,106 @@
+package dns
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+const cloudflareTestZones = `{"success":true,"errors":[],"messages":[],"result":[
+	{"id":"z1","name":"a.example","status":"active","account":{"id":"acc"},"permissions":[]},
+	{"id":"z2","name":"b.example","status":"active","account":{"id":"acc"},"permissions":[]}
+],"result_info":{"page":1,"per_page":50,"count":2,"total_count":2,"total_pages":1}}`
+
+// newTestCloudflare 启动模拟 Cloudflare API 的测试服务器，policies 为 Token 详情的响应（空表示无权读取）
+func newTestCloudflare(t *testing.T, policies string) *CloudflareUpdater {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/zones":
+			io.WriteString(w, cloudflareTestZones)
+		case "/user/tokens/verify":
+			io.WriteString(w, `{"success":true,"errors":[],"messages":[],"result":{"id":"tok","status":"active"}}`)
+		case "/user/tokens/tok":
+			if policies == "" {
+				w.WriteHeader(http.StatusForbidden)
+				io.WriteString(w, `{"success":false,"errors":[{"code":9109,"message":"Unauthorized to access requested resource"}],"messages":[],"result":null}`)
+				return
+			}
+			io.WriteString(w, `{"success":true,"errors":[],"messages":[],"result":{"id":"tok","status":"active","policies":`+policies+`}}`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	api, err := cloudflare.NewWithAPIToken("token", cloudflare.BaseURL(srv.URL), cloudflare.UsingRetryPolicy(0, 0, 0), cloudflare.UsingRateLimit(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &CloudflareUpdater{api: api, account: "test", authType: cloudflareAuthAPIToken}
+}
+
+// zone 列表不带 permissions 时按 Token 的权限策略判断 DNS 权限
+func TestCloudflareZonesTokenPolicies(t *testing.T) {
+	c := newTestCloudflare(t, `[
+		{"id":"p1","effect":"allow","resources":{"com.cloudflare.api.account.zone.z1":"*"},"permission_groups":[{"id":"g1","name":"DNS Write"}]},
+		{"id":"p2","effect":"allow","resources":{"com.cloudflare.api.account.acc":{"com.cloudflare.api.account.zone.*":"*"}},"permission_groups":[{"id":"g2","name":"DNS Read"}]}
+	]`)
+
+	zones, err := c.Zones(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 2 {
+		t.Fatalf("zones = %+v", zones)
+	}
+	if z := zones[0]; z.Editable == nil || !*z.Editable || len(z.Missing) != 0 || z.PermissionsUnknown {
+		t.Errorf("a.example = %+v, want editable", z)
+	}
+	if z := zones[1]; z.Editable == nil || *z.Editable || !slices.Equal(z.Missing, []string{PermissionDNSEdit}) || z.PermissionsUnknown {
+		t.Errorf("b.example = %+v, want read-only missing %s", z, PermissionDNSEdit)
+	}
+}
+
+// deny 策略优先于 allow 策略
+func TestCloudflareZonesDenyPolicy(t *testing.T) {
+	c := newTestCloudflare(t, `[
+		{"id":"p1","effect":"allow","resources":{"com.cloudflare.api.account.zone.*":"*"},"permission_groups":[{"id":"g1","name":"DNS Write"}]},
+		{"id":"p2","effect":"deny","resources":{"com.cloudflare.api.account.zone.z2":"*"},"permission_groups":[{"id":"g1","name":"DNS Write"}]}
+	]`)
+
+	zones, err := c.Zones(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z := zones[0]; z.Editable == nil || !*z.Editable {
+		t.Errorf("a.example = %+v, want editable", z)
+	}
+	if z := zones[1]; z.Editable == nil || *z.Editable {
+		t.Errorf("b.example = %+v, want not editable", z)
+	}
+}
+
+// Token 无权读取自身的权限策略时，明确标记权限未知
+func TestCloudflareZonesPermissionsUnknown(t *testing.T) {
+	c := newTestCloudflare(t, "")
+
+	zones, err := c.Zones(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, z := range zones {
+		if !z.PermissionsUnknown || z.Editable != nil || len(z.Missing) != 0 {
+			t.Errorf("%s = %+v, want permissions unknown", z.Name, z)
+		}
+	}
+}
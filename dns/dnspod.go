@@ -0,0 +1,377 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"idrd/config"
+	"idrd/ip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DNSPod (腾讯云 API 3.0) 常量
+const (
+	defaultDNSPodEndpoint = "dnspod.tencentcloudapi.com"
+	dnspodService         = "dnspod"
+	dnspodAPIVersion      = "2021-03-23"
+	defaultDNSPodLine     = "默认"
+	defaultDNSPodTTL      = 600
+
+	dnspodDomainInterval = 500 * time.Millisecond // 同一域名两次请求的最小间隔
+	dnspodRateCooldown   = 5 * time.Second        // 触发频率限制后该域名的冷却时间
+)
+
+// DNSPodUpdater 通过腾讯云 DNSPod API 3.0 更新记录
+type DNSPodUpdater struct {
+	endpoint  string
+	secretID  string
+	secretKey string
+	line      string // 解析线路，只管理该线路上的记录
+	weight    *int   // 记录权重，设置后使用 ModifyRecord 更新
+	ttl       int    // 新记录的 TTL
+	client    *http.Client
+}
+
+// dnspodLimiter 按域名限制请求频率
+// DNSPod 对单个域名有独立的频率限制，Updater 每次同步都会重新创建，因此状态保存在包级别
+var dnspodLimiter = struct {
+	mu   sync.Mutex
+	next map[string]time.Time // 域名 -> 下次允许请求的时间
+}{next: map[string]time.Time{}}
+
+func init() {
+	Register(Backend{
+		Provider:   "dnspod",
+		Name:       "DNSPod (腾讯云)",
+		Ownership:  OwnershipTXT,
+		TokenLabel: "SecretKey",
+		Properties: []ip.PropertySchema{
+			{Name: "secret_id", Label: "SecretId", Type: "string", Required: true},
+			{Name: "line", Label: "Line (线路)", Type: "string", Default: defaultDNSPodLine, Help: "解析线路，如 默认、电信、联通、移动、境外"},
+			{Name: "weight", Label: "Weight", Type: "number", Help: "记录权重 (0-100)，留空则不设置"},
+			{Name: "ttl", Label: "TTL", Type: "number", Default: strconv.Itoa(defaultDNSPodTTL), Help: "新建记录的 TTL（秒），已有记录保留原 TTL"},
+			{Name: "endpoint", Label: "Endpoint", Type: "string", Default: defaultDNSPodEndpoint},
+		},
+		New: newDNSPodUpdater,
+		Validate: func(a *config.DNSAccount) error {
+			if a.APIToken == "" {
+				return fmt.Errorf("SecretKey required")
+			}
+			if w := a.Properties["weight"]; w != "" {
+				if n, err := strconv.Atoi(w); err != nil || n < 0 || n > 100 {
+					return fmt.Errorf("invalid weight %s (0-100)", w)
+				}
+			}
+			if ttl := a.Properties["ttl"]; ttl != "" {
+				if n, err := strconv.Atoi(ttl); err != nil || n <= 0 {
+					return fmt.Errorf("invalid ttl %s", ttl)
+				}
+			}
+			return nil
+		},
+		MaxConcurrency: 2, // DNSPod API 按账户限速，并行过多会触发 RequestLimitExceeded
+	})
+}
+
+// newDNSPodUpdater 根据账户配置创建 DNSPod 客户端
+func newDNSPodUpdater(account config.DNSAccount) (Updater, error) {
+	props := account.Properties
+	if props["secret_id"] == "" || account.APIToken == "" {
+		return nil, fmt.Errorf("SecretId or SecretKey is empty")
+	}
+
+	u := &DNSPodUpdater{
+		endpoint:  props["endpoint"],
+		secretID:  props["secret_id"],
+		secretKey: account.APIToken,
+		line:      props["line"],
+		ttl:       defaultDNSPodTTL,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	if u.endpoint == "" {
+		u.endpoint = defaultDNSPodEndpoint
+	}
+	if u.line == "" {
+		u.line = defaultDNSPodLine
+	}
+	if ttl, err := strconv.Atoi(props["ttl"]); err == nil && ttl > 0 {
+		u.ttl = ttl
+	}
+	if w, err := strconv.Atoi(props["weight"]); err == nil {
+		u.weight = &w
+	}
+	return u, nil
+}
+
+// waitDomain 等待直到允许向该域名发送请求，并预留下一个时间片
+func waitDomain(ctx context.Context, domain string) error {
+	dnspodLimiter.mu.Lock()
+	now := time.Now()
+	at := dnspodLimiter.next[domain]
+	if at.Before(now) {
+		at = now
+	}
+	dnspodLimiter.next[domain] = at.Add(dnspodDomainInterval)
+	dnspodLimiter.mu.Unlock()
+
+	if wait := time.Until(at); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return nil
+}
+
+// coolDownDomain 触发频率限制后推迟该域名的下一次请求
+func coolDownDomain(domain string) {
+	dnspodLimiter.mu.Lock()
+	defer dnspodLimiter.mu.Unlock()
+	if next := time.Now().Add(dnspodRateCooldown); next.After(dnspodLimiter.next[domain]) {
+		dnspodLimiter.next[domain] = next
+	}
+}
+
+// isDNSPodRateLimit 判断错误码是否为频率限制
+func isDNSPodRateLimit(code string) bool {
+	return strings.HasPrefix(code, "RequestLimitExceeded") || strings.Contains(code, "FrequencyLimit")
+}
+
+// dnspodErrorClass 按错误码前缀分类（如 AuthFailure.SignatureFailure），未知错误码视为临时错误
+func dnspodErrorClass(code string) string {
+	switch {
+	case isDNSPodRateLimit(code):
+		return ErrorClassRateLimit
+	case strings.HasPrefix(code, "AuthFailure") || strings.HasPrefix(code, "UnauthorizedOperation"):
+		return ErrorClassAuth
+	case strings.HasPrefix(code, "ResourceNotFound"):
+		return ErrorClassNotFound
+	case strings.HasPrefix(code, "InvalidParameter") || strings.HasPrefix(code, "MissingParameter"):
+		return ErrorClassValidation
+	}
+	return ErrorClassTransient
+}
+
+// call 调用 API 3.0 接口：TC3-HMAC-SHA256 签名，解析 Response 到 out
+// domain 非空时按域名限速
+func (u *DNSPodUpdater) call(ctx context.Context, domain, action string, params map[string]any, out any) error {
+	if domain != "" {
+		if err := waitDomain(ctx, domain); err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+u.endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("X-TC-Action", action)
+	req.Header.Set("X-TC-Version", dnspodAPIVersion)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("Authorization", tc3Authorization(u.secretID, u.secretKey, u.endpoint, dnspodService, payload, timestamp))
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpStatusError("dnspod", action, resp.StatusCode)
+	}
+
+	// 业务错误在 HTTP 200 的 Response.Error 中返回
+	var envelope struct {
+		Response json.RawMessage `json:"Response"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return fmt.Errorf("%s: 解析响应失败: %w", action, err)
+	}
+	var apiErr struct {
+		Error *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+	}
+	if err := json.Unmarshal(envelope.Response, &apiErr); err == nil && apiErr.Error != nil {
+		if domain != "" && isDNSPodRateLimit(apiErr.Error.Code) {
+			coolDownDomain(domain)
+		}
+		return &ProviderError{Provider: "dnspod", Code: apiErr.Error.Code, Message: apiErr.Error.Message, Class: dnspodErrorClass(apiErr.Error.Code)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Response, out)
+}
+
+// tc3Authorization 计算腾讯云 API 3.0 的 TC3-HMAC-SHA256 签名头
+// 签名的头部固定为 content-type 和 host
+func tc3Authorization(secretID, secretKey, host, service string, payload []byte, timestamp int64) string {
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	requestHash := sha256.Sum256([]byte(tc3CanonicalRequest(host, payload)))
+
+	scope := date + "/" + service + "/tc3_request"
+	stringToSign := strings.Join([]string{
+		"TC3-HMAC-SHA256",
+		strconv.FormatInt(timestamp, 10),
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	secretDate := hmacSHA256([]byte("TC3"+secretKey), date)
+	secretService := hmacSHA256(secretDate, service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	return fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s", secretID, scope, signature)
+}
+
+// tc3CanonicalRequest 构造 POST / 请求的规范请求串（签名头部为 content-type 和 host）
+func tc3CanonicalRequest(host string, payload []byte) string {
+	payloadHash := sha256.Sum256(payload)
+	return strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		"content-type:application/json; charset=utf-8\nhost:" + host + "\n",
+		"content-type;host",
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// ListRecords 列出 zone 中指定名称、类型且位于配置线路上的记录
+func (u *DNSPodUpdater) ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error) {
+	subdomain := RelativeName(name, zone)
+
+	var resp struct {
+		RecordList []struct {
+			RecordID uint64 `json:"RecordId"`
+			Name     string `json:"Name"`
+			Type     string `json:"Type"`
+			Value    string `json:"Value"`
+			TTL      int    `json:"TTL"`
+			Line     string `json:"Line"`
+		} `json:"RecordList"`
+	}
+	err := u.call(ctx, zone, "DescribeRecordList", map[string]any{
+		"Domain":     zone,
+		"Subdomain":  subdomain,
+		"RecordType": recordType,
+		"RecordLine": u.line,
+	}, &resp)
+	if err != nil {
+		// 没有匹配的记录时 DNSPod 返回错误而不是空列表
+		if ErrorCode(err) == "ResourceNotFound.NoDataOfRecord" {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []Record
+	for _, r := range resp.RecordList {
+		if r.Name != subdomain || r.Type != recordType || r.Line != u.line {
+			continue
+		}
+		records = append(records, Record{
+			ID:      strconv.FormatUint(r.RecordID, 10),
+			Type:    r.Type,
+			Name:    FQDN(r.Name, zone),
+			Content: r.Value,
+			TTL:     r.TTL,
+		})
+	}
+	return records, nil
+}
+
+// CreateRecord 在配置线路上创建记录
+func (u *DNSPodUpdater) CreateRecord(ctx context.Context, zone string, rec Record) error {
+	ttl := rec.TTL
+	if ttl <= 0 {
+		ttl = u.ttl
+	}
+	params := map[string]any{
+		"Domain":     zone,
+		"SubDomain":  RelativeName(rec.Name, zone),
+		"RecordType": rec.Type,
+		"RecordLine": u.line,
+		"Value":      rec.Content,
+		"TTL":        ttl,
+	}
+	if u.weight != nil {
+		params["Weight"] = *u.weight
+	}
+	return u.call(ctx, zone, "CreateRecord", params, nil)
+}
+
+// UpdateRecord 更新记录，保留原有 TTL
+// 未设置权重时使用 ModifyDynamicDNS（DDNS 专用接口），否则使用 ModifyRecord 以同时写入权重
+func (u *DNSPodUpdater) UpdateRecord(ctx context.Context, zone string, rec Record) error {
+	recordID, err := strconv.ParseUint(rec.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid record id %s", rec.ID)
+	}
+
+	params := map[string]any{
+		"Domain":     zone,
+		"SubDomain":  RelativeName(rec.Name, zone),
+		"RecordId":   recordID,
+		"RecordLine": u.line,
+		"Value":      rec.Content,
+	}
+	if rec.TTL > 0 {
+		params["Ttl"] = rec.TTL
+	}
+	if u.weight == nil {
+		return u.call(ctx, zone, "ModifyDynamicDNS", params, nil)
+	}
+
+	params["RecordType"] = rec.Type
+	params["Weight"] = *u.weight
+	if ttl, ok := params["Ttl"]; ok {
+		delete(params, "Ttl")
+		params["TTL"] = ttl
+	}
+	return u.call(ctx, zone, "ModifyRecord", params, nil)
+}
+
+// DeleteRecord 删除记录
+func (u *DNSPodUpdater) DeleteRecord(ctx context.Context, zone string, rec Record) error {
+	recordID, err := strconv.ParseUint(rec.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid record id %s", rec.ID)
+	}
+	return u.call(ctx, zone, "DeleteRecord", map[string]any{"Domain": zone, "RecordId": recordID}, nil)
+}
+
+// Verify 验证密钥是否可用（列出账户下的域名）
+func (u *DNSPodUpdater) Verify(ctx context.Context) error {
+	return u.call(ctx, "", "DescribeDomainList", map[string]any{"Limit": 1}, nil)
+}
@@ -0,0 +1,114 @@
+package dns
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"idrd/config"
+)
+
+// 腾讯云 API 3.0 签名文档（签名方法 v3）中的示例：CVM DescribeInstances，签名头部为 content-type 和 host
+func TestTC3Signature(t *testing.T) {
+	payload := []byte(`{"Limit": 1, "Filters": [{"Values": ["\u672a\u547d\u540d"], "Name": "instance-name"}]}`)
+
+	wantCanonical := "POST\n/\n\ncontent-type:application/json; charset=utf-8\nhost:cvm.tencentcloudapi.com\n\ncontent-type;host\n" +
+		"35e9c5b0e3ae67532d3c9f17ead6c90222632e5b1ff7f6e89887f1398934f064"
+	if got := tc3CanonicalRequest("cvm.tencentcloudapi.com", payload); got != wantCanonical {
+		t.Errorf("canonical request =\n%s\nwant\n%s", got, wantCanonical)
+	}
+
+	want := "TC3-HMAC-SHA256 Credential=AKIDz8krbsJ5yKBZQpn74WFkmLPx3*******/2019-02-25/cvm/tc3_request, " +
+		"SignedHeaders=content-type;host, Signature=2230eefd229f582d8b1b891af7107b91597240707d778ab3738f756258d7652c"
+	got := tc3Authorization("AKIDz8krbsJ5yKBZQpn74WFkmLPx3*******", "Gu5t9xGARNpq86cd98joQYCN3*******", "cvm.tencentcloudapi.com", "cvm", payload, 1551113065)
+	if got != want {
+		t.Errorf("authorization =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// newTestDNSPod 启动 TLS 测试服务器，handler 收到的请求已通过签名校验
+func newTestDNSPod(t *testing.T, handler func(action string, params map[string]any) any) *DNSPodUpdater {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, err := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+		if err != nil {
+			t.Errorf("X-TC-Timestamp = %q", r.Header.Get("X-TC-Timestamp"))
+		}
+		if want := tc3Authorization("AKIDtest", "secret", r.Host, dnspodService, body, ts); r.Header.Get("Authorization") != want {
+			t.Errorf("Authorization = %q, want %q", r.Header.Get("Authorization"), want)
+		}
+		if v := r.Header.Get("X-TC-Version"); v != dnspodAPIVersion {
+			t.Errorf("X-TC-Version = %q", v)
+		}
+		var params map[string]any
+		json.Unmarshal(body, &params)
+		json.NewEncoder(w).Encode(map[string]any{"Response": handler(r.Header.Get("X-TC-Action"), params)})
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := newDNSPodUpdater(config.DNSAccount{
+		APIToken:   "secret",
+		Properties: map[string]string{"secret_id": "AKIDtest", "endpoint": strings.TrimPrefix(srv.URL, "https://")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := u.(*DNSPodUpdater)
+	d.client = srv.Client()
+	return d
+}
+
+func TestDNSPodListRecords(t *testing.T) {
+	u := newTestDNSPod(t, func(action string, params map[string]any) any {
+		if action != "DescribeRecordList" || params["Subdomain"] != "home" || params["RecordLine"] != defaultDNSPodLine {
+			t.Errorf("unexpected request %s %v", action, params)
+		}
+		return map[string]any{"RecordList": []map[string]any{
+			{"RecordId": 101, "Name": "home", "Type": "A", "Value": "192.0.2.1", "TTL": 600, "Line": defaultDNSPodLine},
+			{"RecordId": 102, "Name": "home", "Type": "A", "Value": "192.0.2.2", "TTL": 600, "Line": "电信"},
+		}}
+	})
+	got, err := u.ListRecords(t.Context(), "list.example", "home.list.example", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ID != "101" || got[0].Name != "home.list.example" || got[0].Content != "192.0.2.1" {
+		t.Errorf("ListRecords = %+v, want only the record on the default line", got)
+	}
+}
+
+func TestDNSPodErrorClass(t *testing.T) {
+	tests := []struct {
+		code, class string
+	}{
+		{"AuthFailure.SignatureFailure", ErrorClassAuth},
+		{"UnauthorizedOperation.NotManagedUser", ErrorClassAuth},
+		{"ResourceNotFound.NoDataOfDomain", ErrorClassNotFound},
+		{"InvalidParameter.DomainInvalid", ErrorClassValidation},
+		{"MissingParameter", ErrorClassValidation},
+		{"RequestLimitExceeded", ErrorClassRateLimit},
+		{"InternalError", ErrorClassTransient},
+	}
+	for _, tt := range tests {
+		u := newTestDNSPod(t, func(string, map[string]any) any {
+			return map[string]any{"Error": map[string]string{"Code": tt.code, "Message": "test"}, "RequestId": "1"}
+		})
+		err := u.Verify(t.Context())
+		if ErrorCode(err) != tt.code || ErrorClass(err) != tt.class {
+			t.Errorf("%s: code %q class %q, want class %q", tt.code, ErrorCode(err), ErrorClass(err), tt.class)
+		}
+	}
+
+	// 没有匹配的记录时返回空列表而不是错误
+	u := newTestDNSPod(t, func(string, map[string]any) any {
+		return map[string]any{"Error": map[string]string{"Code": "ResourceNotFound.NoDataOfRecord", "Message": "记录列表为空"}}
+	})
+	if got, err := u.ListRecords(t.Context(), "empty.example", "home.empty.example", "A"); err != nil || len(got) != 0 {
+		t.Errorf("ListRecords = %+v, %v; want empty", got, err)
+	}
+}
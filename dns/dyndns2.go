@@ -0,0 +1,246 @@
+package dns
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"idrd/config"
+	"idrd/ip"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDynDNS2URL   = "https://dynupdate.no-ip.com/nic/update"
+	dyndns2UserAgent    = "idrd/1.0 dyndns2-client"
+	dyndns2ServerWait   = 30 * time.Minute // 911/dnserr 后的最短等待时间
+	dyndns2MaxBodyBytes = 4096
+)
+
+// DynDNS2Updater 通过经典 dyndns2 协议 (/nic/update) 更新主机名
+// 兼容 No-IP、Dynu、OVH DynHost、Strato、dynv6 等服务
+type DynDNS2Updater struct {
+	endpoint string
+	username string
+	password string
+	client   *http.Client
+}
+
+// dyndns2Block 被服务器拒绝的主机名
+type dyndns2Block struct {
+	code  string
+	until time.Time // 为零表示直到配置变更前都不再请求
+}
+
+// dyndns2State 记录被阻止的主机名和最近一次成功提交的 IP
+// Updater 每次同步都会重新创建，状态按 (地址, 凭据, 主机名) 保存在包级别；修改配置后键随之变化，阻止自动解除
+var dyndns2State = struct {
+	mu      sync.Mutex
+	blocked map[string]dyndns2Block
+	lastIP  map[string]string
+}{blocked: map[string]dyndns2Block{}, lastIP: map[string]string{}}
+
+// dyndns2Fatal 协议规定客户端在用户修改配置前不得重试的返回码
+var dyndns2Fatal = map[string]string{
+	"badauth":  "用户名或密码错误",
+	"!donator": "该功能需要付费账户",
+	"notfqdn":  "主机名不是完整域名",
+	"nohost":   "主机名不存在或不属于该账户",
+	"numhost":  "一次请求的主机名过多",
+	"abuse":    "主机名因滥用被封禁",
+	"badagent": "客户端被服务器拒绝",
+}
+
+func init() {
+	Register(Backend{
+		Provider:   "dyndns2",
+		Name:       "DynDNS2 (No-IP, Dynu, OVH, Strato, dynv6...)",
+		TokenLabel: "Password",
+		Properties: []ip.PropertySchema{
+			{Name: "url", Label: "Update URL", Type: "string", Required: true, Default: defaultDynDNS2URL, Help: "如 https://api.dynu.com/nic/update、https://www.ovh.com/nic/update、https://dyndns.strato.com/nic/update"},
+			{Name: "username", Label: "Username", Type: "string", Required: true},
+		},
+		New: newDynDNS2Updater,
+		Validate: func(a *config.DNSAccount) error {
+			if a.APIToken == "" {
+				return fmt.Errorf("password required")
+			}
+			if u, err := url.Parse(a.Properties["url"]); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				return fmt.Errorf("invalid url %s", a.Properties["url"])
+			}
+			return nil
+		},
+		MaxConcurrency: 1, // dyndns2 服务通常对频繁更新进行封禁，逐个更新
+	})
+}
+
+// newDynDNS2Updater 根据账户配置创建 dyndns2 客户端
+func newDynDNS2Updater(account config.DNSAccount) (Updater, error) {
+	props := account.Properties
+	if props["username"] == "" || account.APIToken == "" {
+		return nil, fmt.Errorf("username or password is empty")
+	}
+	endpoint := props["url"]
+	if endpoint == "" {
+		endpoint = defaultDynDNS2URL
+	}
+	return &DynDNS2Updater{
+		endpoint: endpoint,
+		username: props["username"],
+		password: account.APIToken,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// stateKey 返回主机名在当前配置下的状态键
+func (u *DynDNS2Updater) stateKey(hostname string) string {
+	sum := sha256.Sum256([]byte(u.endpoint + "\x00" + u.username + "\x00" + u.password + "\x00" + strings.ToLower(hostname)))
+	return hex.EncodeToString(sum[:])
+}
+
+// ListRecords dyndns2 没有查询接口：优先返回本进程最近一次成功提交的 IP，否则解析主机名
+// 避免每次检查都提交未变化的 IP（重复的 nochg 会被部分服务视为滥用）
+func (u *DynDNS2Updater) ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error) {
+	dyndns2State.mu.Lock()
+	last := dyndns2State.lastIP[u.stateKey(name)]
+	dyndns2State.mu.Unlock()
+	if last != "" && RecordTypeFor(last) == recordType {
+		return []Record{{ID: name, Type: recordType, Name: name, Content: last}}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, name)
+	if err != nil {
+		// 解析失败按记录不存在处理，直接提交更新
+		return nil, nil
+	}
+	var records []Record
+	for _, addr := range addrs {
+		if RecordTypeFor(addr.IP.String()) != recordType {
+			continue
+		}
+		records = append(records, Record{ID: name, Type: recordType, Name: name, Content: addr.IP.String()})
+	}
+	return records, nil
+}
+
+// CreateRecord 提交更新（dyndns2 中创建与更新相同）
+func (u *DynDNS2Updater) CreateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.update(ctx, rec.Name, rec.Content)
+}
+
+// UpdateRecord 提交更新
+func (u *DynDNS2Updater) UpdateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.update(ctx, rec.Name, rec.Content)
+}
+
+// update 调用 /nic/update 并解析返回码
+// 致命返回码会阻止该主机名的后续请求，直到配置变更
+func (u *DynDNS2Updater) update(ctx context.Context, hostname, myip string) error {
+	key := u.stateKey(hostname)
+
+	dyndns2State.mu.Lock()
+	block, blocked := dyndns2State.blocked[key]
+	if blocked && !block.until.IsZero() && time.Now().After(block.until) {
+		delete(dyndns2State.blocked, key)
+		blocked = false
+	}
+	dyndns2State.mu.Unlock()
+	if blocked {
+		pe := &ProviderError{Provider: "dyndns2", Code: block.code, Message: "服务器拒绝更新，修改配置后才会重试", Class: dyndns2ErrorClass(block.code), Permanent: true}
+		if !block.until.IsZero() {
+			pe.Message = fmt.Sprintf("服务器要求暂停更新，%s 后重试", block.until.Format("15:04:05"))
+			pe.RetryAfter = time.Until(block.until)
+		}
+		return pe
+	}
+
+	endpoint, err := url.Parse(u.endpoint)
+	if err != nil {
+		return err
+	}
+	query := endpoint.Query()
+	query.Set("hostname", hostname)
+	query.Set("myip", myip)
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(u.username, u.password)
+	req.Header.Set("User-Agent", dyndns2UserAgent)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, dyndns2MaxBodyBytes))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		body = []byte("badauth")
+	}
+
+	fields := strings.Fields(strings.TrimSpace(string(body)))
+	if len(fields) == 0 {
+		return fmt.Errorf("dyndns2: 空响应 (HTTP %d)", resp.StatusCode)
+	}
+	code := fields[0]
+
+	switch {
+	case code == "good" || code == "nochg":
+		dyndns2State.mu.Lock()
+		dyndns2State.lastIP[key] = myip
+		dyndns2State.mu.Unlock()
+		return nil
+
+	case dyndns2Fatal[code] != "":
+		dyndns2State.mu.Lock()
+		dyndns2State.blocked[key] = dyndns2Block{code: code}
+		dyndns2State.mu.Unlock()
+		log.Printf("⛔ dyndns2 服务器拒绝 %s (%s)，修改配置前不再重试", hostname, code)
+		return &ProviderError{Provider: "dyndns2", Code: code, Message: dyndns2Fatal[code], Class: dyndns2ErrorClass(code), Permanent: true}
+
+	case code == "911" || code == "dnserr":
+		until := time.Now().Add(dyndns2ServerWait)
+		dyndns2State.mu.Lock()
+		dyndns2State.blocked[key] = dyndns2Block{code: code, until: until}
+		dyndns2State.mu.Unlock()
+		return &ProviderError{Provider: "dyndns2", Code: code, Message: "服务器故障，30 分钟内不再重试", Class: dyndns2ErrorClass(code), Permanent: true, RetryAfter: dyndns2ServerWait}
+
+	default:
+		return &ProviderError{Provider: "dyndns2", Code: code, Message: strings.TrimSpace(string(body)), Class: httpErrorClass(resp.StatusCode)}
+	}
+}
+
+// dyndns2ErrorClass 按返回码分类错误
+// 911/dnserr 是服务器故障，但服务器要求暂停更新，RetryAfter 超出同步的剩余时间，Manager 不会立即重试
+func dyndns2ErrorClass(code string) string {
+	switch code {
+	case "badauth":
+		return ErrorClassAuth
+	case "nohost":
+		return ErrorClassNotFound
+	case "911", "dnserr":
+		return ErrorClassTransient
+	}
+	return ErrorClassValidation
+}
+
+// Verify dyndns2 没有只读接口，无法在不提交更新的情况下验证凭据，仅检查是否已配置
+func (u *DynDNS2Updater) Verify(ctx context.Context) error {
+	if u.username == "" || u.password == "" {
+		return fmt.Errorf("username or password is empty")
+	}
+	return nil
+}
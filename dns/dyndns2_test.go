@@ -0,0 +1,46 @@
+package dns
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"idrd/config"
+)
+
+func TestDynDNS2ErrorClass(t *testing.T) {
+	tests := []struct {
+		body      string
+		wantClass string
+		permanent bool
+	}{
+		{"badauth", ErrorClassAuth, true},
+		{"nohost", ErrorClassNotFound, true},
+		{"notfqdn", ErrorClassValidation, true},
+		{"abuse", ErrorClassValidation, true},
+		{"911", ErrorClassTransient, false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, tt.body)
+		}))
+		u, err := newDynDNS2Updater(config.DNSAccount{APIToken: "secret", Properties: map[string]string{"username": "user", "url": srv.URL}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rec := Record{Type: "A", Name: "home.example.com", Content: "192.0.2.1"}
+		for attempt, request := range []string{"response", "blocked"} {
+			err := u.UpdateRecord(t.Context(), "example.com", rec)
+			if ErrorCode(err) != tt.body || ErrorClass(err) != tt.wantClass || IsPermanent(err) != tt.permanent {
+				t.Errorf("%s (%s): code %q class %q permanent %v, want %q %v", tt.body, request, ErrorCode(err), ErrorClass(err), IsPermanent(err), tt.wantClass, tt.permanent)
+			}
+			// 服务器故障时要求的等待时间超出同步的剩余时间，Manager 不会立即重试
+			if tt.body == "911" && retryAfter(err) <= 0 {
+				t.Errorf("911 (attempt %d): no RetryAfter", attempt+1)
+			}
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,791 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"idrd/config"
+	"idrd/db"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 重试配置常量
+const (
+	maxRetries     = 3                // 最大重试次数
+	initialBackoff = 1 * time.Second  // 初始退避时间
+	maxBackoff     = 10 * time.Second // 最大退避时间
+)
+
+// Manager 遍历所有 DNS 账户，通过对应服务商的 Updater 同步记录
+type Manager struct {
+	Config *config.SafeConfig
+	DB     *db.DB
+
+	syncMu  sync.Mutex        // 串行化 IP 变化、设备推送和定期校准触发的同步
+	planned map[string]string // 演练模式：账户|域名|类型 -> 最近一次计划（动作|当前|期望|错误），由 syncMu 保护
+
+	conflictMu sync.Mutex
+	conflicts  map[string]Conflict // 账户|域名|类型 -> 所有权冲突
+	stale      map[string]bool     // 完整同步中尚未再次检查的冲突
+
+	ipsMu sync.Mutex
+	ips   []string // 所有出口的当前 IP（多 WAN），rrset 模式使用
+
+	cacheOnce sync.Once // 首次使用时从数据库加载 Zone ID 缓存
+
+	propMu      sync.Mutex
+	propagation map[string]*propagationCheck // 账户|域名|类型 -> 最近一次更新的传播检查
+
+	pushMu sync.Mutex
+	pushed map[string]string // 主机名|类型 -> 设备推送的 IP，完整同步和校准不会改为本机 IP
+
+	// OnReport 每次同步结束后调用（如广播到 WebSocket 客户端），可为 nil
+	OnReport func(report *Report)
+}
+
+// Conflict 因所有权检查而未修改的记录
+type Conflict struct {
+	Account string    `json:"account"`
+	Domain  string    `json:"domain"`
+	Type    string    `json:"type"`
+	Owner   string    `json:"owner,omitempty"` // 其他实例的所有者标识，为空表示记录没有标记
+	Error   string    `json:"error"`
+	Since   time.Time `json:"since"`
+}
+
+// UpdateIP 更新所有配置的 DNS 记录到新 IP，返回每条记录的结果，任一记录失败时同时返回错误
+func (m *Manager) UpdateIP(newIP string) (*Report, error) {
+	report := m.sync(newIP, nil, false)
+	return report, report.Err()
+}
+
+// UpdateHostnames 只更新指定主机名（完整域名）对应的记录（dyndns2 设备推送），任一记录失败时返回错误
+// 之后的完整同步跳过这些主机名，校准时按推送的 IP 校准，直到设备再次推送
+func (m *Manager) UpdateHostnames(newIP string, hostnames []string) (*Report, error) {
+	recordType := RecordTypeFor(newIP)
+	wanted := make(map[string]bool, len(hostnames))
+	m.pushMu.Lock()
+	if m.pushed == nil {
+		m.pushed = make(map[string]string)
+	}
+	for _, h := range hostnames {
+		h = strings.ToLower(h)
+		wanted[h] = true
+		m.pushed[h+"|"+recordType] = newIP
+	}
+	m.pushMu.Unlock()
+	report := m.sync(newIP, func(fqdn string) bool { return wanted[strings.ToLower(fqdn)] }, false)
+	return report, report.Err()
+}
+
+// Reconcile 将所有记录与期望状态（当前 IP 和记录属性）比对并修复偏差
+// 与 UpdateIP 不同，一致的记录不写入 dns_updates，避免定期校准产生大量重复记录
+// 任一记录失败时返回错误
+func (m *Manager) Reconcile(currentIP string) (*Report, error) {
+	report := m.sync(currentIP, nil, true)
+	return report, report.Err()
+}
+
+// Conflicts 返回当前的所有权冲突（按域名排序）
+func (m *Manager) Conflicts() []Conflict {
+	m.conflictMu.Lock()
+	defer m.conflictMu.Unlock()
+	list := make([]Conflict, 0, len(m.conflicts))
+	for _, c := range m.conflicts {
+		list = append(list, c)
+	}
+	slices.SortFunc(list, func(a, b Conflict) int { return strings.Compare(a.Domain, b.Domain) })
+	return list
+}
+
+// trackConflict 根据同步结果记录或清除所有权冲突，返回是否为新发现的冲突
+func (m *Manager) trackConflict(accountName, domain, recordType string, err error) bool {
+	m.conflictMu.Lock()
+	defer m.conflictMu.Unlock()
+	key := accountName + "|" + domain + "|" + recordType
+	delete(m.stale, key)
+	var oe *OwnershipError
+	if !errors.As(err, &oe) {
+		delete(m.conflicts, key)
+		return false
+	}
+	if _, exists := m.conflicts[key]; exists {
+		return false
+	}
+	if m.conflicts == nil {
+		m.conflicts = map[string]Conflict{}
+	}
+	m.conflicts[key] = Conflict{Account: accountName, Domain: domain, Type: recordType, Owner: oe.Owner, Error: oe.Error(), Since: time.Now()}
+	return true
+}
+
+// pruneConflicts 在完整同步开始时标记所有冲突，结束时删除未再次出现的冲突（如记录已从配置中移除）
+func (m *Manager) pruneConflicts(start bool) {
+	m.conflictMu.Lock()
+	defer m.conflictMu.Unlock()
+	if start {
+		m.stale = make(map[string]bool, len(m.conflicts))
+		for key := range m.conflicts {
+			m.stale[key] = true
+		}
+		return
+	}
+	for key := range m.stale {
+		delete(m.conflicts, key)
+	}
+	m.stale = nil
+}
+
+// Hostnames 返回所有已配置记录的完整域名
+func (m *Manager) Hostnames() []string {
+	var names []string
+	for _, account := range m.Config.Get().DNSAccounts {
+		for _, zone := range account.Zones {
+			for _, record := range zone.Records {
+				names = append(names, FQDN(record.Name, zone.ZoneName))
+			}
+		}
+	}
+	return names
+}
+
+// sync 同步记录到 newIP，filter 为 nil 时同步全部记录
+// reconcile 为 true 时（定期校准）不记录和输出未变化的记录
+// 结束后将报告交给 OnReport（如果设置）
+func (m *Manager) sync(newIP string, filter func(fqdn string) bool, reconcile bool) *Report {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+	m.loadCaches()
+
+	report := newReport(newIP)
+	report.Reconcile = reconcile
+	defer m.publish(report)
+
+	cfg := m.Config.Get()
+	full := filter == nil
+
+	// 设备推送的主机名以推送的 IP 为准，完整同步不改为本机 IP
+	var pushed map[string]string
+	if full && cfg.DynDNSServer.Enabled {
+		pushed = m.pushedHostnames(newIP)
+		if len(pushed) > 0 {
+			filter = func(fqdn string) bool {
+				_, ok := pushed[strings.ToLower(fqdn)]
+				return !ok
+			}
+		}
+	}
+
+	if cfg.DryRun {
+		report.DryRun = true
+		m.dryRun(&cfg, report, filter)
+		return report
+	}
+
+	if full {
+		m.pruneConflicts(true)
+		defer m.pruneConflicts(false)
+	}
+
+	for _, rec := range m.syncAccounts(&cfg, newIP, filter, reconcile) {
+		report.add(rec)
+	}
+
+	// 校准时推送的主机名按各自推送的 IP 校准
+	if reconcile {
+		byIP := make(map[string]map[string]bool)
+		for host, ip := range pushed {
+			if byIP[ip] == nil {
+				byIP[ip] = make(map[string]bool)
+			}
+			byIP[ip][host] = true
+		}
+		for ip, hosts := range byIP {
+			for _, rec := range m.syncAccounts(&cfg, ip, func(fqdn string) bool { return hosts[strings.ToLower(fqdn)] }, reconcile) {
+				report.add(rec)
+			}
+		}
+	}
+
+	if full {
+		for _, rec := range m.handleOrphans(&cfg) {
+			report.add(rec)
+		}
+	}
+	m.checkPropagation(&cfg, report)
+	return report
+}
+
+// syncAccounts 各账户并行同步，账户内的 zone 由有界工作池处理，全部完成后汇总结果
+func (m *Manager) syncAccounts(cfg *config.AppConfig, newIP string, filter func(fqdn string) bool, reconcile bool) []RecordReport {
+	results := make(chan []RecordReport, len(cfg.DNSAccounts))
+	var wg sync.WaitGroup
+	for _, account := range cfg.DNSAccounts {
+		wg.Go(func() {
+			results <- m.syncAccount(cfg, account, newIP, filter, reconcile)
+		})
+	}
+	wg.Wait()
+	close(results)
+
+	var records []RecordReport
+	for r := range results {
+		records = append(records, r...)
+	}
+	return records
+}
+
+// pushedHostnames 返回与 ip 同类型、且设备推送的 IP 与 ip 不同的主机名 -> 推送的 IP
+func (m *Manager) pushedHostnames(ip string) map[string]string {
+	suffix := "|" + RecordTypeFor(ip)
+	m.pushMu.Lock()
+	defer m.pushMu.Unlock()
+	hosts := make(map[string]string)
+	for key, pushedIP := range m.pushed {
+		if host, ok := strings.CutSuffix(key, suffix); ok && pushedIP != ip {
+			hosts[host] = pushedIP
+		}
+	}
+	return hosts
+}
+
+// publish 完成报告，输出摘要并交给 OnReport
+func (m *Manager) publish(report *Report) {
+	report.finish()
+	switch {
+	case report.Failed > 0:
+		log.Printf("❌ DNS 同步完成: %s (%dms)", report.Summary(), report.Duration)
+	case report.Changed > 0 || !report.Reconcile:
+		log.Printf("✅ DNS 同步完成: %s (%dms)", report.Summary(), report.Duration)
+	}
+	if m.OnReport != nil {
+		m.OnReport(report)
+	}
+}
+
+// accountConcurrency 返回账户同时处理的 zone 数：未设置时使用默认值，且不超过服务商的速率限制
+func accountConcurrency(account config.DNSAccount) int {
+	n := account.Concurrency
+	if n <= 0 {
+		n = config.DefaultAccountConcurrency
+	}
+	if b, ok := backends[account.ProviderName()]; ok && b.MaxConcurrency > 0 {
+		n = min(n, b.MaxConcurrency)
+	}
+	return n
+}
+
+// syncAccount 同步账户下的所有 zone，最多 accountConcurrency 个 zone 同时进行
+func (m *Manager) syncAccount(cfg *config.AppConfig, account config.DNSAccount, newIP string, filter func(fqdn string) bool, reconcile bool) []RecordReport {
+	var zones []config.Zone
+	for _, zone := range account.Zones {
+		if filter != nil {
+			zone = filterZone(zone, filter)
+			if len(zone.Records) == 0 {
+				continue
+			}
+		}
+		zones = append(zones, zone)
+	}
+
+	var records []RecordReport
+	updater, err := NewUpdater(account)
+	if err != nil {
+		log.Printf("❌ 创建 DNS 客户端失败 (账户: %s, 服务商: %s): %v", account.Name, account.ProviderName(), err)
+		err = fmt.Errorf("创建 DNS 客户端失败: %w", err)
+		for _, zone := range zones {
+			for _, record := range zone.Records {
+				rec, start := recordReport(account.Name, zone.ZoneName, FQDN(record.Name, zone.ZoneName), RecordTypeFor(newIP), newIP)
+				rec.done(start, err)
+				records = append(records, rec)
+			}
+		}
+		return records
+	}
+
+	own := ownershipFor(cfg, account)
+	results := make(chan []RecordReport, len(zones))
+	sem := make(chan struct{}, accountConcurrency(account))
+	var wg sync.WaitGroup
+	for _, zone := range zones {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results <- m.syncZone(account.Name, updater, own, zone, newIP, reconcile)
+		})
+	}
+	wg.Wait()
+	close(results)
+
+	for r := range results {
+		records = append(records, r...)
+	}
+	return records
+}
+
+// syncZone 同步单个 zone 中的记录，每个 zone 使用独立的超时
+func (m *Manager) syncZone(accountName string, updater Updater, own *ownership, zone config.Zone, newIP string, reconcile bool) []RecordReport {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second) // 增加超时以容纳重试
+	defer cancel()
+
+	recordType := RecordTypeFor(newIP)
+
+	// 支持批量提交的服务商：整个 zone 的变更合并为一次提交
+	if batch, ok := updater.(BatchUpdater); ok {
+		return m.syncZoneBatch(ctx, accountName, batch, own, zone, recordType, newIP, reconcile)
+	}
+
+	// 遍历并更新每个子域名
+	var records []RecordReport
+	for _, record := range zone.Records {
+		fullDomain := FQDN(record.Name, zone.ZoneName)
+		rc := zone.Effective(record)
+		desired := desiredRecord(rc, fullDomain, recordType, newIP)
+		own.mark(&desired)
+
+		contents := []string{newIP}
+		if rc.RRSet {
+			contents = m.rrsetContents(newIP, recordType)
+		}
+		rec, start := recordReport(accountName, zone.ZoneName, fullDomain, recordType, strings.Join(contents, ", "))
+		rec.proxied = desired.Proxied != nil && *desired.Proxied
+		var ids []string
+		err := withRetry(ctx, fmt.Sprintf("更新 DNS 记录 (%s)", fullDomain), func() error {
+			rec.Attempts++
+			var err error
+			if rc.RRSet {
+				ids, err = m.syncRRSet(ctx, updater, own, zone.ZoneName, desired, rc.Adopt, contents, &rec)
+			} else {
+				ids, err = m.syncRecord(ctx, updater, own, zone.ZoneName, desired, rc, &rec)
+			}
+			return err
+		})
+		rec.done(start, err)
+		records = append(records, rec)
+
+		if err == nil {
+			m.trackManaged(accountName, zone.ZoneName, desired, ids, contents)
+		}
+		if isOwnershipError(err) {
+			log.Printf("⛔ %v", err)
+		} else if err != nil {
+			log.Printf("❌ 更新 DNS 记录失败 (%s): %v", fullDomain, err)
+		} else if rec.Action == ActionNoop {
+			if reconcile {
+				continue
+			}
+			log.Printf("ℹ️  IP 未变化，跳过 DNS 更新: %s -> %s", fullDomain, rec.New)
+		}
+		m.recordResult(rec)
+	}
+	return records
+}
+
+// filterZone 返回只包含 filter 命中记录的 zone 副本
+func filterZone(zone config.Zone, filter func(fqdn string) bool) config.Zone {
+	filtered := zone
+	filtered.Records = nil
+	for _, record := range zone.Records {
+		if filter(FQDN(record.Name, zone.ZoneName)) {
+			filtered.Records = append(filtered.Records, record)
+		}
+	}
+	return filtered
+}
+
+// recordResult 将单条记录的同步结果写入 dns_updates
+// 同一所有权冲突只在首次发现时写入，避免定期校准重复记录
+func (m *Manager) recordResult(rec RecordReport) {
+	isNew := m.trackConflict(rec.Account, rec.Domain, rec.Type, rec.err)
+	if m.DB == nil || (isOwnershipError(rec.err) && !isNew) {
+		return
+	}
+	m.DB.AddDNSUpdate(db.DNSUpdate{
+		AccountName: rec.Account,
+		IP:          rec.New,
+		OldContent:  rec.Old,
+		RecordType:  rec.Type,
+		Domain:      rec.Domain,
+		Success:     rec.Error == "",
+		Error:       rec.Error,
+		ErrorCode:   rec.ErrorCode,
+		Action:      rec.Action,
+		DurationMs:  rec.Duration,
+		Attempts:    rec.Attempts,
+	})
+}
+
+// recordPlanned 将演练模式的计划变更写入 dns_updates
+func (m *Manager) recordPlanned(change PlannedChange) {
+	if m.DB == nil {
+		return
+	}
+	m.DB.AddDNSUpdate(db.DNSUpdate{
+		AccountName: change.Account,
+		IP:          change.Desired,
+		OldContent:  change.Current,
+		RecordType:  change.Type,
+		Domain:      change.Domain,
+		Success:     change.Action != ActionError && change.Action != ActionConflict,
+		Error:       change.Error,
+		Action:      change.Action,
+		DryRun:      true,
+	})
+}
+
+// syncZoneBatch 查询 zone 中每条记录，将需要变更的记录合并为一次 UpsertRecords 提交
+// 提交返回（变更已生效）后才记录成功，返回每条记录的结果
+// 启用所有权时，新建和接管的记录的伴随 TXT 记录在同一批次中写入
+// rrset 模式的记录以同名同类型的多条记录提交，由服务商整体替换 RRset
+func (m *Manager) syncZoneBatch(ctx context.Context, accountName string, u BatchUpdater, own *ownership, zone config.Zone, recordType, ip string, reconcile bool) []RecordReport {
+	var results []RecordReport
+	fail := func(rec RecordReport, start time.Time, err error) {
+		rec.done(start, err)
+		if isOwnershipError(err) {
+			log.Printf("⛔ %v", err)
+		} else {
+			log.Printf("❌ 更新 DNS 记录失败 (%s): %v", rec.Domain, err)
+		}
+		m.recordResult(rec)
+		results = append(results, rec)
+	}
+
+	// batchEntry 提交成功后才记录结果的记录（变更和只写入所有权标记的接管记录）
+	type batchEntry struct {
+		rec      RecordReport
+		record   Record   // 作为受管记录跟踪的记录
+		ids      []string // 受管记录的 ID（新建记录为空）
+		contents []string
+	}
+
+	start := time.Now()
+	var changes, markers []Record
+	var pending []batchEntry
+
+	// addMarkers 为新建或接管的记录加入伴随 TXT 记录组（备注方式的标记随记录本身写入）
+	addMarkers := func(desired Record) error {
+		if own == nil || own.method != OwnershipTXT {
+			return nil
+		}
+		ms, err := own.batchMarkers(ctx, u, zone.ZoneName, desired)
+		markers = append(markers, ms...)
+		return err
+	}
+	for _, record := range zone.Records {
+		fullDomain := FQDN(record.Name, zone.ZoneName)
+		rc := zone.Effective(record)
+		desired := desiredRecord(rc, fullDomain, recordType, ip)
+		own.mark(&desired)
+		contents := []string{ip}
+		if rc.RRSet {
+			contents = m.rrsetContents(ip, recordType)
+		}
+		rec, recStart := recordReport(accountName, zone.ZoneName, fullDomain, recordType, strings.Join(contents, ", "))
+		rec.proxied = desired.Proxied != nil && *desired.Proxied
+
+		var records []Record
+		err := withRetry(ctx, fmt.Sprintf("查询 DNS 记录 (%s)", fullDomain), func() error {
+			rec.Attempts++
+			var err error
+			records, err = u.ListRecords(ctx, zone.ZoneName, fullDomain, recordType)
+			return err
+		})
+		if err != nil {
+			fail(rec, recStart, err)
+			continue
+		}
+
+		if rc.RRSet {
+			change, err := batchRRSet(ctx, u, own, zone.ZoneName, desired, rc.Adopt, contents, records, &rec)
+			if err != nil {
+				fail(rec, recStart, err)
+				continue
+			}
+			if rec.Action == ActionNoop {
+				ids := make([]string, 0, len(records))
+				for _, r := range records {
+					ids = append(ids, r.ID)
+				}
+				m.trackManaged(accountName, zone.ZoneName, desired, ids, contents)
+				rec.done(recStart, nil)
+				results = append(results, rec)
+				if !reconcile {
+					log.Printf("ℹ️  IP 未变化，跳过 DNS 更新: %s -> %s", fullDomain, rec.New)
+					m.recordResult(rec)
+				}
+				continue
+			}
+			if rec.Action == ActionCreate || change.adopted {
+				if err := addMarkers(desired); err != nil {
+					fail(rec, recStart, err)
+					continue
+				}
+			}
+			log.Printf("🔄 DNS 记录组需要更新 (%s): %s", fullDomain, strings.Join(change.drift, ", "))
+			changes = append(changes, change.records...)
+			pending = append(pending, batchEntry{rec: rec, record: desired, contents: contents})
+			continue
+		}
+
+		// 批量提交按 RRset 整体写入，单值写入会删除同名的其他值
+		if len(records) > 1 {
+			fail(rec, recStart, Permanent(fmt.Errorf("%s 存在 %d 条 %s 记录，该服务商按 RRset 整体写入，单值更新会删除其他记录，请为该记录启用 rrset", fullDomain, len(records), recordType)))
+			continue
+		}
+		current, ok := selectRecord(records, rc.Select)
+		if !ok {
+			if rc.Select != nil && rc.Select.ID != "" {
+				fail(rec, recStart, Permanent(fmt.Errorf("记录 ID %s 不存在", rc.Select.ID)))
+				continue
+			}
+			if err := addMarkers(desired); err != nil {
+				fail(rec, recStart, err)
+				continue
+			}
+			rec.Action = ActionCreate
+			change := withSelectorTag(desired, rc.Select)
+			changes = append(changes, change)
+			pending = append(pending, batchEntry{rec: rec, record: change, contents: contents})
+			continue
+		}
+		rec.Old = current.Content
+		adopted := false
+		if own != nil {
+			if adopted, err = own.checkOrAdopt(ctx, u, zone.ZoneName, current, rc.Adopt); err != nil {
+				fail(rec, recStart, err)
+				continue
+			}
+			if adopted {
+				if err := addMarkers(desired); err != nil {
+					fail(rec, recStart, err)
+					continue
+				}
+			}
+		}
+		drift := driftFields(current, desired)
+		if len(drift) == 0 && adopted {
+			// 记录本身无需修改，但接管时写入的所有权标记与批次一起提交
+			rec.Action = ActionUpdate
+			pending = append(pending, batchEntry{rec: rec, record: current, ids: []string{current.ID}, contents: contents})
+			continue
+		}
+		if len(drift) == 0 {
+			m.trackManaged(accountName, zone.ZoneName, desired, []string{current.ID}, contents)
+			rec.Action = ActionNoop
+			rec.done(recStart, nil)
+			results = append(results, rec)
+			if !reconcile {
+				log.Printf("ℹ️  IP 未变化，跳过 DNS 更新: %s -> %s", fullDomain, ip)
+				m.recordResult(rec)
+			}
+			continue
+		}
+		log.Printf("🔄 DNS 记录需要更新 (%s): %s", fullDomain, strings.Join(drift, ", "))
+		rec.Action = ActionUpdate
+		change := applyDesired(current, desired)
+		changes = append(changes, change)
+		pending = append(pending, batchEntry{rec: rec, record: change, ids: []string{current.ID}, contents: contents})
+	}
+
+	if len(changes) == 0 && len(markers) == 0 {
+		return results
+	}
+
+	attempts := 0
+	err := withRetry(ctx, fmt.Sprintf("提交 DNS 变更 (%s, %d 条记录)", zone.ZoneName, len(markers)+len(changes)), func() error {
+		attempts++
+		return u.UpsertRecords(ctx, zone.ZoneName, append(slices.Clip(markers), changes...))
+	})
+	for _, entry := range pending {
+		rec := entry.rec
+		rec.Attempts += attempts
+		rec.done(start, err)
+		if err != nil {
+			log.Printf("❌ 更新 DNS 记录失败 (%s): %v", rec.Domain, err)
+		} else {
+			log.Printf("✅ 更新 DNS 记录成功: %s -> %s", rec.Domain, rec.New)
+			m.trackManaged(accountName, zone.ZoneName, entry.record, entry.ids, entry.contents)
+		}
+		m.recordResult(rec)
+		results = append(results, rec)
+	}
+	return results
+}
+
+// syncRecord 使单个记录与期望一致：不存在则创建，内容或属性不同则更新（无重试）
+// 将执行的动作（ActionCreate、ActionUpdate 或 ActionNoop）和原内容填入 rec，返回受管记录的 ID（新建记录的 ID 未知，返回空）
+// 同名有多条记录时按 rc.Select 选择，启用所有权（own 非 nil）时不修改没有本实例标记的记录，除非 rc.Adopt 为 true
+func (m *Manager) syncRecord(ctx context.Context, u Updater, own *ownership, zone string, desired Record, rc config.RecordConfig, rec *RecordReport) ([]string, error) {
+	domain, ip := desired.Name, desired.Content
+
+	// 查找现有记录
+	records, err := u.ListRecords(ctx, zone, domain, desired.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok := selectRecord(records, rc.Select)
+	if !ok {
+		if rc.Select != nil && rc.Select.ID != "" {
+			return nil, Permanent(fmt.Errorf("记录 ID %s 不存在", rc.Select.ID))
+		}
+		// 先写入所有权标记，避免记录创建后标记写入失败导致下次同步被视为他人记录
+		if err := own.claim(ctx, u, zone, desired); err != nil {
+			return nil, err
+		}
+		// 创建新记录
+		if err := u.CreateRecord(ctx, zone, withSelectorTag(desired, rc.Select)); err != nil {
+			return nil, err
+		}
+		log.Printf("✅ 创建 DNS 记录成功: %s -> %s", domain, ip)
+		rec.Action = ActionCreate
+		return nil, nil
+	}
+
+	if rc.Select == nil && len(records) > 1 {
+		log.Printf("⚠️  %s 存在 %d 条 %s 记录，只管理第一条（可配置 select 或 rrset）", domain, len(records), desired.Type)
+	}
+
+	// 更新现有记录
+	adopted := false
+	if own != nil {
+		if adopted, err = own.checkOrAdopt(ctx, u, zone, current, rc.Adopt); err != nil {
+			return nil, err
+		}
+		if adopted {
+			if err := own.claim(ctx, u, zone, desired); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	rec.Old = current.Content
+	drift := driftFields(current, desired)
+	if len(drift) == 0 {
+		rec.Action = ActionNoop
+		if adopted {
+			rec.Action = ActionUpdate // 记录未变化，但已写入所有权标记
+		}
+		return []string{current.ID}, nil // IP 和属性均未变化
+	}
+
+	log.Printf("🔄 DNS 记录需要更新 (%s): %s", domain, strings.Join(drift, ", "))
+	if err := u.UpdateRecord(ctx, zone, applyDesired(current, desired)); err != nil {
+		return nil, err
+	}
+	log.Printf("✅ 更新 DNS 记录成功: %s -> %s", domain, ip)
+	rec.Action = ActionUpdate
+	return []string{current.ID}, nil
+}
+
+// desiredRecord 根据记录配置（已继承 zone 默认值）构造期望的记录
+// 按备注选择的记录以选择器的备注为准，保证之后的同步仍能选中
+func desiredRecord(rc config.RecordConfig, name, recordType, ip string) Record {
+	rec := Record{Type: recordType, Name: name, Content: ip, TTL: rc.TTL, Proxied: rc.Proxied}
+	if rc.Select != nil && rc.Select.Comment != "" {
+		rc.Comment = rc.Select.Comment
+	}
+	if rc.Comment != "" {
+		comment := rc.Comment
+		rec.Comment = &comment
+	}
+	if len(rc.Tags) > 0 {
+		rec.Tags = append([]string{}, rc.Tags...)
+	}
+	return rec
+}
+
+// driftFields 返回现有记录与期望不一致的字段
+// 期望中未设置的属性、以及服务商未返回的属性（nil 或 TTL 为 0）不参与比较
+func driftFields(existing, desired Record) []string {
+	var drift []string
+	if existing.Content != desired.Content {
+		drift = append(drift, fmt.Sprintf("content %s -> %s", existing.Content, desired.Content))
+	}
+	if desired.Proxied != nil && existing.Proxied != nil && *existing.Proxied != *desired.Proxied {
+		drift = append(drift, fmt.Sprintf("proxied %t -> %t", *existing.Proxied, *desired.Proxied))
+	}
+	// Cloudflare 代理记录的 TTL 固定为 Auto，不比较
+	proxied := existing.Proxied != nil && *existing.Proxied
+	if desired.Proxied != nil {
+		proxied = *desired.Proxied
+	}
+	if desired.TTL > 0 && existing.TTL > 0 && existing.TTL != desired.TTL && !proxied {
+		drift = append(drift, fmt.Sprintf("ttl %d -> %d", existing.TTL, desired.TTL))
+	}
+	if desired.Comment != nil && existing.Comment != nil && *existing.Comment != *desired.Comment {
+		drift = append(drift, fmt.Sprintf("comment %q -> %q", *existing.Comment, *desired.Comment))
+	}
+	if desired.Tags != nil && existing.Tags != nil && !sameTags(existing.Tags, desired.Tags) {
+		drift = append(drift, fmt.Sprintf("tags %v -> %v", existing.Tags, desired.Tags))
+	}
+	return drift
+}
+
+// applyDesired 在现有记录上应用期望的内容和已设置的属性，未设置的属性保持原值
+func applyDesired(existing, desired Record) Record {
+	rec := existing
+	rec.Content = desired.Content
+	if desired.Proxied != nil {
+		rec.Proxied = desired.Proxied
+	}
+	if desired.TTL > 0 {
+		rec.TTL = desired.TTL
+	}
+	if desired.Comment != nil {
+		rec.Comment = desired.Comment
+	}
+	if desired.Tags != nil {
+		rec.Tags = desired.Tags
+	}
+	return rec
+}
+
+// sameTags 比较两组标签（忽略顺序）
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+// withRetry 以指数退避重试 fn，直到成功、达到最大重试次数或 ctx 结束
+func withRetry(ctx context.Context, desc string, fn func() error) error {
+	var lastErr error
+	backoff := initialBackoff
+
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			// 速率限制时按服务商的 Retry-After 等待，超过剩余时间时直接放弃
+			wait := max(backoff, retryAfter(lastErr))
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+				return fmt.Errorf("等待重试的时间 (%v) 超出剩余时间: %w", wait.Round(time.Second), lastErr)
+			}
+			log.Printf("🔄 重试%s (尝试 %d/%d, %v 后)", desc, attempt, maxRetries, wait.Round(time.Millisecond))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		if IsPermanent(err) {
+			return err
+		}
+		lastErr = err
+	}
+
+	return fmt.Errorf("重试 %d 次后仍失败: %w", maxRetries, lastErr)
+}
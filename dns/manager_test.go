@@ -0,0 +1,79 @@
+package dns
+
+import (
+	"testing"
+
+	"idrd/config"
+
+	dnsmsg "github.com/miekg/dns"
+)
+
+// aRecords 返回测试服务器中 name 的 A 记录内容
+func (zs *testZoneServer) aRecords(name string) []string {
+	zs.mu.Lock()
+	defer zs.mu.Unlock()
+	var contents []string
+	for _, rr := range zs.rrs {
+		if a, ok := rr.(*dnsmsg.A); ok && a.Hdr.Name == name+"." {
+			contents = append(contents, a.A.String())
+		}
+	}
+	return contents
+}
+
+// 设备推送的主机名在之后的校准中保持推送的 IP，偏差按推送的 IP 修复
+func TestReconcileKeepsPushedHostnames(t *testing.T) {
+	zs := startTestZoneServer(t)
+	cfg := &config.AppConfig{
+		DynDNSServer: config.DynDNSServerConfig{Enabled: true},
+		DNSAccounts: []config.DNSAccount{{
+			Name:       "test",
+			Provider:   "rfc2136",
+			APIToken:   testTSIGSecret,
+			Properties: map[string]string{"server": zs.addr, "tsig_key": "idrd-test", "tsig_algorithm": "hmac-sha256"},
+			Zones: []config.Zone{{
+				ZoneName: testZone,
+				Records:  []config.RecordConfig{{Name: "home"}, {Name: "www"}},
+			}},
+		}},
+	}
+	m := &Manager{Config: config.NewSafeConfig(cfg)}
+
+	if _, err := m.UpdateHostnames("198.51.100.7", []string{"Home." + testZone}); err != nil {
+		t.Fatalf("UpdateHostnames: %v", err)
+	}
+	report, err := m.Reconcile("192.0.2.50")
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if got := zs.aRecords("home." + testZone); len(got) != 1 || got[0] != "198.51.100.7" {
+		t.Errorf("pushed hostname after reconcile = %v, want 198.51.100.7", got)
+	}
+	if got := zs.aRecords("www." + testZone); len(got) != 1 || got[0] != "192.0.2.50" {
+		t.Errorf("other hostname after reconcile = %v, want 192.0.2.50", got)
+	}
+	for _, rec := range report.Records {
+		if rec.Domain == "home."+testZone && rec.Action != ActionNoop {
+			t.Errorf("reconcile changed pushed hostname: %+v", rec)
+		}
+	}
+
+	// 记录被其他来源修改后，校准恢复为推送的 IP 而不是本机 IP
+	if err := newTestRFC2136(t, zs.addr, testTSIGSecret, testZone).UpdateRecord(t.Context(), testZone, Record{Type: "A", Name: "home." + testZone, Content: "203.0.113.1", TTL: 300}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Reconcile("192.0.2.50"); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if got := zs.aRecords("home." + testZone); len(got) != 1 || got[0] != "198.51.100.7" {
+		t.Errorf("drifted pushed hostname after reconcile = %v, want 198.51.100.7", got)
+	}
+
+	// 本机 IP 变化时推送的主机名也保持不变
+	if _, err := m.UpdateIP("192.0.2.60"); err != nil {
+		t.Fatalf("UpdateIP: %v", err)
+	}
+	if got := zs.aRecords("home." + testZone); len(got) != 1 || got[0] != "198.51.100.7" {
+		t.Errorf("pushed hostname after UpdateIP = %v, want 198.51.100.7", got)
+	}
+}
@@ -0,0 +1,251 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"idrd/config"
+	"idrd/db"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 孤儿记录的计划动作
+const (
+	ActionDelete = "delete" // 记录已从配置中移除，将删除（orphan_policy 为 delete）
+	ActionOrphan = "orphan" // 记录已从配置中移除，等待确认删除（orphan_policy 为 ask）
+)
+
+// trackManaged 记录同步成功的受管记录，用于之后发现孤儿记录（ID 未知时清理按内容匹配）
+func (m *Manager) trackManaged(accountName, zone string, rec Record, ids, contents []string) {
+	if m.DB == nil {
+		return
+	}
+	known := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != "" {
+			known = append(known, id)
+		}
+	}
+	if len(known) < len(ids) {
+		known = nil
+	}
+	err := m.DB.UpsertManagedRecord(db.ManagedRecord{
+		AccountName: accountName,
+		Zone:        zone,
+		Domain:      rec.Name,
+		RecordType:  rec.Type,
+		RecordIDs:   known,
+		Content:     contents,
+	})
+	if err != nil {
+		log.Printf("⚠️  记录受管 DNS 记录失败 (%s): %v", rec.Name, err)
+	}
+}
+
+// Orphans 返回 idrd 创建或管理过、但已从当前配置中移除的记录
+func (m *Manager) Orphans() ([]db.ManagedRecord, error) {
+	cfg := m.Config.Get()
+	return m.orphans(&cfg)
+}
+
+// orphans 返回不在 cfg 中的受管记录（按账户、zone 和域名比较，不区分记录类型）
+func (m *Manager) orphans(cfg *config.AppConfig) ([]db.ManagedRecord, error) {
+	orphans := []db.ManagedRecord{}
+	if m.DB == nil {
+		return orphans, nil
+	}
+	managed, err := m.DB.GetManagedRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	configured := map[string]bool{}
+	for _, account := range cfg.DNSAccounts {
+		for _, zone := range account.Zones {
+			for _, record := range zone.Records {
+				configured[managedKey(account.Name, zone.ZoneName, FQDN(record.Name, zone.ZoneName))] = true
+			}
+		}
+	}
+	for _, r := range managed {
+		if !configured[managedKey(r.AccountName, r.Zone, r.Domain)] {
+			orphans = append(orphans, r)
+		}
+	}
+	return orphans, nil
+}
+
+func managedKey(account, zone, domain string) string {
+	return account + "|" + strings.ToLower(zone) + "|" + strings.ToLower(domain)
+}
+
+// DeleteOrphans 删除指定的孤儿记录（ids 为 managed_records 的 ID），返回删除成功的数量
+// 仍在配置中的记录会被忽略；删除失败的记录保留在列表中，错误合并返回
+func (m *Manager) DeleteOrphans(ids []int64) (int, error) {
+	m.syncMu.Lock()
+	defer m.syncMu.Unlock()
+	m.loadCaches()
+	cfg := m.Config.Get()
+	results, err := m.deleteOrphans(&cfg, ids)
+	deleted := 0
+	for _, rec := range results {
+		if rec.Error == "" {
+			deleted++
+		}
+	}
+	return deleted, err
+}
+
+// ForgetOrphans 停止跟踪指定的孤儿记录，服务商中的记录保持不变
+func (m *Manager) ForgetOrphans(ids []int64) (int, error) {
+	orphans, err := m.Orphans()
+	if err != nil {
+		return 0, err
+	}
+	forgotten := 0
+	for _, r := range orphans {
+		if !slices.Contains(ids, r.ID) {
+			continue
+		}
+		if err := m.DB.DeleteManagedRecord(r.ID); err != nil {
+			return forgotten, err
+		}
+		log.Printf("ℹ️  停止跟踪孤儿记录: %s (%s)", r.Domain, r.RecordType)
+		forgotten++
+	}
+	return forgotten, nil
+}
+
+// handleOrphans 完整同步结束后按 orphan_policy 处理孤儿记录（调用方持有 syncMu），返回删除的结果
+func (m *Manager) handleOrphans(cfg *config.AppConfig) []RecordReport {
+	if cfg.OrphanPolicy != config.OrphanPolicyDelete && cfg.OrphanPolicy != config.OrphanPolicyKeep {
+		return nil
+	}
+	orphans, err := m.orphans(cfg)
+	if err != nil || len(orphans) == 0 {
+		return nil
+	}
+	ids := make([]int64, 0, len(orphans))
+	for _, r := range orphans {
+		ids = append(ids, r.ID)
+	}
+	if cfg.OrphanPolicy == config.OrphanPolicyKeep {
+		for _, id := range ids {
+			m.DB.DeleteManagedRecord(id)
+		}
+		log.Printf("ℹ️  %d 条记录已从配置中移除，保留服务商中的记录并停止跟踪", len(ids))
+		return nil
+	}
+	results, err := m.deleteOrphans(cfg, ids)
+	if err != nil {
+		log.Printf("⚠️  自动清理孤儿记录失败: %v", err)
+	}
+	return results
+}
+
+// deleteOrphans 删除孤儿记录并写入 dns_updates（调用方持有 syncMu），返回每条记录的结果
+func (m *Manager) deleteOrphans(cfg *config.AppConfig, ids []int64) ([]RecordReport, error) {
+	orphans, err := m.orphans(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []RecordReport
+	var errs []error
+	for _, r := range orphans {
+		if !slices.Contains(ids, r.ID) {
+			continue
+		}
+		content := strings.Join(r.Content, ", ")
+		rec, start := recordReport(r.AccountName, r.Zone, r.Domain, r.RecordType, content)
+		rec.Action, rec.Old, rec.Attempts = ActionDelete, content, 1
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := m.deleteOrphan(ctx, cfg, r)
+		cancel()
+
+		rec.done(start, err)
+		m.recordResult(rec)
+		results = append(results, rec)
+		if err != nil {
+			log.Printf("❌ 删除孤儿记录失败 (%s): %v", r.Domain, err)
+			errs = append(errs, fmt.Errorf("%s: %w", r.Domain, err))
+			continue
+		}
+		m.DB.DeleteManagedRecord(r.ID)
+	}
+	return results, errors.Join(errs...)
+}
+
+// deleteOrphan 删除服务商中与受管记录匹配的记录
+// 只删除 ID（已知时）和内容均与 idrd 最后写入一致的记录，内容已被他人修改的记录不删除
+func (m *Manager) deleteOrphan(ctx context.Context, cfg *config.AppConfig, r db.ManagedRecord) error {
+	i := slices.IndexFunc(cfg.DNSAccounts, func(a config.DNSAccount) bool { return a.Name == r.AccountName })
+	if i < 0 {
+		return Permanent(fmt.Errorf("账户 %s 已不存在，无法删除（可停止跟踪）", r.AccountName))
+	}
+	account := cfg.DNSAccounts[i]
+	updater, err := NewUpdater(account)
+	if err != nil {
+		return err
+	}
+	d, ok := updater.(Deleter)
+	if !ok {
+		return Permanent(fmt.Errorf("服务商 %s 不支持删除记录", account.ProviderName()))
+	}
+
+	records, err := d.ListRecords(ctx, r.Zone, r.Domain, r.RecordType)
+	if err != nil {
+		return err
+	}
+	own := ownershipFor(cfg, account)
+	var modified []string
+	for _, rec := range records {
+		if !slices.Contains(r.Content, rec.Content) || (len(r.RecordIDs) > 0 && !slices.Contains(r.RecordIDs, rec.ID)) {
+			modified = append(modified, rec.Content)
+			continue
+		}
+		if own != nil {
+			if err := own.check(ctx, d, r.Zone, rec); err != nil {
+				return err
+			}
+		}
+		if err := d.DeleteRecord(ctx, r.Zone, rec); err != nil {
+			return err
+		}
+		log.Printf("🗑️  删除孤儿 DNS 记录: %s -> %s", r.Domain, rec.Content)
+	}
+	if len(modified) > 0 && len(modified) == len(records) {
+		return Permanent(fmt.Errorf("记录已被修改 (%s)，未删除（可停止跟踪）", strings.Join(modified, ", ")))
+	}
+	return own.release(ctx, d, r.Zone, r.Domain)
+}
+
+// planOrphans 将孤儿记录加入计划变更
+func (m *Manager) planOrphans(cfg *config.AppConfig) []PlannedChange {
+	if cfg.OrphanPolicy == config.OrphanPolicyKeep {
+		return nil
+	}
+	orphans, err := m.orphans(cfg)
+	if err != nil {
+		return nil
+	}
+	action := ActionOrphan
+	if cfg.OrphanPolicy == config.OrphanPolicyDelete {
+		action = ActionDelete
+	}
+	changes := make([]PlannedChange, 0, len(orphans))
+	for _, r := range orphans {
+		changes = append(changes, PlannedChange{
+			Account: r.AccountName,
+			Zone:    r.Zone,
+			Domain:  r.Domain,
+			Type:    r.RecordType,
+			Action:  action,
+			Current: strings.Join(r.Content, ", "),
+		})
+	}
+	return changes
+}
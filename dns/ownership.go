@@ -0,0 +1,215 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"idrd/config"
+	"log"
+	"strings"
+)
+
+// 所有权标记方式
+const (
+	OwnershipComment = "comment" // 在记录备注中写入标记（Cloudflare）
+	OwnershipTXT     = "txt"     // 在 _idrd.<name> 写入伴随 TXT 记录（类似 external-dns 的 TXT registry）
+)
+
+// ownerTXTPrefix 伴随 TXT 记录的名称前缀
+const ownerTXTPrefix = "_idrd."
+
+// OwnershipError 记录已存在但不属于本实例，且未设置 adopt
+type OwnershipError struct {
+	Domain string
+	Owner  string // 其他实例的所有者标识，为空表示记录没有标记（手工或其他工具管理）
+}
+
+func (e *OwnershipError) Error() string {
+	if e.Owner != "" {
+		return fmt.Sprintf("%s 属于其他 idrd 实例 (owner=%s)，未修改", e.Domain, e.Owner)
+	}
+	return fmt.Sprintf("%s 已存在且不是 idrd 创建的记录，未修改（设置 adopt 以接管）", e.Domain)
+}
+
+// ownership 单个账户的所有权检查
+type ownership struct {
+	method string
+	owner  string
+}
+
+// ownershipFor 返回账户的所有权检查，未启用或服务商不支持标记时返回 nil
+func ownershipFor(cfg *config.AppConfig, account config.DNSAccount) *ownership {
+	if !cfg.Ownership.Enabled {
+		return nil
+	}
+	b, ok := backends[account.ProviderName()]
+	if !ok || b.Ownership == "" {
+		return nil
+	}
+	return &ownership{method: b.Ownership, owner: cfg.Ownership.Owner()}
+}
+
+// txtValue 伴随 TXT 记录的内容
+func (o *ownership) txtValue() string {
+	return "heritage=idrd,idrd/owner=" + o.owner
+}
+
+// commentTag 记录备注中的标记
+func (o *ownership) commentTag() string {
+	return "[idrd:" + o.owner + "]"
+}
+
+// txtName 返回记录对应的伴随 TXT 记录名称（通配符记录使用 _idrd-wildcard 前缀）
+func txtName(name string) string {
+	if rest, ok := strings.CutPrefix(name, "*."); ok {
+		return "_idrd-wildcard." + rest
+	}
+	return ownerTXTPrefix + name
+}
+
+// mark 在期望的记录上加入所有权标记（备注方式），用户配置的备注保留在标记之前
+func (o *ownership) mark(desired *Record) {
+	if o == nil || o.method != OwnershipComment {
+		return
+	}
+	comment := o.commentTag()
+	if desired.Comment != nil && *desired.Comment != "" {
+		comment = *desired.Comment + " " + comment
+	}
+	desired.Comment = &comment
+}
+
+// check 判断已有记录是否属于本实例：属于返回 nil，否则返回 *OwnershipError
+func (o *ownership) check(ctx context.Context, u Updater, zone string, existing Record) error {
+	switch o.method {
+	case OwnershipComment:
+		if existing.Comment != nil {
+			if strings.Contains(*existing.Comment, o.commentTag()) {
+				return nil
+			}
+			if owner := commentOwner(*existing.Comment); owner != "" {
+				return &OwnershipError{Domain: existing.Name, Owner: owner}
+			}
+		}
+		return &OwnershipError{Domain: existing.Name}
+
+	default:
+		owner, err := o.txtOwner(ctx, u, zone, existing.Name)
+		if err != nil {
+			return err
+		}
+		if owner == o.owner {
+			return nil
+		}
+		return &OwnershipError{Domain: existing.Name, Owner: owner}
+	}
+}
+
+// checkOrAdopt 检查已有记录的所有权：属于本实例返回 (false, nil)，
+// 不属于但 adopt 为 true 时返回 (true, nil) 表示需要写入标记，否则返回所有权错误
+func (o *ownership) checkOrAdopt(ctx context.Context, u Updater, zone string, existing Record, adopt bool) (bool, error) {
+	err := o.check(ctx, u, zone, existing)
+	if err == nil {
+		return false, nil
+	}
+	if !adopt || !isOwnershipError(err) {
+		return false, err
+	}
+	log.Printf("🔄 接管已有 DNS 记录: %s (%v)", existing.Name, err)
+	return true, nil
+}
+
+// claim 为新建或接管的记录写入伴随 TXT 记录（备注方式的标记随记录本身写入）
+func (o *ownership) claim(ctx context.Context, u Updater, zone string, rec Record) error {
+	if o == nil || o.method != OwnershipTXT {
+		return nil
+	}
+	owner, err := o.txtOwner(ctx, u, zone, rec.Name)
+	if err != nil {
+		return err
+	}
+	if owner == o.owner {
+		return nil
+	}
+	if err := u.CreateRecord(ctx, zone, o.txtRecord(rec)); err != nil {
+		return fmt.Errorf("写入所有权 TXT 记录失败 (%s): %w", txtName(rec.Name), err)
+	}
+	return nil
+}
+
+// batchMarkers 返回批量提交时写入的伴随 TXT 记录组
+// 批量提交的服务商整体替换 RRset，因此包含同名 TXT 记录组中已有的值（如其他实例的标记）；已有本实例的标记时返回 nil
+func (o *ownership) batchMarkers(ctx context.Context, u Updater, zone string, rec Record) ([]Record, error) {
+	records, err := u.ListRecords(ctx, zone, txtName(rec.Name), "TXT")
+	if err != nil {
+		return nil, fmt.Errorf("查询所有权 TXT 记录失败 (%s): %w", txtName(rec.Name), err)
+	}
+	marker := o.txtRecord(rec)
+	for _, r := range records {
+		if r.Content == marker.Content {
+			return nil, nil
+		}
+	}
+	return append(records, marker), nil
+}
+
+// txtRecord 返回记录对应的伴随 TXT 记录
+func (o *ownership) txtRecord(rec Record) Record {
+	return Record{Type: "TXT", Name: txtName(rec.Name), Content: o.txtValue(), TTL: rec.TTL}
+}
+
+// txtOwner 读取伴随 TXT 记录中的所有者标识，没有 idrd 标记时返回空
+func (o *ownership) txtOwner(ctx context.Context, u Updater, zone, name string) (string, error) {
+	records, err := u.ListRecords(ctx, zone, txtName(name), "TXT")
+	if err != nil {
+		return "", fmt.Errorf("查询所有权 TXT 记录失败 (%s): %w", txtName(name), err)
+	}
+	other := ""
+	for _, r := range records {
+		if owner, ok := strings.CutPrefix(r.Content, "heritage=idrd,idrd/owner="); ok {
+			if owner == o.owner {
+				return owner, nil
+			}
+			other = owner
+		}
+	}
+	return other, nil
+}
+
+// commentOwner 从备注中提取其他实例的所有者标识
+func commentOwner(comment string) string {
+	_, rest, ok := strings.Cut(comment, "[idrd:")
+	if !ok {
+		return ""
+	}
+	owner, _, _ := strings.Cut(rest, "]")
+	return owner
+}
+
+// release 同名的 A/AAAA 记录都已删除后，删除本实例的伴随 TXT 记录
+func (o *ownership) release(ctx context.Context, d Deleter, zone, name string) error {
+	if o == nil || o.method != OwnershipTXT {
+		return nil
+	}
+	for _, recordType := range []string{"A", "AAAA"} {
+		records, err := d.ListRecords(ctx, zone, name, recordType)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			return nil
+		}
+	}
+	markers, err := d.ListRecords(ctx, zone, txtName(name), "TXT")
+	if err != nil {
+		return fmt.Errorf("查询所有权 TXT 记录失败 (%s): %w", txtName(name), err)
+	}
+	for _, r := range markers {
+		if r.Content != o.txtValue() {
+			continue
+		}
+		if err := d.DeleteRecord(ctx, zone, r); err != nil {
+			return fmt.Errorf("删除所有权 TXT 记录失败 (%s): %w", txtName(name), err)
+		}
+	}
+	return nil
+}
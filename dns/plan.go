@@ -0,0 +1,200 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"idrd/config"
+	"log"
+	"strings"
+	"time"
+)
+
+// 计划动作
+const (
+	ActionCreate   = "create"   // 记录不存在，将创建
+	ActionUpdate   = "update"   // 记录内容或属性与期望不一致，将更新
+	ActionNoop     = "noop"     // 记录已与期望一致
+	ActionConflict = "conflict" // 同名存在 CNAME，或已有记录不属于 idrd（启用所有权时）
+	ActionError    = "error"    // 查询失败（zone 不存在、凭据无效等）
+)
+
+// PlannedChange 单条记录的计划变更
+type PlannedChange struct {
+	Account string   `json:"account"`
+	Zone    string   `json:"zone"`
+	Domain  string   `json:"domain"`
+	Type    string   `json:"type"`
+	Action  string   `json:"action"`
+	Current string   `json:"current,omitempty"` // 服务商中的当前内容
+	Desired string   `json:"desired"`
+	Drift   []string `json:"drift,omitempty"` // 需要更新的字段
+	Error   string   `json:"error,omitempty"`
+}
+
+// Plan 计算将 cfg 中的记录同步到 ip 所需的变更，只调用服务商的查询接口，不做任何修改
+// 已从 cfg 中移除的受管记录按 orphan_policy 列为 delete 或 orphan
+func (m *Manager) Plan(cfg *config.AppConfig, ip string) []PlannedChange {
+	return m.plan(cfg, ip, nil)
+}
+
+// plan 计算计划变更，filter 为 nil 时包含全部记录
+func (m *Manager) plan(cfg *config.AppConfig, ip string, filter func(fqdn string) bool) []PlannedChange {
+	m.loadCaches()
+	recordType := RecordTypeFor(ip)
+	changes := []PlannedChange{}
+
+	for _, account := range cfg.DNSAccounts {
+		own := ownershipFor(cfg, account)
+		updater, err := NewUpdater(account)
+		for _, zone := range account.Zones {
+			if filter != nil {
+				zone = filterZone(zone, filter)
+			}
+			if len(zone.Records) == 0 {
+				continue
+			}
+
+			func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+				defer cancel()
+
+				for _, record := range zone.Records {
+					fullDomain := FQDN(record.Name, zone.ZoneName)
+					change := PlannedChange{
+						Account: account.Name,
+						Zone:    zone.ZoneName,
+						Domain:  fullDomain,
+						Type:    recordType,
+						Desired: ip,
+					}
+					if err != nil {
+						change.Action = ActionError
+						change.Error = fmt.Sprintf("创建 DNS 客户端失败: %v", err)
+					} else {
+						rc := zone.Effective(record)
+						desired := desiredRecord(rc, fullDomain, recordType, ip)
+						own.mark(&desired)
+						if rc.RRSet {
+							planRRSet(ctx, updater, own, zone.ZoneName, desired, rc.Adopt, m.rrsetContents(ip, recordType), &change)
+						} else {
+							planRecord(ctx, updater, own, zone.ZoneName, desired, rc, &change)
+						}
+					}
+					changes = append(changes, change)
+				}
+			}()
+		}
+	}
+
+	// 完整计划包含已从配置中移除的受管记录
+	if filter == nil {
+		changes = append(changes, m.planOrphans(cfg)...)
+	}
+	return changes
+}
+
+// planRecord 查询单条记录并填充计划动作
+func planRecord(ctx context.Context, u Updater, own *ownership, zone string, desired Record, rc config.RecordConfig, change *PlannedChange) {
+	records, err := u.ListRecords(ctx, zone, desired.Name, desired.Type)
+	if err != nil {
+		change.Action = ActionError
+		change.Error = err.Error()
+		return
+	}
+
+	current, ok := selectRecord(records, rc.Select)
+	if !ok && rc.Select != nil && rc.Select.ID != "" {
+		change.Action, change.Error = ActionError, fmt.Sprintf("记录 ID %s 不存在", rc.Select.ID)
+		return
+	}
+	if !ok {
+		// 同名 CNAME 存在时无法创建 A/AAAA 记录（查询失败时不判断）
+		if cnames, err := u.ListRecords(ctx, zone, desired.Name, "CNAME"); err == nil && len(cnames) > 0 {
+			change.Action = ActionConflict
+			change.Current = cnames[0].Content
+			change.Error = fmt.Sprintf("%s 已存在 CNAME 记录 (%s)", desired.Name, cnames[0].Content)
+			return
+		}
+		change.Action = ActionCreate
+		return
+	}
+
+	change.Current = current.Content
+	if own != nil {
+		if err := own.check(ctx, u, zone, current); err != nil {
+			switch {
+			case !isOwnershipError(err):
+				change.Action, change.Error = ActionError, err.Error()
+				return
+			case !rc.Adopt:
+				change.Action, change.Error = ActionConflict, err.Error()
+				return
+			}
+			change.Drift = append(change.Drift, "adopt")
+		}
+	}
+	change.Drift = append(change.Drift, driftFields(current, desired)...)
+	if len(change.Drift) == 0 {
+		change.Action = ActionNoop
+	} else {
+		change.Action = ActionUpdate
+	}
+}
+
+// dryRun 演练模式：计算计划变更并写入 dns_updates，不调用服务商写接口
+func (m *Manager) dryRun(cfg *config.AppConfig, report *Report, filter func(fqdn string) bool) {
+	for _, change := range m.plan(cfg, report.IP, filter) {
+		if change.Action == ActionOrphan {
+			continue
+		}
+		report.add(RecordReport{
+			Account: change.Account,
+			Zone:    change.Zone,
+			Domain:  change.Domain,
+			Type:    change.Type,
+			Action:  change.Action,
+			Old:     change.Current,
+			New:     change.Desired,
+			Error:   change.Error,
+		})
+		// 定期校准时只记录与上次计划不同的变更，避免每次校准重复写入相同的计划
+		key := change.Account + "|" + strings.ToLower(change.Domain) + "|" + change.Type
+		plan := strings.Join([]string{change.Action, change.Current, change.Desired, change.Error}, "|")
+		last, seen := m.planned[key]
+		if m.planned == nil {
+			m.planned = map[string]string{}
+		}
+		m.planned[key] = plan
+		if report.Reconcile && (change.Action == ActionNoop || seen && last == plan) {
+			continue
+		}
+		switch change.Action {
+		case ActionNoop:
+			log.Printf("ℹ️  [演练] IP 未变化，无需更新 DNS 记录: %s -> %s", change.Domain, change.Desired)
+		case ActionDelete:
+			log.Printf("📝 [演练] 计划删除孤儿 DNS 记录: %s (%s)", change.Domain, change.Current)
+		case ActionCreate, ActionUpdate:
+			log.Printf("📝 [演练] 计划%s DNS 记录: %s -> %s", actionLabel(change.Action), change.Domain, report.IP)
+		default:
+			log.Printf("⚠️  [演练] DNS 记录无法同步 (%s): %s", change.Domain, change.Error)
+		}
+		m.recordPlanned(change)
+	}
+}
+
+// actionLabel 返回动作的中文描述
+func actionLabel(action string) string {
+	switch action {
+	case ActionCreate:
+		return "创建"
+	case ActionUpdate:
+		return "更新"
+	case ActionDelete:
+		return "删除"
+	case ActionConflict:
+		return "冲突"
+	case ActionError:
+		return "失败"
+	}
+	return "跳过"
+}
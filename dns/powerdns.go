@@ -0,0 +1,227 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"idrd/config"
+	"idrd/ip"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPowerDNSServerID = "localhost"
+	defaultPowerDNSTTL      = 300
+)
+
+// PowerDNSUpdater 通过 PowerDNS Authoritative HTTP API 更新记录
+// 同一 zone 的变更通过一次 PATCH 原子提交
+type PowerDNSUpdater struct {
+	baseURL  string // 如 http://127.0.0.1:8081
+	serverID string
+	apiKey   string
+	ttl      int
+	client   *http.Client
+	records  recordSnapshot // 本次同步中已列出的 zone 记录
+}
+
+// powerDNSRRSet zone 的 rrset 结构
+type powerDNSRRSet struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	TTL        int    `json:"ttl,omitempty"`
+	ChangeType string `json:"changetype,omitempty"`
+	Records    []struct {
+		Content  string `json:"content"`
+		Disabled bool   `json:"disabled"`
+	} `json:"records"`
+}
+
+func init() {
+	Register(Backend{
+		Provider:   "powerdns",
+		Name:       "PowerDNS Authoritative",
+		Ownership:  OwnershipTXT,
+		TokenLabel: "API Key",
+		Properties: []ip.PropertySchema{
+			{Name: "url", Label: "API URL", Type: "string", Required: true, Help: "PowerDNS webserver 地址，如 http://127.0.0.1:8081"},
+			{Name: "server_id", Label: "Server ID", Type: "string", Default: defaultPowerDNSServerID},
+			{Name: "ttl", Label: "TTL", Type: "number", Default: strconv.Itoa(defaultPowerDNSTTL), Help: "新建记录的 TTL（秒），已有记录保留原 TTL"},
+		},
+		New: newPowerDNSUpdater,
+		Validate: func(a *config.DNSAccount) error {
+			if a.APIToken == "" {
+				return fmt.Errorf("API key required")
+			}
+			return validateAPIURL(a.Properties["url"], a.Properties["ttl"])
+		},
+	})
+}
+
+// validateAPIURL 校验自托管服务的 API 地址和 TTL 属性
+func validateAPIURL(rawURL, ttl string) error {
+	if u, err := url.Parse(rawURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid url %s", rawURL)
+	}
+	if ttl != "" {
+		if n, err := strconv.Atoi(ttl); err != nil || n <= 0 {
+			return fmt.Errorf("invalid ttl %s", ttl)
+		}
+	}
+	return nil
+}
+
+// newPowerDNSUpdater 根据账户配置创建 PowerDNS 客户端
+func newPowerDNSUpdater(account config.DNSAccount) (Updater, error) {
+	props := account.Properties
+	if props["url"] == "" || account.APIToken == "" {
+		return nil, fmt.Errorf("url or API key is empty")
+	}
+
+	u := &PowerDNSUpdater{
+		baseURL:  strings.TrimSuffix(props["url"], "/"),
+		serverID: props["server_id"],
+		apiKey:   account.APIToken,
+		ttl:      defaultPowerDNSTTL,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	if u.serverID == "" {
+		u.serverID = defaultPowerDNSServerID
+	}
+	if ttl, err := strconv.Atoi(props["ttl"]); err == nil && ttl > 0 {
+		u.ttl = ttl
+	}
+	return u, nil
+}
+
+// do 发送带 X-API-Key 的请求，并将 JSON 响应解析到 out
+func (u *PowerDNSUpdater) do(ctx context.Context, method, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.baseURL+"/api/v1/servers/"+url.PathEscape(u.serverID)+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", u.apiKey)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return &ProviderError{Provider: "powerdns", Code: strconv.Itoa(resp.StatusCode), Message: apiErr.Error, Class: httpErrorClass(resp.StatusCode)}
+		}
+		return httpStatusError("powerdns", method+" "+path, resp.StatusCode)
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// zonePath 返回 zone 的 API 路径（zone ID 为带结尾点的规范名称）
+func zonePath(zone string) string {
+	return "/zones/" + url.PathEscape(strings.TrimSuffix(zone, ".")+".")
+}
+
+// ListRecords 列出 zone 中指定名称和类型的记录（每次同步每个 zone 只列出一次）
+func (u *PowerDNSUpdater) ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error) {
+	return u.records.filter(ctx, zone, name, recordType, u.ListZoneRecords)
+}
+
+// ListZoneRecords 列出 zone 中的全部记录（忽略已禁用的记录）
+func (u *PowerDNSUpdater) ListZoneRecords(ctx context.Context, zone string) ([]Record, error) {
+	var resp struct {
+		RRSets []powerDNSRRSet `json:"rrsets"`
+	}
+	if err := u.do(ctx, http.MethodGet, zonePath(zone)+"?rrsets=true", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, set := range resp.RRSets {
+		for _, r := range set.Records {
+			if r.Disabled {
+				continue
+			}
+			records = append(records, Record{
+				ID:      r.Content,
+				Type:    set.Type,
+				Name:    strings.TrimSuffix(set.Name, "."),
+				Content: unquoteTXT(set.Type, r.Content),
+				TTL:     set.TTL,
+			})
+		}
+	}
+	return records, nil
+}
+
+// CreateRecord 创建记录（REPLACE 整个 RRset）
+func (u *PowerDNSUpdater) CreateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.UpsertRecords(ctx, zone, []Record{rec})
+}
+
+// UpdateRecord 更新记录（REPLACE 整个 RRset）
+func (u *PowerDNSUpdater) UpdateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.UpsertRecords(ctx, zone, []Record{rec})
+}
+
+// UpsertRecords 通过一次 PATCH 替换 zone 中的多个 RRset
+func (u *PowerDNSUpdater) UpsertRecords(ctx context.Context, zone string, recs []Record) error {
+	var patch struct {
+		RRSets []powerDNSRRSet `json:"rrsets"`
+	}
+	for _, group := range groupRRSets(recs) {
+		set := powerDNSRRSet{
+			Name:       group[0].Name + ".",
+			Type:       group[0].Type,
+			TTL:        group[0].TTL,
+			ChangeType: "REPLACE",
+		}
+		if set.TTL <= 0 {
+			set.TTL = u.ttl
+		}
+		for _, rec := range group {
+			set.Records = append(set.Records, struct {
+				Content  string `json:"content"`
+				Disabled bool   `json:"disabled"`
+			}{Content: quoteTXT(rec)})
+		}
+		patch.RRSets = append(patch.RRSets, set)
+	}
+	// REPLACE 会替换整个 RRset，提交后重新列出 zone
+	u.records.invalidate(zone)
+	return u.do(ctx, http.MethodPatch, zonePath(zone), patch, nil)
+}
+
+// Verify 验证 API Key 是否可用
+func (u *PowerDNSUpdater) Verify(ctx context.Context) error {
+	return u.do(ctx, http.MethodGet, "", nil, nil)
+}
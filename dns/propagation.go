@@ -0,0 +1,308 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"idrd/config"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	dnsmsg "github.com/miekg/dns"
+)
+
+// 传播状态
+const (
+	PropagationPending    = "pending"    // 等待所有权威服务器返回新内容
+	PropagationPropagated = "propagated" // 所有权威服务器都已返回新内容
+	PropagationStale      = "stale"      // 超时后仍有权威服务器返回旧内容（或无法查询）
+)
+
+// propagationInterval 两次查询权威服务器的间隔
+const propagationInterval = 5 * time.Second
+
+// ServerAnswer 单个名称服务器或解析器的查询结果
+type ServerAnswer struct {
+	Server string   `json:"server"`
+	Answer []string `json:"answer"`
+	TTL    uint32   `json:"ttl,omitempty"` // 解析器缓存的剩余 TTL
+	OK     bool     `json:"ok"`            // 返回的内容与期望一致
+	Error  string   `json:"error,omitempty"`
+}
+
+// Propagation 单条记录更新后的传播状态
+type Propagation struct {
+	Account   string         `json:"account"`
+	Domain    string         `json:"domain"`
+	Type      string         `json:"type"`
+	Expected  []string       `json:"expected"`
+	Status    string         `json:"status"`
+	Servers   []ServerAnswer `json:"servers"`             // 权威服务器
+	Resolvers []ServerAnswer `json:"resolvers,omitempty"` // 公共解析器（权威服务器一致或超时后检查）
+	StartedAt time.Time      `json:"started_at"`
+	Duration  int64          `json:"duration_ms,omitempty"` // 所有权威服务器一致所用的时间
+	Error     string         `json:"error,omitempty"`
+}
+
+// propagationCheck 正在进行或已完成的检查，同一记录再次更新时取消旧的检查
+type propagationCheck struct {
+	state  Propagation
+	cancel context.CancelFunc
+}
+
+// Propagation 返回最近更新的记录的传播状态（按域名排序）
+func (m *Manager) Propagation() []Propagation {
+	m.propMu.Lock()
+	defer m.propMu.Unlock()
+	list := make([]Propagation, 0, len(m.propagation))
+	for _, c := range m.propagation {
+		list = append(list, c.state)
+	}
+	slices.SortFunc(list, func(a, b Propagation) int { return strings.Compare(a.Domain, b.Domain) })
+	return list
+}
+
+// checkPropagation 为报告中创建或更新成功的记录启动后台传播检查
+// Cloudflare 代理的记录对外解析为 Cloudflare 的地址，不检查
+func (m *Manager) checkPropagation(cfg *config.AppConfig, report *Report) {
+	if !cfg.Propagation.Enabled || report.DryRun {
+		return
+	}
+	resolvers := cfg.Propagation.ResolverList()
+	timeout := cfg.Propagation.TimeoutDuration()
+	for _, rec := range report.Records {
+		if rec.Error != "" || rec.proxied || (rec.Action != ActionCreate && rec.Action != ActionUpdate) {
+			continue
+		}
+		key := rec.Account + "|" + strings.ToLower(rec.Domain) + "|" + rec.Type
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		check := &propagationCheck{cancel: cancel, state: Propagation{
+			Account:   rec.Account,
+			Domain:    rec.Domain,
+			Type:      rec.Type,
+			Expected:  strings.Split(rec.New, ", "),
+			Status:    PropagationPending,
+			StartedAt: time.Now(),
+		}}
+
+		m.propMu.Lock()
+		if old, ok := m.propagation[key]; ok {
+			old.cancel()
+		}
+		if m.propagation == nil {
+			m.propagation = map[string]*propagationCheck{}
+		}
+		m.propagation[key] = check
+		m.propMu.Unlock()
+
+		go m.watchPropagation(ctx, key, check, rec.Zone, resolvers)
+	}
+}
+
+// watchPropagation 定期查询 zone 的权威服务器，直到全部返回期望内容或超时，之后检查公共解析器的缓存
+func (m *Manager) watchPropagation(ctx context.Context, key string, check *propagationCheck, zone string, resolvers []string) {
+	defer check.cancel()
+	state := m.propagationState(check)
+
+	servers, err := authoritativeServers(ctx, zone)
+	if err != nil {
+		state.Status = PropagationStale
+		state.Error = fmt.Sprintf("查询 %s 的 NS 记录失败: %v", zone, err)
+		log.Printf("⚠️  传播检查失败 (%s): %s", state.Domain, state.Error)
+		m.setPropagation(key, check, state)
+		return
+	}
+
+	for {
+		state.Servers = queryAll(ctx, servers, state.Domain, state.Type, state.Expected, false)
+		if !slices.ContainsFunc(state.Servers, func(a ServerAnswer) bool { return !a.OK }) {
+			state.Status = PropagationPropagated
+			state.Duration = time.Since(state.StartedAt).Milliseconds()
+			log.Printf("✅ %s 已在全部 %d 个权威服务器生效 (%dms)", state.Domain, len(servers), state.Duration)
+			break
+		}
+		if !m.setPropagation(key, check, state) {
+			return // 记录再次更新，由新的检查接管
+		}
+
+		timer := time.NewTimer(propagationInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if !m.setPropagation(key, check, state) {
+				return
+			}
+			state.Status = PropagationStale
+			var lagging []string
+			for _, a := range state.Servers {
+				if !a.OK {
+					lagging = append(lagging, a.Server)
+				}
+			}
+			log.Printf("⚠️  %s 未在全部权威服务器生效: %s 仍返回旧内容", state.Domain, strings.Join(lagging, ", "))
+		case <-timer.C:
+			continue
+		}
+		break
+	}
+
+	// 公共解析器可能在 TTL 内继续返回旧地址，只提示不影响状态
+	rctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	state.Resolvers = queryAll(rctx, resolverAddrs(resolvers), state.Domain, state.Type, state.Expected, true)
+	for _, a := range state.Resolvers {
+		if !a.OK && a.Error == "" {
+			log.Printf("⚠️  公共解析器 %s 仍缓存 %s 的旧内容 %s（剩余 TTL %ds）", a.Server, state.Domain, strings.Join(a.Answer, ", "), a.TTL)
+		}
+	}
+	m.setPropagation(key, check, state)
+}
+
+// propagationState 返回检查的当前状态副本
+func (m *Manager) propagationState(check *propagationCheck) Propagation {
+	m.propMu.Lock()
+	defer m.propMu.Unlock()
+	return check.state
+}
+
+// setPropagation 保存检查状态，检查已被同一记录的新检查取代时返回 false
+func (m *Manager) setPropagation(key string, check *propagationCheck, state Propagation) bool {
+	m.propMu.Lock()
+	defer m.propMu.Unlock()
+	if m.propagation[key] != check {
+		return false
+	}
+	check.state = state
+	return true
+}
+
+// authoritativeServers 查询 zone 的 NS 记录，返回每个名称服务器的 host:53（优先 IPv4）
+func authoritativeServers(ctx context.Context, zone string) ([]string, error) {
+	nss, err := net.DefaultResolver.LookupNS(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	var servers []string
+	for _, ns := range nss {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, ns.Host)
+		if err != nil || len(addrs) == 0 {
+			continue
+		}
+		addr := addrs[0].IP
+		if i := slices.IndexFunc(addrs, func(a net.IPAddr) bool { return a.IP.To4() != nil }); i >= 0 {
+			addr = addrs[i].IP
+		}
+		servers = append(servers, strings.TrimSuffix(ns.Host, ".")+"@"+net.JoinHostPort(addr.String(), "53"))
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("没有可用的名称服务器")
+	}
+	return servers, nil
+}
+
+// resolverAddrs 为未指定端口的解析器加上 53 端口
+func resolverAddrs(resolvers []string) []string {
+	addrs := make([]string, 0, len(resolvers))
+	for _, r := range resolvers {
+		if _, _, err := net.SplitHostPort(r); err != nil {
+			r = net.JoinHostPort(r, "53")
+		}
+		addrs = append(addrs, r)
+	}
+	return addrs
+}
+
+// queryAll 并行查询多个服务器（name@addr 或 addr），比较返回的内容与 expected
+func queryAll(ctx context.Context, servers []string, domain, recordType string, expected []string, recursive bool) []ServerAnswer {
+	answers := make([]ServerAnswer, len(servers))
+	done := make(chan struct{})
+	for i, server := range servers {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			label, addr := server, server
+			if name, a, ok := strings.Cut(server, "@"); ok {
+				label, addr = name, a
+			}
+			answer := ServerAnswer{Server: label, Answer: []string{}}
+			contents, ttl, err := queryServer(ctx, addr, domain, recordType, recursive)
+			if err != nil {
+				answer.Error = err.Error()
+			} else {
+				answer.Answer, answer.TTL = contents, ttl
+				answer.OK = sameContents(contents, expected)
+			}
+			answers[i] = answer
+		}()
+	}
+	for range servers {
+		<-done
+	}
+	return answers
+}
+
+// queryServer 向单个服务器查询记录，返回记录内容和最小 TTL（应答被截断时改用 TCP）
+func queryServer(ctx context.Context, addr, domain, recordType string, recursive bool) ([]string, uint32, error) {
+	qtype, ok := dnsmsg.StringToType[recordType]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported record type %s", recordType)
+	}
+	msg := new(dnsmsg.Msg)
+	msg.SetQuestion(dnsmsg.Fqdn(domain), qtype)
+	msg.RecursionDesired = recursive
+
+	client := &dnsmsg.Client{Timeout: 5 * time.Second}
+	resp, _, err := client.ExchangeContext(ctx, msg, addr)
+	if err == nil && resp.Truncated {
+		client.Net = "tcp"
+		resp, _, err = client.ExchangeContext(ctx, msg, addr)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if resp.Rcode != dnsmsg.RcodeSuccess && resp.Rcode != dnsmsg.RcodeNameError {
+		return nil, 0, fmt.Errorf("rcode %s", dnsmsg.RcodeToString[resp.Rcode])
+	}
+
+	contents := []string{}
+	var ttl uint32
+	for _, rr := range resp.Answer {
+		var content string
+		switch r := rr.(type) {
+		case *dnsmsg.A:
+			content = r.A.String()
+		case *dnsmsg.AAAA:
+			content = r.AAAA.String()
+		default:
+			continue
+		}
+		if ttl == 0 || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+		contents = append(contents, content)
+	}
+	return contents, ttl, nil
+}
+
+// sameContents 判断两组地址是否相同（不计顺序）
+func sameContents(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, c := range a {
+		if !slices.ContainsFunc(b, func(d string) bool { return sameIP(c, d) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameIP 比较地址（IPv6 可能有不同的书写形式）
+func sameIP(a, b string) bool {
+	ipA, ipB := net.ParseIP(a), net.ParseIP(b)
+	if ipA == nil || ipB == nil {
+		return a == b
+	}
+	return ipA.Equal(ipB)
+}
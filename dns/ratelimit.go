@@ -0,0 +1,192 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter 单个账户的令牌桶：按 limit/window 的速率补充令牌，最多积累 burst 个
+// 服务商返回 429 时按 Retry-After 暂停该账户的所有请求
+// Updater 按同步创建，限流器放在包级别以跨同步保留（同一账户并行的 zone 共享）
+type rateLimiter struct {
+	provider string
+	account  string
+	limit    int           // 窗口内允许的请求数
+	window   time.Duration // 服务商的限流窗口
+	burst    int
+
+	mu        sync.Mutex
+	tokens    float64
+	last      time.Time   // 上次补充令牌的时间
+	pauseEnd  time.Time   // Retry-After 结束时间
+	requests  []time.Time // 窗口内的请求时间，用于统计配额
+	throttled int         // 收到 429 的次数
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = map[string]*rateLimiter{} // 服务商|账户 -> 限流器
+)
+
+// limiterFor 返回账户的限流器，首次使用时创建（限额变化时重新创建）
+func limiterFor(provider, account string, limit int, window time.Duration, burst int) *rateLimiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	key := provider + "|" + account
+	if l, ok := limiters[key]; ok && l.limit == limit && l.window == window && l.burst == burst {
+		return l
+	}
+	l := &rateLimiter{provider: provider, account: account, limit: limit, window: window, burst: burst, tokens: float64(burst), last: time.Now()}
+	limiters[key] = l
+	return l
+}
+
+// wait 等待可用令牌或 Retry-After 结束，ctx 结束时返回错误
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens = min(float64(l.burst), l.tokens+now.Sub(l.last).Seconds()*float64(l.limit)/l.window.Seconds())
+		l.last = now
+		var delay time.Duration
+		switch {
+		case now.Before(l.pauseEnd):
+			delay = l.pauseEnd.Sub(now)
+		case l.tokens >= 1:
+			l.tokens--
+			l.requests = append(l.prune(now), now)
+			l.mu.Unlock()
+			return nil
+		default:
+			delay = time.Duration((1 - l.tokens) * float64(l.window) / float64(l.limit))
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("等待 API 速率限制: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// pause 收到 429 后暂停请求直到 Retry-After 结束，并清空令牌
+func (l *rateLimiter) pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.throttled++
+	l.tokens = 0
+	if end := time.Now().Add(d); end.After(l.pauseEnd) {
+		l.pauseEnd = end
+	}
+}
+
+// prune 丢弃窗口外的请求时间（调用方持有 mu）
+func (l *rateLimiter) prune(now time.Time) []time.Time {
+	i := slices.IndexFunc(l.requests, func(t time.Time) bool { return now.Sub(t) < l.window })
+	if i < 0 {
+		return l.requests[:0]
+	}
+	return l.requests[i:]
+}
+
+// Quota 账户的 API 配额使用情况（本实例发出的请求）
+type Quota struct {
+	Provider       string     `json:"provider"`
+	Account        string     `json:"account"`
+	Limit          int        `json:"limit"`          // 窗口内允许的请求数
+	WindowSeconds  int        `json:"window_seconds"` // 限流窗口
+	Used           int        `json:"used"`           // 窗口内已发出的请求数
+	Remaining      int        `json:"remaining"`
+	Throttled      int        `json:"throttled"`                 // 收到 429 的次数
+	ThrottledUntil *time.Time `json:"throttled_until,omitempty"` // Retry-After 结束时间，未暂停时为空
+}
+
+// Quotas 返回所有已使用限流器的账户的配额（按账户排序）
+func Quotas() []Quota {
+	limitersMu.Lock()
+	list := make([]*rateLimiter, 0, len(limiters))
+	for _, l := range limiters {
+		list = append(list, l)
+	}
+	limitersMu.Unlock()
+
+	quotas := make([]Quota, 0, len(list))
+	for _, l := range list {
+		quotas = append(quotas, l.quota())
+	}
+	slices.SortFunc(quotas, func(a, b Quota) int { return strings.Compare(a.Account, b.Account) })
+	return quotas
+}
+
+func (l *rateLimiter) quota() Quota {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.requests = l.prune(now)
+	q := Quota{
+		Provider:      l.provider,
+		Account:       l.account,
+		Limit:         l.limit,
+		WindowSeconds: int(l.window.Seconds()),
+		Used:          len(l.requests),
+		Remaining:     max(l.limit-len(l.requests), 0),
+		Throttled:     l.throttled,
+	}
+	if now.Before(l.pauseEnd) {
+		end := l.pauseEnd
+		q.ThrottledUntil = &end
+	}
+	return q
+}
+
+// rateLimitedTransport 每个请求先从账户的令牌桶取令牌，429 响应转换为带 RetryAfter 的 rate_limit 错误
+type rateLimitedTransport struct {
+	limiter *rateLimiter
+	base    http.RoundTripper
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.wait(req.Context()); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	wait := parseRetryAfter(resp.Header.Get("Retry-After"))
+	t.limiter.pause(wait)
+	return nil, &ProviderError{
+		Provider:   t.limiter.provider,
+		Code:       strconv.Itoa(resp.StatusCode),
+		Message:    fmt.Sprintf("超出 API 速率限制，%v 后重试", wait),
+		Class:      ErrorClassRateLimit,
+		RetryAfter: wait,
+	}
+}
+
+// defaultRetryAfter 429 响应没有 Retry-After 时的等待时间
+const defaultRetryAfter = 5 * time.Second
+
+// parseRetryAfter 解析 Retry-After（秒数或 HTTP 日期）
+func parseRetryAfter(value string) time.Duration {
+	if secs, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return defaultRetryAfter
+}
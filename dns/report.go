@@ -0,0 +1,110 @@
+package dns
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RecordReport 单条记录的同步结果
+type RecordReport struct {
+	Account    string `json:"account"`
+	Zone       string `json:"zone"`
+	Domain     string `json:"domain"`
+	Type       string `json:"type"`
+	Action     string `json:"action"`
+	Old        string `json:"old,omitempty"` // 修改前的内容，新建或查询失败时为空
+	New        string `json:"new"`           // 期望的内容（rrset 模式为逗号分隔的全部地址）
+	Duration   int64  `json:"duration_ms"`
+	Attempts   int    `json:"attempts"` // 调用服务商的次数（含重试），批量提交时为整个批次的次数
+	Error      string `json:"error,omitempty"`
+	ErrorCode  string `json:"error_code,omitempty"`
+	ErrorClass string `json:"error_class,omitempty"` // auth、not_found、validation、rate_limit 或 transient
+
+	err     error // 原始错误，用于识别所有权冲突
+	proxied bool  // Cloudflare 代理的记录，不检查传播
+}
+
+// Report 一次同步的结果
+type Report struct {
+	IP        string         `json:"ip"`
+	DryRun    bool           `json:"dry_run,omitempty"`   // 演练模式，记录为计划变更
+	Reconcile bool           `json:"reconcile,omitempty"` // 定期校准
+	StartedAt time.Time      `json:"started_at"`
+	Duration  int64          `json:"duration_ms"`
+	Total     int            `json:"total"`   // 检查的记录数
+	Changed   int            `json:"changed"` // 创建、更新或删除的记录数
+	Failed    int            `json:"failed"`  // 失败或冲突的记录数
+	Records   []RecordReport `json:"records"`
+}
+
+// newReport 创建同步开始时的报告
+func newReport(ip string) *Report {
+	return &Report{IP: ip, StartedAt: time.Now(), Records: []RecordReport{}}
+}
+
+// add 加入单条记录的结果并更新统计
+func (r *Report) add(rec RecordReport) {
+	r.Records = append(r.Records, rec)
+	r.Total++
+	switch {
+	case rec.Error != "":
+		r.Failed++
+	case rec.Action == ActionCreate || rec.Action == ActionUpdate || rec.Action == ActionDelete:
+		r.Changed++
+	}
+}
+
+// finish 记录耗时并按账户和域名排序（各 zone 并行完成，顺序不固定）
+func (r *Report) finish() {
+	r.Duration = time.Since(r.StartedAt).Milliseconds()
+	slices.SortStableFunc(r.Records, func(a, b RecordReport) int {
+		if c := strings.Compare(a.Account, b.Account); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+}
+
+// Summary 返回结果摘要
+func (r *Report) Summary() string {
+	changed := "已更新"
+	if r.Reconcile {
+		changed = "已修复"
+	}
+	if r.DryRun {
+		changed = "计划变更"
+	}
+	return fmt.Sprintf("%d 条记录, %d 条%s, %d 条失败", r.Total, r.Changed, changed, r.Failed)
+}
+
+// Err 任一记录失败时返回错误，包含第一条失败记录的原因
+func (r *Report) Err() error {
+	if r.Failed == 0 {
+		return nil
+	}
+	i := slices.IndexFunc(r.Records, func(rec RecordReport) bool { return rec.Error != "" })
+	return fmt.Errorf("%d 条记录同步失败 (%s: %s)", r.Failed, r.Records[i].Domain, r.Records[i].Error)
+}
+
+// recordReport 创建记录的结果，记录开始时间供 done 计算耗时
+func recordReport(account, zone, domain, recordType, content string) (RecordReport, time.Time) {
+	return RecordReport{Account: account, Zone: zone, Domain: domain, Type: recordType, New: content}, time.Now()
+}
+
+// done 填入耗时和错误（出错时 Action 改为 error 或 conflict）
+func (rec *RecordReport) done(start time.Time, err error) {
+	rec.Duration = time.Since(start).Milliseconds()
+	if err == nil {
+		return
+	}
+	rec.err = err
+	rec.Error = err.Error()
+	rec.ErrorCode = ErrorCode(err)
+	rec.ErrorClass = ErrorClass(err)
+	rec.Action = ActionError
+	if isOwnershipError(err) {
+		rec.Action = ActionConflict
+	}
+}
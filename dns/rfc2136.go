@@ -0,0 +1,251 @@
+package dns
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"idrd/config"
+	"idrd/ip"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	dnsmsg "github.com/miekg/dns"
+)
+
+// RFC 2136 相关默认值
+const (
+	defaultRFC2136Port = "53"
+	defaultRFC2136TTL  = 300
+	tsigFudge          = 300 // TSIG 允许的时钟偏差（秒）
+)
+
+// tsigAlgorithms 支持的 TSIG 算法（配置名 -> 算法名）
+var tsigAlgorithms = map[string]string{
+	"hmac-sha256": dnsmsg.HmacSHA256,
+	"hmac-sha512": dnsmsg.HmacSHA512,
+}
+
+// RFC2136Updater 通过 RFC 2136 UPDATE 报文更新权威服务器（BIND、Knot、PowerDNS 等）上的记录
+type RFC2136Updater struct {
+	server    string   // 主服务器 host:port
+	transport string   // udp 或 tcp
+	keyName   string   // TSIG 密钥名（FQDN），为空时不签名
+	algorithm string   // TSIG 算法
+	secret    string   // TSIG 密钥（base64）
+	ttl       uint32   // 新记录的 TTL
+	zones     []string // 账户下的 zone，用于 Verify
+}
+
+func init() {
+	Register(Backend{
+		Provider:   "rfc2136",
+		Name:       "RFC 2136 (BIND / Knot / PowerDNS)",
+		Ownership:  OwnershipTXT,
+		TokenLabel: "TSIG Secret (base64)",
+		Properties: []ip.PropertySchema{
+			{Name: "server", Label: "Primary Server", Type: "string", Required: true, Help: "接受 UPDATE 的主服务器地址 (host:port)，默认端口 53"},
+			{Name: "tsig_key", Label: "TSIG Key Name", Type: "string", Help: "TSIG 密钥名，留空则发送未签名的 UPDATE"},
+			{Name: "tsig_algorithm", Label: "TSIG Algorithm", Type: "select", Default: "hmac-sha256", Options: []string{"hmac-sha256", "hmac-sha512"}},
+			{Name: "ttl", Label: "TTL", Type: "number", Default: strconv.Itoa(defaultRFC2136TTL), Help: "写入记录的 TTL（秒）"},
+			{Name: "transport", Label: "Transport", Type: "select", Default: "udp", Options: []string{"udp", "tcp"}, Help: "UDP 响应被截断时自动改用 TCP"},
+		},
+		New: newRFC2136Updater,
+		Validate: func(a *config.DNSAccount) error {
+			if _, _, err := net.SplitHostPort(rfc2136Server(a.Properties["server"])); err != nil {
+				return fmt.Errorf("invalid server address %s: %w", a.Properties["server"], err)
+			}
+			if alg := a.Properties["tsig_algorithm"]; alg != "" {
+				if _, ok := tsigAlgorithms[alg]; !ok {
+					return fmt.Errorf("unsupported TSIG algorithm: %s", alg)
+				}
+			}
+			if ttl := a.Properties["ttl"]; ttl != "" {
+				if n, err := strconv.Atoi(ttl); err != nil || n <= 0 {
+					return fmt.Errorf("invalid ttl %s", ttl)
+				}
+			}
+			if a.Properties["tsig_key"] != "" {
+				if a.APIToken == "" {
+					return fmt.Errorf("TSIG secret required when tsig_key is set")
+				}
+				// 脱敏值将在保存时回填
+				if a.APIToken != "***" {
+					if _, err := base64.StdEncoding.DecodeString(a.APIToken); err != nil {
+						return fmt.Errorf("TSIG secret must be base64: %w", err)
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// rfc2136Server 未指定端口时补全默认端口 53
+func rfc2136Server(server string) string {
+	server = strings.TrimSpace(server)
+	if _, _, err := net.SplitHostPort(server); err != nil && server != "" {
+		return net.JoinHostPort(strings.Trim(server, "[]"), defaultRFC2136Port)
+	}
+	return server
+}
+
+// newRFC2136Updater 根据账户配置创建 RFC 2136 客户端
+func newRFC2136Updater(account config.DNSAccount) (Updater, error) {
+	props := account.Properties
+	u := &RFC2136Updater{
+		server:    rfc2136Server(props["server"]),
+		transport: props["transport"],
+		ttl:       defaultRFC2136TTL,
+	}
+	if u.server == "" {
+		return nil, fmt.Errorf("server is empty")
+	}
+	if u.transport == "" {
+		u.transport = "udp"
+	}
+	if ttl, err := strconv.Atoi(props["ttl"]); err == nil && ttl > 0 {
+		u.ttl = uint32(ttl)
+	}
+
+	if key := props["tsig_key"]; key != "" {
+		alg, ok := tsigAlgorithms[props["tsig_algorithm"]]
+		if props["tsig_algorithm"] == "" {
+			alg, ok = dnsmsg.HmacSHA256, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("unsupported TSIG algorithm: %s", props["tsig_algorithm"])
+		}
+		if account.APIToken == "" {
+			return nil, fmt.Errorf("TSIG secret is empty")
+		}
+		u.keyName = dnsmsg.Fqdn(key)
+		u.algorithm = alg
+		u.secret = account.APIToken
+	}
+
+	for _, zone := range account.Zones {
+		u.zones = append(u.zones, zone.ZoneName)
+	}
+	return u, nil
+}
+
+// exchange 发送报文（按需 TSIG 签名），UDP 响应被截断时改用 TCP 重发
+func (u *RFC2136Updater) exchange(ctx context.Context, m *dnsmsg.Msg) (*dnsmsg.Msg, error) {
+	client := &dnsmsg.Client{Net: u.transport}
+	if u.keyName != "" {
+		client.TsigSecret = map[string]string{u.keyName: u.secret}
+		m.SetTsig(u.keyName, u.algorithm, tsigFudge, time.Now().Unix())
+	}
+
+	r, _, err := client.ExchangeContext(ctx, m, u.server)
+	if err == nil && r.Truncated && client.Net == "udp" {
+		client.Net = "tcp"
+		r, _, err = client.ExchangeContext(ctx, m, u.server)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.Rcode != dnsmsg.RcodeSuccess {
+		return r, fmt.Errorf("服务器返回 %s", dnsmsg.RcodeToString[r.Rcode])
+	}
+	return r, nil
+}
+
+// ListRecords 直接向主服务器查询记录，避免缓存导致读到旧值
+// DNS 记录没有服务商 ID，使用记录内容作为 ID
+func (u *RFC2136Updater) ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error) {
+	qtype, ok := dnsmsg.StringToType[recordType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported record type: %s", recordType)
+	}
+
+	m := new(dnsmsg.Msg)
+	m.SetQuestion(dnsmsg.Fqdn(name), qtype)
+	m.RecursionDesired = false
+
+	r, err := u.exchange(ctx, m)
+	if err != nil {
+		// NXDOMAIN 表示记录不存在
+		if r != nil && r.Rcode == dnsmsg.RcodeNameError {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("查询 %s 失败: %w", name, err)
+	}
+
+	var records []Record
+	for _, rr := range r.Answer {
+		var content string
+		switch v := rr.(type) {
+		case *dnsmsg.A:
+			content = v.A.String()
+		case *dnsmsg.AAAA:
+			content = v.AAAA.String()
+		case *dnsmsg.TXT:
+			content = strings.Join(v.Txt, "")
+		default:
+			continue
+		}
+		records = append(records, Record{
+			ID:      content,
+			Type:    recordType,
+			Name:    strings.TrimSuffix(rr.Header().Name, "."),
+			Content: content,
+			TTL:     int(rr.Header().Ttl),
+		})
+	}
+	return records, nil
+}
+
+// CreateRecord 向 RRset 添加记录，同名同类型的其他记录（如其他实例的所有权标记）保留
+func (u *RFC2136Updater) CreateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.update(ctx, zone, rec, false)
+}
+
+// UpdateRecord 更新记录（删除同名同类型的 RRset 后添加）
+func (u *RFC2136Updater) UpdateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.update(ctx, zone, rec, true)
+}
+
+// update 发送添加记录的 UPDATE 报文；replace 为 true 时在同一报文中先删除整个 RRset，服务器端原子执行
+func (u *RFC2136Updater) update(ctx context.Context, zone string, rec Record, replace bool) error {
+	ttl := u.ttl
+	if rec.TTL > 0 {
+		ttl = uint32(rec.TTL)
+	}
+
+	rr, err := dnsmsg.NewRR(fmt.Sprintf("%s %d IN %s %s", dnsmsg.Fqdn(rec.Name), ttl, rec.Type, quoteTXT(rec)))
+	if err != nil {
+		return fmt.Errorf("构造记录失败: %w", err)
+	}
+
+	m := new(dnsmsg.Msg)
+	m.SetUpdate(dnsmsg.Fqdn(zone))
+	if replace {
+		m.RemoveRRset([]dnsmsg.RR{rr}) // 只使用名称和类型
+	}
+	m.Insert([]dnsmsg.RR{rr})
+
+	if _, err := u.exchange(ctx, m); err != nil {
+		return fmt.Errorf("UPDATE %s 失败: %w", rec.Name, err)
+	}
+	return nil
+}
+
+// Verify 查询每个 zone 的 SOA 记录，检查服务器可达且 TSIG 密钥被接受
+// 没有配置 zone 时无法验证（根域名的 SOA 不能说明密钥对任何 zone 有效）
+func (u *RFC2136Updater) Verify(ctx context.Context) error {
+	if len(u.zones) == 0 {
+		return fmt.Errorf("no zones configured: add at least one zone to verify the server and TSIG key")
+	}
+	for _, zone := range u.zones {
+		m := new(dnsmsg.Msg)
+		m.SetQuestion(dnsmsg.Fqdn(zone), dnsmsg.TypeSOA)
+		m.RecursionDesired = false
+		if _, err := u.exchange(ctx, m); err != nil {
+			return fmt.Errorf("zone %s: %w", zone, err)
+		}
+	}
+	return nil
+}
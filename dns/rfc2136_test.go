@@ -0,0 +1,253 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"idrd/config"
+
+	dnsmsg "github.com/miekg/dns"
+)
+
+const (
+	testTSIGKey    = "idrd-test."
+	testTSIGSecret = "c2VjcmV0LWtleS1mb3ItaWRyZC10ZXN0cw=="
+	testZone       = "example.test"
+)
+
+// testZoneServer 内存中的权威服务器：应答查询并执行带 TSIG 签名的 UPDATE
+type testZoneServer struct {
+	mu      sync.Mutex
+	rrs     []dnsmsg.RR
+	updates [][]dnsmsg.RR // 收到的每个 UPDATE 报文的 Update 段，按顺序记录
+	addr    string
+}
+
+func startTestZoneServer(t *testing.T, rrs ...string) *testZoneServer {
+	t.Helper()
+	zs := &testZoneServer{}
+	for _, s := range rrs {
+		rr, err := dnsmsg.NewRR(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zs.rrs = append(zs.rrs, rr)
+	}
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zs.addr = pc.LocalAddr().String()
+	started := make(chan struct{})
+	srv := &dnsmsg.Server{
+		PacketConn:        pc,
+		TsigSecret:        map[string]string{testTSIGKey: testTSIGSecret},
+		Handler:           zs,
+		NotifyStartedFunc: func() { close(started) },
+		// 默认的 MsgAcceptFunc 拒绝 UPDATE
+		MsgAcceptFunc: func(dh dnsmsg.Header) dnsmsg.MsgAcceptAction { return dnsmsg.MsgAccept },
+	}
+	go srv.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { srv.Shutdown() })
+	return zs
+}
+
+func (zs *testZoneServer) ServeDNS(w dnsmsg.ResponseWriter, r *dnsmsg.Msg) {
+	m := new(dnsmsg.Msg)
+	m.SetReply(r)
+	tsig := r.IsTsig()
+	if tsig == nil || w.TsigStatus() != nil {
+		m.Rcode = dnsmsg.RcodeNotAuth
+		w.WriteMsg(m)
+		return
+	}
+	m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsigFudge, time.Now().Unix())
+
+	zs.mu.Lock()
+	defer zs.mu.Unlock()
+	q := r.Question[0]
+	switch r.Opcode {
+	case dnsmsg.OpcodeUpdate:
+		zs.updates = append(zs.updates, slices.Clone(r.Ns))
+		for _, rr := range r.Ns {
+			h := rr.Header()
+			switch h.Class {
+			case dnsmsg.ClassANY: // 删除 RRset
+				zs.rrs = slices.DeleteFunc(zs.rrs, func(x dnsmsg.RR) bool {
+					return strings.EqualFold(x.Header().Name, h.Name) && x.Header().Rrtype == h.Rrtype
+				})
+			case dnsmsg.ClassINET:
+				zs.rrs = append(zs.rrs, dnsmsg.Copy(rr))
+			}
+		}
+	default:
+		if q.Qtype == dnsmsg.TypeSOA && strings.EqualFold(q.Name, dnsmsg.Fqdn(testZone)) {
+			soa, _ := dnsmsg.NewRR(testZone + ". 3600 IN SOA ns1.example.test. admin.example.test. 1 3600 600 86400 300")
+			m.Answer = append(m.Answer, soa)
+			break
+		}
+		for _, rr := range zs.rrs {
+			if strings.EqualFold(rr.Header().Name, q.Name) && rr.Header().Rrtype == q.Qtype {
+				m.Answer = append(m.Answer, rr)
+			}
+		}
+	}
+	w.WriteMsg(m)
+}
+
+func newTestRFC2136(t *testing.T, server, secret string, zones ...string) *RFC2136Updater {
+	t.Helper()
+	account := config.DNSAccount{
+		Name:       "test",
+		Provider:   "rfc2136",
+		APIToken:   secret,
+		Properties: map[string]string{"server": server, "tsig_key": "idrd-test", "tsig_algorithm": "hmac-sha256"},
+	}
+	for _, z := range zones {
+		account.Zones = append(account.Zones, config.Zone{ZoneName: z})
+	}
+	u, err := newRFC2136Updater(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.(*RFC2136Updater)
+}
+
+func TestRFC2136UpdateReplacesRRset(t *testing.T) {
+	zs := startTestZoneServer(t,
+		"home.example.test. 300 IN A 192.0.2.1",
+		"home.example.test. 300 IN A 192.0.2.2",
+		"home.example.test. 300 IN AAAA 2001:db8::1",
+	)
+	u := newTestRFC2136(t, zs.addr, testTSIGSecret, testZone)
+	ctx := context.Background()
+
+	tests := []struct {
+		recordType, content string
+	}{
+		{"A", "198.51.100.7"},
+		{"AAAA", "2001:db8::7"},
+	}
+	for i, tt := range tests {
+		rec := Record{Type: tt.recordType, Name: "home." + testZone, Content: tt.content, TTL: 120}
+		if err := u.UpdateRecord(ctx, testZone, rec); err != nil {
+			t.Fatalf("UpdateRecord %s: %v", tt.recordType, err)
+		}
+
+		// 同一个报文中先删除整个 RRset，再添加新记录
+		zs.mu.Lock()
+		update := zs.updates[i]
+		zs.mu.Unlock()
+		if len(update) != 2 {
+			t.Fatalf("%s: update section has %d RRs, want 2", tt.recordType, len(update))
+		}
+		del, add := update[0].Header(), update[1].Header()
+		if del.Class != dnsmsg.ClassANY || dnsmsg.TypeToString[del.Rrtype] != tt.recordType || del.Rdlength != 0 {
+			t.Errorf("%s: first RR = %v, want RRset delete", tt.recordType, update[0])
+		}
+		if add.Class != dnsmsg.ClassINET || dnsmsg.TypeToString[add.Rrtype] != tt.recordType || add.Ttl != 120 {
+			t.Errorf("%s: second RR = %v, want add with TTL 120", tt.recordType, update[1])
+		}
+
+		got, err := u.ListRecords(ctx, testZone, "home."+testZone, tt.recordType)
+		if err != nil {
+			t.Fatalf("ListRecords %s: %v", tt.recordType, err)
+		}
+		if len(got) != 1 || got[0].Content != tt.content || got[0].ID != tt.content || got[0].Name != "home."+testZone || got[0].TTL != 120 {
+			t.Errorf("ListRecords %s = %+v, want single %s", tt.recordType, got, tt.content)
+		}
+
+		// 替换 A 记录不影响同名的 AAAA 记录
+		if tt.recordType == "A" {
+			if got, _ := u.ListRecords(ctx, testZone, "home."+testZone, "AAAA"); len(got) != 1 || got[0].Content != "2001:db8::1" {
+				t.Errorf("AAAA after A update = %+v", got)
+			}
+		}
+	}
+
+	// 不存在的记录返回空列表
+	got, err := u.ListRecords(ctx, testZone, "missing."+testZone, "A")
+	if err != nil || len(got) != 0 {
+		t.Errorf("ListRecords missing = %+v, %v; want none", got, err)
+	}
+}
+
+func TestRFC2136RejectsWrongKey(t *testing.T) {
+	zs := startTestZoneServer(t)
+	u := newTestRFC2136(t, zs.addr, "d3Jvbmcta2V5", testZone)
+	ctx := context.Background()
+
+	if err := u.Verify(ctx); err == nil {
+		t.Error("Verify with wrong TSIG secret succeeded")
+	}
+	if err := u.CreateRecord(ctx, testZone, Record{Type: "A", Name: "home." + testZone, Content: "192.0.2.1"}); err == nil {
+		t.Error("CreateRecord with wrong TSIG secret succeeded")
+	}
+	zs.mu.Lock()
+	defer zs.mu.Unlock()
+	if len(zs.updates) != 0 {
+		t.Errorf("server applied %d updates signed with the wrong key", len(zs.updates))
+	}
+}
+
+func TestRFC2136Verify(t *testing.T) {
+	zs := startTestZoneServer(t)
+	ctx := context.Background()
+
+	if err := newTestRFC2136(t, zs.addr, testTSIGSecret, testZone).Verify(ctx); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	if err := newTestRFC2136(t, zs.addr, testTSIGSecret).Verify(ctx); err == nil {
+		t.Error("Verify without zones succeeded")
+	}
+}
+
+// 写入所有权标记时保留同名 TXT 记录组中其他实例的标记
+func TestRFC2136ClaimKeepsForeignMarker(t *testing.T) {
+	zs := startTestZoneServer(t, `_idrd.home.example.test. 300 IN TXT "heritage=idrd,idrd/owner=other"`)
+	u := newTestRFC2136(t, zs.addr, testTSIGSecret, testZone)
+	own := &ownership{method: OwnershipTXT, owner: "me"}
+	ctx := context.Background()
+
+	if err := own.claim(ctx, u, testZone, Record{Type: "A", Name: "home." + testZone, Content: "192.0.2.1"}); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	zs.mu.Lock()
+	for _, rr := range zs.updates[0] {
+		if rr.Header().Class == dnsmsg.ClassANY {
+			t.Errorf("claim deleted the RRset: %v", rr)
+		}
+	}
+	zs.mu.Unlock()
+
+	got, err := u.ListRecords(ctx, testZone, "_idrd.home."+testZone, "TXT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, r := range got {
+		contents = append(contents, r.Content)
+	}
+	slices.Sort(contents)
+	if want := []string{"heritage=idrd,idrd/owner=me", "heritage=idrd,idrd/owner=other"}; !slices.Equal(contents, want) {
+		t.Errorf("markers = %v, want %v", contents, want)
+	}
+
+	// 已有本实例的标记时不再写入
+	if err := own.claim(ctx, u, testZone, Record{Type: "A", Name: "home." + testZone}); err != nil {
+		t.Fatalf("second claim: %v", err)
+	}
+	zs.mu.Lock()
+	defer zs.mu.Unlock()
+	if len(zs.updates) != 1 {
+		t.Errorf("%d updates, want 1", len(zs.updates))
+	}
+}
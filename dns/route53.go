@@ -0,0 +1,409 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"idrd/config"
+	"idrd/ip"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Route 53 API 常量
+const (
+	defaultRoute53Endpoint = "https://route53.amazonaws.com"
+	defaultRoute53Region   = "us-east-1" // Route 53 是全局服务，签名固定使用 us-east-1
+	defaultRoute53TTL      = 300
+	route53APIVersion      = "2013-04-01"
+	route53Namespace       = "https://route53.amazonaws.com/doc/2013-04-01/"
+
+	route53PollInterval = 2 * time.Second // GetChange 轮询间隔
+)
+
+// Route53Updater 通过 AWS Route 53 REST API 更新记录
+type Route53Updater struct {
+	endpoint        string
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	ttl             int
+	client          *http.Client
+	account         string // 账户名称，用于 Hosted Zone ID 缓存
+}
+
+// route53RecordSet ResourceRecordSet 的 XML 结构
+type route53RecordSet struct {
+	Name            string                  `xml:"Name"`
+	Type            string                  `xml:"Type"`
+	SetIdentifier   string                  `xml:"SetIdentifier,omitempty"`
+	TTL             int                     `xml:"TTL,omitempty"`
+	ResourceRecords []route53ResourceRecord `xml:"ResourceRecords>ResourceRecord"`
+}
+
+// route53ResourceRecord RRset 中的单个值
+type route53ResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+// route53ChangeInfo ChangeResourceRecordSets / GetChange 返回的变更状态
+type route53ChangeInfo struct {
+	ID     string `xml:"ChangeInfo>Id"`
+	Status string `xml:"ChangeInfo>Status"`
+}
+
+func init() {
+	Register(Backend{
+		Provider:   "route53",
+		Name:       "AWS Route 53",
+		Ownership:  OwnershipTXT,
+		TokenLabel: "Secret Access Key",
+		Properties: []ip.PropertySchema{
+			{Name: "access_key_id", Label: "Access Key ID", Type: "string", Required: true},
+			{Name: "ttl", Label: "TTL", Type: "number", Default: strconv.Itoa(defaultRoute53TTL), Help: "新建记录的 TTL（秒），已有记录保留原 TTL"},
+			{Name: "region", Label: "Signing Region", Type: "string", Default: defaultRoute53Region, Help: "SigV4 签名区域，中国区使用 cn-northwest-1"},
+			{Name: "endpoint", Label: "Endpoint", Type: "string", Default: defaultRoute53Endpoint, Help: "API 地址，可指向本地模拟服务用于测试"},
+		},
+		New: newRoute53Updater,
+		Validate: func(a *config.DNSAccount) error {
+			if a.APIToken == "" {
+				return fmt.Errorf("secret access key required")
+			}
+			if ttl := a.Properties["ttl"]; ttl != "" {
+				if n, err := strconv.Atoi(ttl); err != nil || n <= 0 {
+					return fmt.Errorf("invalid ttl %s", ttl)
+				}
+			}
+			if endpoint := a.Properties["endpoint"]; endpoint != "" {
+				if u, err := url.Parse(endpoint); err != nil || u.Host == "" {
+					return fmt.Errorf("invalid endpoint %s", endpoint)
+				}
+			}
+			return nil
+		},
+		MaxConcurrency: 2, // Route 53 API 按账户限制每秒 5 次请求
+	})
+}
+
+// newRoute53Updater 根据账户配置创建 Route 53 客户端
+func newRoute53Updater(account config.DNSAccount) (Updater, error) {
+	props := account.Properties
+	if props["access_key_id"] == "" || account.APIToken == "" {
+		return nil, fmt.Errorf("access key ID or secret is empty")
+	}
+
+	u := &Route53Updater{
+		endpoint:        strings.TrimSuffix(props["endpoint"], "/"),
+		region:          props["region"],
+		accessKeyID:     props["access_key_id"],
+		secretAccessKey: account.APIToken,
+		ttl:             defaultRoute53TTL,
+		client:          &http.Client{Timeout: 30 * time.Second},
+		account:         account.Name,
+	}
+	if u.endpoint == "" {
+		u.endpoint = defaultRoute53Endpoint
+	}
+	if u.region == "" {
+		u.region = defaultRoute53Region
+	}
+	if ttl, err := strconv.Atoi(props["ttl"]); err == nil && ttl > 0 {
+		u.ttl = ttl
+	}
+	return u, nil
+}
+
+// do 发送 SigV4 签名的请求，并将 XML 响应解析到 out
+func (u *Route53Updater) do(ctx context.Context, method, path string, query url.Values, body []byte, out any) error {
+	endpoint, err := url.Parse(u.endpoint)
+	if err != nil {
+		return err
+	}
+	endpoint.Path = "/" + route53APIVersion + path
+	endpoint.RawQuery = sigV4Query(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	signSigV4(req, body, u.accessKeyID, u.secretAccessKey, u.region, "route53", time.Now())
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Code    string `xml:"Error>Code"`
+			Message string `xml:"Error>Message"`
+		}
+		if xml.Unmarshal(data, &apiErr) == nil && apiErr.Code != "" {
+			class := httpErrorClass(resp.StatusCode)
+			switch apiErr.Code {
+			case "Throttling":
+				class = ErrorClassRateLimit
+			case "PriorRequestNotComplete":
+				class = ErrorClassTransient
+			}
+			return &ProviderError{Provider: "route53", Code: apiErr.Code, Message: apiErr.Message, Class: class}
+		}
+		return httpStatusError("route53", method+" "+path, resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, out)
+}
+
+// sigV4Query 按 SigV4 规范编码查询串（按键排序，空格编码为 %20）
+func sigV4Query(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// signSigV4 为请求添加 AWS Signature Version 4 签名头
+// 签名 host、x-amz-date 和 x-amz-content-sha256 三个头部
+func signSigV4(req *http.Request, body []byte, accessKeyID, secret, region, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	payloadHash := sha256.Sum256(body)
+	payloadHex := hex.EncodeToString(payloadHash[:])
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHex)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-date":           amzDate,
+		"x-amz-content-sha256": payloadHex,
+	}
+	canonicalRequest, signedHeaders := sigV4CanonicalRequest(req.Method, req.URL.EscapedPath(), req.URL.RawQuery, headers, payloadHex)
+	scope := amzDate[:8] + "/" + region + "/" + service + "/aws4_request"
+	signature := sigV4Signature(secret, amzDate, region, service, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKeyID, scope, signedHeaders, signature))
+}
+
+// sigV4CanonicalRequest 构造规范请求串，headers 的键为小写头部名，返回规范请求和签名头部列表
+func sigV4CanonicalRequest(method, path, rawQuery string, headers map[string]string, payloadHex string) (string, string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	return strings.Join([]string{
+		method,
+		path,
+		rawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHex,
+	}, "\n"), signedHeaders
+}
+
+// sigV4Signature 按 SigV4 派生签名密钥并对规范请求签名，amzDate 格式为 20060102T150405Z
+func sigV4Signature(secret, amzDate, region, service, canonicalRequest string) string {
+	date := amzDate[:8]
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		date + "/" + region + "/" + service + "/aws4_request",
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// hostedZoneID 获取 zone 名称对应的 Hosted Zone ID（跨同步缓存）
+func (u *Route53Updater) hostedZoneID(ctx context.Context, zone string) (string, error) {
+	return zoneIDs.resolve("route53", u.account, zone, func() (string, error) {
+		return u.lookupHostedZoneID(ctx, zone)
+	})
+}
+
+// lookupHostedZoneID 通过 ListHostedZonesByName 查询 Hosted Zone ID
+func (u *Route53Updater) lookupHostedZoneID(ctx context.Context, zone string) (string, error) {
+	var resp struct {
+		HostedZones []struct {
+			ID   string `xml:"Id"`
+			Name string `xml:"Name"`
+		} `xml:"HostedZones>HostedZone"`
+	}
+	query := url.Values{"dnsname": {zone}, "maxitems": {"1"}}
+	if err := u.do(ctx, http.MethodGet, "/hostedzonesbyname", query, nil, &resp); err != nil {
+		return "", fmt.Errorf("获取 Hosted Zone ID 失败 (%s): %w", zone, err)
+	}
+	// ListHostedZonesByName 从 dnsname 开始按字典序返回，需要确认名称一致
+	if len(resp.HostedZones) == 0 || !strings.EqualFold(strings.TrimSuffix(resp.HostedZones[0].Name, "."), zone) {
+		return "", fmt.Errorf("未找到 Hosted Zone: %s", zone)
+	}
+
+	return strings.TrimPrefix(resp.HostedZones[0].ID, "/hostedzone/"), nil
+}
+
+// failed Hosted Zone 已不存在时使 ID 缓存失效
+func (u *Route53Updater) failed(zone string, err error) error {
+	if ErrorCode(err) == "NoSuchHostedZone" {
+		zoneIDs.invalidate("route53", u.account, zone)
+	}
+	return err
+}
+
+// ListRecords 列出 zone 中指定名称和类型的记录（每个值一条）
+// 带 SetIdentifier 的路由策略记录不由 idrd 管理，会被忽略
+func (u *Route53Updater) ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error) {
+	zoneID, err := u.hostedZoneID(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		RecordSets []route53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+	}
+	query := url.Values{"name": {name + "."}, "type": {recordType}, "maxitems": {"1"}}
+	if err := u.do(ctx, http.MethodGet, "/hostedzone/"+zoneID+"/rrset", query, nil, &resp); err != nil {
+		return nil, u.failed(zone, err)
+	}
+
+	var records []Record
+	for _, set := range resp.RecordSets {
+		// 列表从 name/type 开始，后续记录可能属于其他名称
+		if !strings.EqualFold(strings.TrimSuffix(route53Unescape(set.Name), "."), name) || set.Type != recordType || set.SetIdentifier != "" {
+			continue
+		}
+		for _, rr := range set.ResourceRecords {
+			records = append(records, Record{
+				ID:      rr.Value,
+				Type:    set.Type,
+				Name:    name,
+				Content: unquoteTXT(set.Type, rr.Value),
+				TTL:     set.TTL,
+			})
+		}
+	}
+	return records, nil
+}
+
+// route53Unescape 还原 Route 53 返回名称中的八进制转义（如通配符 \052 -> *）
+func route53Unescape(name string) string {
+	if !strings.Contains(name, "\\") {
+		return name
+	}
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) {
+			if n, err := strconv.ParseUint(name[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// CreateRecord 创建记录（UPSERT）
+func (u *Route53Updater) CreateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.UpsertRecords(ctx, zone, []Record{rec})
+}
+
+// UpdateRecord 更新记录（UPSERT 会替换整个 RRset）
+func (u *Route53Updater) UpdateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.UpsertRecords(ctx, zone, []Record{rec})
+}
+
+// UpsertRecords 将 zone 中的所有变更合并为一个 change batch 提交，并等待变更状态变为 INSYNC
+func (u *Route53Updater) UpsertRecords(ctx context.Context, zone string, recs []Record) error {
+	zoneID, err := u.hostedZoneID(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	// 每个值对应一个 ResourceRecord 元素
+	type change struct {
+		Action    string           `xml:"Action"`
+		RecordSet route53RecordSet `xml:"ResourceRecordSet"`
+	}
+	var request struct {
+		XMLName xml.Name `xml:"ChangeResourceRecordSetsRequest"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Comment string   `xml:"ChangeBatch>Comment"`
+		Changes []change `xml:"ChangeBatch>Changes>Change"`
+	}
+	request.Xmlns = route53Namespace
+	request.Comment = "idrd dynamic DNS update"
+	for _, set := range groupRRSets(recs) {
+		c := change{Action: "UPSERT"}
+		c.RecordSet.Name = set[0].Name + "."
+		c.RecordSet.Type = set[0].Type
+		c.RecordSet.TTL = set[0].TTL
+		if c.RecordSet.TTL <= 0 {
+			c.RecordSet.TTL = u.ttl
+		}
+		for _, rec := range set {
+			c.RecordSet.ResourceRecords = append(c.RecordSet.ResourceRecords, route53ResourceRecord{Value: quoteTXT(rec)})
+		}
+		request.Changes = append(request.Changes, c)
+	}
+
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return err
+	}
+	body = append([]byte(xml.Header), body...)
+
+	var info route53ChangeInfo
+	if err := u.do(ctx, http.MethodPost, "/hostedzone/"+zoneID+"/rrset/", nil, body, &info); err != nil {
+		return u.failed(zone, err)
+	}
+	return u.waitInsync(ctx, info)
+}
+
+// waitInsync 轮询 GetChange 直到变更已同步到所有权威服务器
+func (u *Route53Updater) waitInsync(ctx context.Context, info route53ChangeInfo) error {
+	changeID := strings.TrimPrefix(info.ID, "/change/")
+	for info.Status != "INSYNC" {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("等待变更 %s 同步超时 (状态: %s): %w", changeID, info.Status, ctx.Err())
+		case <-time.After(route53PollInterval):
+		}
+		if err := u.do(ctx, http.MethodGet, "/change/"+changeID, nil, nil, &info); err != nil {
+			return fmt.Errorf("查询变更状态失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Verify 验证凭据是否可用（查询 Hosted Zone 数量）
+func (u *Route53Updater) Verify(ctx context.Context) error {
+	return u.do(ctx, http.MethodGet, "/hostedzonecount", nil, nil, nil)
+}
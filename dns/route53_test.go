@@ -0,0 +1,303 @@
+package dns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"idrd/config"
+)
+
+// emptyPayloadHash 空请求体的 SHA-256
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// AWS SigV4 文档中的签名示例（aws-sig-v4-test-suite get-vanilla 和 IAM ListUsers）
+func TestSigV4Signature(t *testing.T) {
+	const secret = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	tests := []struct {
+		name, service, query string
+		headers              map[string]string
+		wantRequest          string
+		wantSignature        string
+	}{
+		{
+			name:    "get-vanilla",
+			service: "service",
+			headers: map[string]string{"host": "example.amazonaws.com", "x-amz-date": "20150830T123600Z"},
+			wantRequest: "GET\n/\n\nhost:example.amazonaws.com\nx-amz-date:20150830T123600Z\n\nhost;x-amz-date\n" +
+				emptyPayloadHash,
+			wantSignature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:    "iam-list-users",
+			service: "iam",
+			query:   "Action=ListUsers&Version=2010-05-08",
+			headers: map[string]string{
+				"content-type": "application/x-www-form-urlencoded; charset=utf-8",
+				"host":         "iam.amazonaws.com",
+				"x-amz-date":   "20150830T123600Z",
+			},
+			wantRequest: "GET\n/\nAction=ListUsers&Version=2010-05-08\n" +
+				"content-type:application/x-www-form-urlencoded; charset=utf-8\nhost:iam.amazonaws.com\nx-amz-date:20150830T123600Z\n\n" +
+				"content-type;host;x-amz-date\n" + emptyPayloadHash,
+			wantSignature: "5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, tt := range tests {
+		request, _ := sigV4CanonicalRequest("GET", "/", tt.query, tt.headers, emptyPayloadHash)
+		if request != tt.wantRequest {
+			t.Errorf("%s: canonical request =\n%s\nwant\n%s", tt.name, request, tt.wantRequest)
+		}
+		if got := sigV4Signature(secret, "20150830T123600Z", "us-east-1", tt.service, request); got != tt.wantSignature {
+			t.Errorf("%s: signature = %s, want %s", tt.name, got, tt.wantSignature)
+		}
+	}
+}
+
+func TestRoute53Unescape(t *testing.T) {
+	for in, want := range map[string]string{
+		`\052.example.com.`:  "*.example.com.",
+		`a\100b.example.com`: "a@b.example.com",
+		"home.example.com.":  "home.example.com.",
+		`trailing\05`:        `trailing\05`,
+	} {
+		if got := route53Unescape(in); got != want {
+			t.Errorf("route53Unescape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+var authorizationRe = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/(\d{8})/us-east-1/route53/aws4_request, SignedHeaders=([a-z0-9;-]+), Signature=([0-9a-f]{64})$`)
+
+// testRoute53 Route 53 REST API 的替身：校验签名，保存 ChangeResourceRecordSets 提交的 RRset
+type testRoute53 struct {
+	mu      sync.Mutex
+	sets    []route53RecordSet // 名称按 Route 53 的方式转义（通配符为 \052）
+	changes int                // 收到的 ChangeResourceRecordSets 请求数
+}
+
+func newTestRoute53(t *testing.T, sets ...route53RecordSet) (*testRoute53, *Route53Updater) {
+	t.Helper()
+	fake := &testRoute53{sets: sets}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fake.verify(t, r, body)
+
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		w.Header().Set("Content-Type", "text/xml")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/2013-04-01/hostedzonesbyname":
+			io.WriteString(w, `<ListHostedZonesByNameResponse><HostedZones><HostedZone><Id>/hostedzone/Z1TEST</Id><Name>example.com.</Name></HostedZone></HostedZones></ListHostedZonesByNameResponse>`)
+		case r.Method == http.MethodGet && r.URL.Path == "/2013-04-01/hostedzone/Z1TEST/rrset":
+			// 与 Route 53 一样从 name/type 开始返回，未命中时返回下一个 RRset
+			name, recordType := r.URL.Query().Get("name"), r.URL.Query().Get("type")
+			var resp struct {
+				XMLName xml.Name           `xml:"ListResourceRecordSetsResponse"`
+				Sets    []route53RecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+			}
+			for _, set := range fake.sets {
+				if route53Unescape(set.Name) == name && set.Type == recordType {
+					resp.Sets = []route53RecordSet{set}
+					break
+				}
+				if resp.Sets == nil {
+					resp.Sets = []route53RecordSet{set}
+				}
+			}
+			out, _ := xml.Marshal(resp)
+			w.Write(out)
+		case r.Method == http.MethodPost && r.URL.Path == "/2013-04-01/hostedzone/Z1TEST/rrset/":
+			fake.change(t, body)
+			io.WriteString(w, `<ChangeResourceRecordSetsResponse><ChangeInfo><Id>/change/C1</Id><Status>INSYNC</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := newRoute53Updater(config.DNSAccount{
+		Name:       t.Name(), // Hosted Zone ID 按账户缓存
+		APIToken:   "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Properties: map[string]string{"access_key_id": "AKIDEXAMPLE", "endpoint": srv.URL, "ttl": "60"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, u.(*Route53Updater)
+}
+
+// verify 按请求中的签名头部重新计算签名
+func (fake *testRoute53) verify(t *testing.T, r *http.Request, body []byte) {
+	m := authorizationRe.FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil {
+		t.Errorf("%s %s: malformed Authorization %q", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+		return
+	}
+	payloadHash := sha256.Sum256(body)
+	if got := r.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(payloadHash[:]) {
+		t.Errorf("%s %s: X-Amz-Content-Sha256 = %s, want body hash", r.Method, r.URL.Path, got)
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if !strings.HasPrefix(amzDate, m[1]) {
+		t.Errorf("credential scope date %s does not match X-Amz-Date %s", m[1], amzDate)
+	}
+
+	headers := map[string]string{}
+	for _, name := range strings.Split(m[2], ";") {
+		if name == "host" {
+			headers[name] = r.Host
+		} else {
+			headers[name] = r.Header.Get(name)
+		}
+	}
+	request, _ := sigV4CanonicalRequest(r.Method, r.URL.EscapedPath(), r.URL.RawQuery, headers, hex.EncodeToString(payloadHash[:]))
+	if want := sigV4Signature("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", amzDate, "us-east-1", "route53", request); m[3] != want {
+		t.Errorf("%s %s: signature = %s, want %s", r.Method, r.URL.Path, m[3], want)
+	}
+}
+
+// change 按 UPSERT 语义替换整个 RRset
+func (fake *testRoute53) change(t *testing.T, body []byte) {
+	var req struct {
+		XMLName xml.Name `xml:"ChangeResourceRecordSetsRequest"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Changes []struct {
+			Action    string           `xml:"Action"`
+			RecordSet route53RecordSet `xml:"ResourceRecordSet"`
+		} `xml:"ChangeBatch>Changes>Change"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		t.Errorf("ChangeResourceRecordSets body: %v\n%s", err, body)
+		return
+	}
+	if req.Xmlns != route53Namespace {
+		t.Errorf("xmlns = %q, want %q", req.Xmlns, route53Namespace)
+	}
+	fake.changes++
+	for _, c := range req.Changes {
+		if c.Action != "UPSERT" {
+			t.Errorf("action = %s, want UPSERT", c.Action)
+		}
+		set := c.RecordSet
+		set.Name = strings.ReplaceAll(set.Name, "*", `\052`)
+		fake.sets = slices.DeleteFunc(fake.sets, func(s route53RecordSet) bool {
+			return s.Name == set.Name && s.Type == set.Type
+		})
+		fake.sets = append(fake.sets, set)
+	}
+}
+
+// recordSet 构造测试用 RRset
+func recordSet(name, recordType string, ttl int, values ...string) route53RecordSet {
+	set := route53RecordSet{Name: name, Type: recordType, TTL: ttl}
+	for _, v := range values {
+		set.ResourceRecords = append(set.ResourceRecords, route53ResourceRecord{Value: v})
+	}
+	return set
+}
+
+func TestRoute53ListRecords(t *testing.T) {
+	_, u := newTestRoute53(t,
+		recordSet(`\052.example.com.`, "A", 300, "192.0.2.1", "192.0.2.2"),
+		recordSet("www.example.com.", "A", 300, "192.0.2.9"),
+	)
+	ctx := t.Context()
+
+	got, err := u.ListRecords(ctx, "example.com", "*.example.com", "A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Content != "192.0.2.1" || got[1].Content != "192.0.2.2" || got[0].Name != "*.example.com" || got[0].TTL != 300 {
+		t.Errorf("wildcard records = %+v", got)
+	}
+
+	// 返回的下一个 RRset 属于其他名称
+	if got, err := u.ListRecords(ctx, "example.com", "home.example.com", "A"); err != nil || len(got) != 0 {
+		t.Errorf("ListRecords missing = %+v, %v; want none", got, err)
+	}
+}
+
+func TestRoute53UpsertRecords(t *testing.T) {
+	fake, u := newTestRoute53(t)
+	ctx := t.Context()
+
+	err := u.UpsertRecords(ctx, "example.com", []Record{
+		{Type: "TXT", Name: "_idrd.*.example.com", Content: "heritage=idrd,owner=test"},
+		{Type: "A", Name: "*.example.com", Content: "198.51.100.1"},
+		{Type: "A", Name: "*.example.com", Content: "198.51.100.2"},
+		{Type: "AAAA", Name: "home.example.com", Content: "2001:db8::1", TTL: 120},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.changes != 1 {
+		t.Fatalf("%d ChangeResourceRecordSets requests, want 1", fake.changes)
+	}
+	if len(fake.sets) != 3 {
+		t.Errorf("%d RRsets after upsert, want 3", len(fake.sets))
+	}
+
+	// 同名同类型的记录合并为一个 RRset，未指定 TTL 时使用账户默认 TTL
+	got, err := u.ListRecords(ctx, "example.com", "*.example.com", "A")
+	if err != nil || len(got) != 2 || got[0].Content != "198.51.100.1" || got[1].Content != "198.51.100.2" || got[0].TTL != 60 {
+		t.Errorf("A records = %+v, %v", got, err)
+	}
+	got, err = u.ListRecords(ctx, "example.com", "home.example.com", "AAAA")
+	if err != nil || len(got) != 1 || got[0].TTL != 120 {
+		t.Errorf("AAAA records = %+v, %v", got, err)
+	}
+	got, err = u.ListRecords(ctx, "example.com", "_idrd.*.example.com", "TXT")
+	if err != nil || len(got) != 1 || got[0].ID != `"heritage=idrd,owner=test"` || got[0].Content != "heritage=idrd,owner=test" {
+		t.Errorf("TXT records = %+v, %v", got, err)
+	}
+}
+
+// 非 rrset 模式下 UPSERT 单个值会删除 RRset 中的其他值，同步应拒绝
+func TestRoute53SyncRefusesMultiValueSet(t *testing.T) {
+	fake, u := newTestRoute53(t, recordSet(`\052.example.com.`, "A", 300, "192.0.2.1", "192.0.2.2"))
+	m := &Manager{}
+	zone := config.Zone{ZoneName: "example.com", Records: []config.RecordConfig{{Name: "*"}}}
+
+	results := m.syncZoneBatch(t.Context(), "test", u, nil, zone, "A", "198.51.100.7", false)
+	if len(results) != 1 || results[0].Error == "" || results[0].ErrorClass != ErrorClassValidation {
+		t.Fatalf("results = %+v, want a validation error", results)
+	}
+	if fake.changes != 0 {
+		t.Errorf("%d ChangeResourceRecordSets requests, want none", fake.changes)
+	}
+}
+
+// 批量提交整体替换 RRset 时，所有权标记与同名 TXT 记录组中其他实例的标记一起提交
+func TestRoute53BatchKeepsForeignMarker(t *testing.T) {
+	_, u := newTestRoute53(t, recordSet("_idrd.home.example.com.", "TXT", 300, `"heritage=idrd,idrd/owner=other"`))
+	m := &Manager{}
+	own := &ownership{method: OwnershipTXT, owner: "me"}
+	zone := config.Zone{ZoneName: "example.com", Records: []config.RecordConfig{{Name: "home"}}}
+
+	results := m.syncZoneBatch(t.Context(), "test", u, own, zone, "A", "198.51.100.7", false)
+	if len(results) != 1 || results[0].Error != "" || results[0].Action != ActionCreate {
+		t.Fatalf("results = %+v, want create", results)
+	}
+
+	got, err := u.ListRecords(t.Context(), "example.com", "_idrd.home.example.com", "TXT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var contents []string
+	for _, r := range got {
+		contents = append(contents, r.Content)
+	}
+	if want := []string{"heritage=idrd,idrd/owner=other", "heritage=idrd,idrd/owner=me"}; !slices.Equal(contents, want) {
+		t.Errorf("markers = %v, want %v", contents, want)
+	}
+}
@@ -0,0 +1,316 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"idrd/config"
+	"log"
+	"slices"
+	"strings"
+)
+
+// SetIPs 保存所有出口的当前 IP（多 WAN），供 rrset 模式的记录使用，返回地址组是否变化
+func (m *Manager) SetIPs(ips []string) bool {
+	m.ipsMu.Lock()
+	defer m.ipsMu.Unlock()
+	if slices.Equal(m.ips, ips) {
+		return false
+	}
+	m.ips = slices.Clone(ips)
+	return true
+}
+
+// rrsetContents 返回 rrset 模式记录的期望内容：ip 加上地址组中同类型的其他地址
+func (m *Manager) rrsetContents(ip, recordType string) []string {
+	m.ipsMu.Lock()
+	defer m.ipsMu.Unlock()
+	contents := []string{ip}
+	for _, other := range m.ips {
+		if other != ip && RecordTypeFor(other) == recordType {
+			contents = append(contents, other)
+		}
+	}
+	return contents
+}
+
+// selectRecord 从同名记录中选出要管理的一条：未配置选择器时取第一条
+func selectRecord(records []Record, sel *config.RecordSelector) (Record, bool) {
+	for _, r := range records {
+		if sel == nil || matchesSelector(r, sel) {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
+
+// matchesSelector 判断记录是否满足选择器的全部条件（备注比较时忽略 idrd 所有权标记）
+func matchesSelector(r Record, sel *config.RecordSelector) bool {
+	if sel.ID != "" && r.ID != sel.ID {
+		return false
+	}
+	if sel.Comment != "" && (r.Comment == nil || commentText(*r.Comment) != sel.Comment) {
+		return false
+	}
+	if sel.Tag != "" && !slices.Contains(r.Tags, sel.Tag) {
+		return false
+	}
+	return true
+}
+
+// commentText 去掉备注中的 idrd 所有权标记
+func commentText(comment string) string {
+	if i := strings.Index(comment, "[idrd:"); i >= 0 {
+		if j := strings.Index(comment[i:], "]"); j >= 0 {
+			comment = comment[:i] + comment[i+j+1:]
+		}
+	}
+	return strings.TrimSpace(comment)
+}
+
+// withSelectorTag 新建记录时写入选择器的标签，使之后的同步能选中该记录
+func withSelectorTag(rec Record, sel *config.RecordSelector) Record {
+	if sel != nil && sel.Tag != "" && !slices.Contains(rec.Tags, sel.Tag) {
+		rec.Tags = append(slices.Clone(rec.Tags), sel.Tag)
+	}
+	return rec
+}
+
+// syncRRSet 使同名同类型的整组记录与 contents 完全一致（无重试），将执行的动作和原内容填入 rec，返回受管记录的 ID
+// 已有地址保持不变，多余的记录优先改写为缺少的地址，仍多余的记录被删除
+func (m *Manager) syncRRSet(ctx context.Context, u Updater, own *ownership, zone string, desired Record, adopt bool, contents []string, rec *RecordReport) ([]string, error) {
+	d, ok := u.(Deleter)
+	if !ok {
+		return nil, Permanent(fmt.Errorf("该服务商不支持 rrset 模式（无法单独删除同名记录）"))
+	}
+
+	records, err := u.ListRecords(ctx, zone, desired.Name, desired.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if own != nil {
+		adopted := len(records) == 0
+		for _, r := range records {
+			a, err := own.checkOrAdopt(ctx, u, zone, r, adopt)
+			if err != nil {
+				return nil, err
+			}
+			adopted = adopted || a
+			if own.method == OwnershipTXT {
+				break // TXT 标记按名称，检查一条即可
+			}
+		}
+		if adopted {
+			if err := own.claim(ctx, u, zone, desired); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	old := make([]string, 0, len(records))
+	for _, r := range records {
+		old = append(old, r.Content)
+	}
+	rec.Old = strings.Join(old, ", ")
+
+	keep, spare, missing := diffRRSet(records, contents)
+	var ids []string
+	for _, r := range keep {
+		ids = append(ids, r.ID)
+	}
+	action := ActionNoop
+	if len(records) == 0 {
+		action = ActionCreate
+	}
+
+	for _, r := range keep {
+		want := desired
+		want.Content = r.Content
+		if drift := driftFields(r, want); len(drift) > 0 {
+			if err := u.UpdateRecord(ctx, zone, applyDesired(r, want)); err != nil {
+				return nil, err
+			}
+			log.Printf("✅ 更新 DNS 记录属性成功: %s (%s)", desired.Name, strings.Join(drift, ", "))
+			action = ActionUpdate
+		}
+	}
+
+	for _, content := range missing {
+		want := desired
+		want.Content = content
+		if len(spare) > 0 {
+			r := spare[0]
+			spare = spare[1:]
+			if err := u.UpdateRecord(ctx, zone, applyDesired(r, want)); err != nil {
+				return nil, err
+			}
+			log.Printf("✅ 更新 DNS 记录成功: %s %s -> %s", desired.Name, r.Content, content)
+			ids = append(ids, r.ID)
+			action = ActionUpdate
+			continue
+		}
+		if err := u.CreateRecord(ctx, zone, want); err != nil {
+			return nil, err
+		}
+		log.Printf("✅ 创建 DNS 记录成功: %s -> %s", desired.Name, content)
+		ids = nil // 新建记录的 ID 未知，清理时按内容匹配
+		if action == ActionNoop {
+			action = ActionUpdate
+		}
+	}
+
+	for _, r := range spare {
+		if err := d.DeleteRecord(ctx, zone, r); err != nil {
+			return nil, err
+		}
+		log.Printf("🗑️  删除多余的 DNS 记录: %s -> %s", desired.Name, r.Content)
+		action = ActionUpdate
+	}
+
+	rec.Action = action
+	return ids, nil
+}
+
+// rrsetBatch 批量提交的服务商中 rrset 模式记录的变更
+type rrsetBatch struct {
+	records []Record // 整组记录（每个地址一条），服务商整体替换同名同类型的 RRset
+	drift   []string
+	adopted bool // 接管了不属于本实例的记录，需要写入所有权标记
+}
+
+// batchRRSet 比对已列出的整组记录与 contents，将执行的动作和原内容填入 rec
+// 需要变更时返回替换整个 RRset 的记录（批量提交的服务商以 UPSERT/REPLACE 整体写入，不需要 Deleter）
+func batchRRSet(ctx context.Context, u Updater, own *ownership, zone string, desired Record, adopt bool, contents []string, records []Record, rec *RecordReport) (rrsetBatch, error) {
+	var batch rrsetBatch
+	if own != nil {
+		for _, r := range records {
+			a, err := own.checkOrAdopt(ctx, u, zone, r, adopt)
+			if err != nil {
+				return batch, err
+			}
+			batch.adopted = batch.adopted || a
+			if own.method == OwnershipTXT {
+				break // TXT 标记按名称，检查一条即可
+			}
+		}
+		if batch.adopted {
+			batch.drift = append(batch.drift, "adopt")
+		}
+	}
+
+	old := make([]string, 0, len(records))
+	for _, r := range records {
+		old = append(old, r.Content)
+	}
+	rec.Old = strings.Join(old, ", ")
+
+	keep, spare, missing := diffRRSet(records, contents)
+	for _, c := range missing {
+		batch.drift = append(batch.drift, "add "+c)
+	}
+	for _, r := range spare {
+		batch.drift = append(batch.drift, "remove "+r.Content)
+	}
+	for _, r := range keep {
+		want := desired
+		want.Content = r.Content
+		batch.drift = append(batch.drift, driftFields(r, want)...)
+	}
+
+	switch {
+	case len(records) == 0:
+		rec.Action = ActionCreate
+	case len(batch.drift) == 0:
+		rec.Action = ActionNoop
+		return batch, nil
+	default:
+		rec.Action = ActionUpdate
+	}
+	for _, c := range contents {
+		want := desired
+		want.Content = c
+		batch.records = append(batch.records, want)
+	}
+	return batch, nil
+}
+
+// diffRRSet 将现有记录与期望内容比对：keep 为内容已存在的记录，spare 为多余的记录，missing 为缺少的内容
+func diffRRSet(records []Record, contents []string) (keep, spare []Record, missing []string) {
+	want := make(map[string]bool, len(contents))
+	for _, c := range contents {
+		want[c] = true
+	}
+	found := make(map[string]bool, len(contents))
+	for _, r := range records {
+		if want[r.Content] && !found[r.Content] {
+			found[r.Content] = true
+			keep = append(keep, r)
+		} else {
+			spare = append(spare, r)
+		}
+	}
+	for _, c := range contents {
+		if !found[c] {
+			missing = append(missing, c)
+		}
+	}
+	return keep, spare, missing
+}
+
+// planRRSet 计算 rrset 模式记录的计划动作
+func planRRSet(ctx context.Context, u Updater, own *ownership, zone string, desired Record, adopt bool, contents []string, change *PlannedChange) {
+	change.Desired = strings.Join(contents, ", ")
+	_, batch := u.(BatchUpdater)
+	if _, ok := u.(Deleter); !ok && !batch {
+		change.Action, change.Error = ActionError, "该服务商不支持 rrset 模式（无法单独删除同名记录）"
+		return
+	}
+
+	records, err := u.ListRecords(ctx, zone, desired.Name, desired.Type)
+	if err != nil {
+		change.Action, change.Error = ActionError, err.Error()
+		return
+	}
+	if len(records) == 0 {
+		change.Action = ActionCreate
+		return
+	}
+
+	current := make([]string, 0, len(records))
+	for _, r := range records {
+		current = append(current, r.Content)
+	}
+	change.Current = strings.Join(current, ", ")
+
+	if own != nil {
+		if err := own.check(ctx, u, zone, records[0]); err != nil {
+			switch {
+			case !isOwnershipError(err):
+				change.Action, change.Error = ActionError, err.Error()
+				return
+			case !adopt:
+				change.Action, change.Error = ActionConflict, err.Error()
+				return
+			}
+			change.Drift = append(change.Drift, "adopt")
+		}
+	}
+
+	keep, spare, missing := diffRRSet(records, contents)
+	for _, c := range missing {
+		change.Drift = append(change.Drift, "add "+c)
+	}
+	for _, r := range spare {
+		change.Drift = append(change.Drift, "remove "+r.Content)
+	}
+	for _, r := range keep {
+		want := desired
+		want.Content = r.Content
+		change.Drift = append(change.Drift, driftFields(r, want)...)
+	}
+
+	change.Action = ActionNoop
+	if len(change.Drift) > 0 {
+		change.Action = ActionUpdate
+	}
+}
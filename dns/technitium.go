@@ -0,0 +1,201 @@
+package dns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"idrd/config"
+	"idrd/ip"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTechnitiumTTL = 300
+
+// TechnitiumUpdater 通过 Technitium DNS Server HTTP API 更新记录
+type TechnitiumUpdater struct {
+	baseURL string // 如 http://127.0.0.1:5380
+	token   string
+	ttl     int
+	client  *http.Client
+}
+
+func init() {
+	Register(Backend{
+		Provider:   "technitium",
+		Name:       "Technitium DNS Server",
+		Ownership:  OwnershipTXT,
+		TokenLabel: "API Token",
+		Properties: []ip.PropertySchema{
+			{Name: "url", Label: "API URL", Type: "string", Required: true, Help: "Technitium Web 控制台地址，如 http://127.0.0.1:5380"},
+			{Name: "ttl", Label: "TTL", Type: "number", Default: strconv.Itoa(defaultTechnitiumTTL), Help: "新建记录的 TTL（秒），已有记录保留原 TTL"},
+		},
+		New: newTechnitiumUpdater,
+		Validate: func(a *config.DNSAccount) error {
+			if a.APIToken == "" {
+				return fmt.Errorf("API token required")
+			}
+			return validateAPIURL(a.Properties["url"], a.Properties["ttl"])
+		},
+	})
+}
+
+// newTechnitiumUpdater 根据账户配置创建 Technitium 客户端
+func newTechnitiumUpdater(account config.DNSAccount) (Updater, error) {
+	props := account.Properties
+	if props["url"] == "" || account.APIToken == "" {
+		return nil, fmt.Errorf("url or API token is empty")
+	}
+
+	u := &TechnitiumUpdater{
+		baseURL: strings.TrimSuffix(props["url"], "/"),
+		token:   account.APIToken,
+		ttl:     defaultTechnitiumTTL,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+	if ttl, err := strconv.Atoi(props["ttl"]); err == nil && ttl > 0 {
+		u.ttl = ttl
+	}
+	return u, nil
+}
+
+// call 调用 API，并将 response 字段解析到 out
+// Technitium 总是返回 HTTP 200，通过 status 字段区分成功和失败
+func (u *TechnitiumUpdater) call(ctx context.Context, path string, params url.Values, out any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("token", u.token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.baseURL+path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return httpStatusError("technitium", path, resp.StatusCode)
+	}
+
+	var envelope struct {
+		Status       string          `json:"status"`
+		ErrorMessage string          `json:"errorMessage"`
+		Response     json.RawMessage `json:"response"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return fmt.Errorf("technitium %s: 解析响应失败: %w", path, err)
+	}
+	if envelope.Status != "ok" {
+		return &ProviderError{Provider: "technitium", Code: envelope.Status, Message: envelope.ErrorMessage, Class: technitiumErrorClass(envelope.Status, envelope.ErrorMessage)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Response, out)
+}
+
+// technitiumErrorClass 按 status 分类错误：invalid-token 为凭据错误，
+// error 按消息区分 zone 或记录不存在与请求参数错误（Technitium 没有错误码）
+func technitiumErrorClass(status, message string) string {
+	if status == "invalid-token" {
+		return ErrorClassAuth
+	}
+	msg := strings.ToLower(message)
+	if strings.Contains(msg, "not found") || strings.Contains(msg, "no such") {
+		return ErrorClassNotFound
+	}
+	return ErrorClassValidation
+}
+
+// ListRecords 列出 zone 中指定名称和类型的记录（忽略已禁用的记录）
+func (u *TechnitiumUpdater) ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error) {
+	var resp struct {
+		Records []struct {
+			Name     string `json:"name"`
+			Type     string `json:"type"`
+			TTL      int    `json:"ttl"`
+			Disabled bool   `json:"disabled"`
+			RData    struct {
+				IPAddress string `json:"ipAddress"`
+				Text      string `json:"text"`
+			} `json:"rData"`
+		} `json:"records"`
+	}
+	err := u.call(ctx, "/api/zones/records/get", url.Values{
+		"domain":   {name},
+		"zone":     {zone},
+		"listZone": {"false"},
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, r := range resp.Records {
+		if !strings.EqualFold(r.Name, name) || r.Type != recordType || r.Disabled {
+			continue
+		}
+		content := r.RData.IPAddress
+		if r.Type == "TXT" {
+			content = r.RData.Text
+		}
+		records = append(records, Record{
+			ID:      content,
+			Type:    r.Type,
+			Name:    name,
+			Content: content,
+			TTL:     r.TTL,
+		})
+	}
+	return records, nil
+}
+
+// CreateRecord 向同名同类型的记录组添加记录，已有记录（如其他实例的所有权标记）保留
+func (u *TechnitiumUpdater) CreateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.addRecord(ctx, zone, rec, false)
+}
+
+// UpdateRecord 更新记录（overwrite 替换同名同类型的全部记录）
+func (u *TechnitiumUpdater) UpdateRecord(ctx context.Context, zone string, rec Record) error {
+	return u.addRecord(ctx, zone, rec, true)
+}
+
+// addRecord 写入记录，overwrite 为 true 时一次请求完成替换
+func (u *TechnitiumUpdater) addRecord(ctx context.Context, zone string, rec Record, overwrite bool) error {
+	ttl := rec.TTL
+	if ttl <= 0 {
+		ttl = u.ttl
+	}
+	params := url.Values{
+		"domain":    {rec.Name},
+		"zone":      {zone},
+		"type":      {rec.Type},
+		"ttl":       {strconv.Itoa(ttl)},
+		"overwrite": {strconv.FormatBool(overwrite)},
+	}
+	if rec.Type == "TXT" {
+		params.Set("text", rec.Content)
+	} else {
+		params.Set("ipAddress", rec.Content)
+	}
+	return u.call(ctx, "/api/zones/records/add", params, nil)
+}
+
+// Verify 验证 API Token 是否可用
+func (u *TechnitiumUpdater) Verify(ctx context.Context) error {
+	return u.call(ctx, "/api/zones/list", nil, nil)
+}
@@ -0,0 +1,48 @@
+package dns
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"idrd/config"
+)
+
+func TestTechnitiumErrorClass(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		wantCode  string
+		wantClass string
+	}{
+		{http.StatusOK, `{"status":"invalid-token","errorMessage":"Invalid token or session expired."}`, "invalid-token", ErrorClassAuth},
+		{http.StatusOK, `{"status":"error","errorMessage":"No such zone was found: example.com"}`, "error", ErrorClassNotFound},
+		{http.StatusOK, `{"status":"error","errorMessage":"Zone was not found: example.com"}`, "error", ErrorClassNotFound},
+		{http.StatusOK, `{"status":"error","errorMessage":"Invalid IP address: 300.1.1.1"}`, "error", ErrorClassValidation},
+		{http.StatusForbidden, `Forbidden`, "403", ErrorClassAuth},
+		{http.StatusBadGateway, `Bad Gateway`, "502", ErrorClassTransient},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := r.ParseForm(); err != nil || r.PostForm.Get("token") != "token" {
+				t.Errorf("token = %q, %v", r.PostForm.Get("token"), err)
+			}
+			w.WriteHeader(tt.status)
+			io.WriteString(w, tt.body)
+		}))
+		u, err := newTechnitiumUpdater(config.DNSAccount{APIToken: "token", Properties: map[string]string{"url": srv.URL}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = u.ListRecords(t.Context(), "example.com", "home.example.com", "A")
+		if ErrorCode(err) != tt.wantCode || ErrorClass(err) != tt.wantClass {
+			t.Errorf("%s: code %q class %q, want %q %q", tt.body, ErrorCode(err), ErrorClass(err), tt.wantCode, tt.wantClass)
+		}
+		if IsPermanent(err) != (tt.wantClass != ErrorClassTransient) {
+			t.Errorf("%s: IsPermanent = %v", tt.body, IsPermanent(err))
+		}
+		srv.Close()
+	}
+}
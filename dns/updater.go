@@ -0,0 +1,294 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"idrd/config"
+	"idrd/ip"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record 服务商无关的 DNS 记录
+type Record struct {
+	ID      string // 服务商内部的记录 ID（创建时为空）
+	Type    string // A, AAAA
+	Name    string // 完整域名 (FQDN)
+	Content string
+	TTL     int      // 0 表示使用服务商默认值
+	Proxied *bool    // 仅部分服务商支持（Cloudflare）
+	Comment *string  // 记录备注，nil 表示服务商不支持或不修改
+	Tags    []string // 记录标签，nil 表示服务商不支持或不修改
+}
+
+// Updater 单个 DNS 服务商账户的记录操作
+type Updater interface {
+	// ListRecords 列出 zone 中指定名称和类型的记录
+	ListRecords(ctx context.Context, zone, name, recordType string) ([]Record, error)
+	// CreateRecord 在 zone 中创建记录
+	CreateRecord(ctx context.Context, zone string, rec Record) error
+	// UpdateRecord 更新已有记录（rec.ID 来自 ListRecords）
+	UpdateRecord(ctx context.Context, zone string, rec Record) error
+	// Verify 验证账户凭据是否可用
+	Verify(ctx context.Context) error
+}
+
+// BatchUpdater 可选接口：支持将同一 zone 的多条记录合并为一次变更提交的服务商
+type BatchUpdater interface {
+	Updater
+	// UpsertRecords 一次性创建或更新 zone 中的多条记录，返回时变更应已生效
+	// 同名同类型的多条记录替换为一个包含全部内容的 RRset（rrset 模式）
+	UpsertRecords(ctx context.Context, zone string, recs []Record) error
+}
+
+// groupRRSets 将同名同类型的记录合并为一组（保持首次出现的顺序），批量提交时每组作为一个 RRset 整体写入
+func groupRRSets(recs []Record) [][]Record {
+	var groups [][]Record
+	index := make(map[string]int)
+	for _, rec := range recs {
+		key := strings.ToLower(rec.Name) + "|" + rec.Type
+		if i, ok := index[key]; ok {
+			groups[i] = append(groups[i], rec)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []Record{rec})
+	}
+	return groups
+}
+
+// Deleter 可选接口：支持按 ID 删除单条记录的服务商（同名多条记录可独立增删）
+// 不支持批量提交的服务商的 rrset 模式依赖该接口删除多余的记录
+type Deleter interface {
+	Updater
+	DeleteRecord(ctx context.Context, zone string, rec Record) error
+}
+
+// Backend 描述一种 DNS 服务商：构造函数、账户校验函数和 properties 定义
+type Backend struct {
+	Provider   string              `json:"provider"`
+	Name       string              `json:"name"`
+	TokenLabel string              `json:"token_label,omitempty"` // api_token 字段在该服务商下的含义
+	Properties []ip.PropertySchema `json:"properties"`            // 账户 properties 的属性定义，复用 IP 提供者的表单描述
+	Ownership  string              `json:"ownership,omitempty"`   // 所有权标记方式（OwnershipComment 或 OwnershipTXT），为空表示不支持
+	// MaxConcurrency 同一账户同时处理的 zone 数上限（服务商 API 速率限制），0 表示不限制
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+
+	// New 根据账户配置构造 Updater
+	New func(account config.DNSAccount) (Updater, error) `json:"-"`
+	// Validate 校验账户的服务商特定字段（可选）
+	Validate func(account *config.DNSAccount) error `json:"-"`
+}
+
+var backends = map[string]*Backend{}
+
+// Register 注册 DNS 服务商，通常在各服务商文件的 init 中调用
+// 同时将校验函数注册到 config 包
+func Register(b Backend) {
+	if b.Provider == "" || b.New == nil {
+		panic("dns: Register 需要 Provider 和 New")
+	}
+	if _, exists := backends[b.Provider]; exists {
+		panic(fmt.Sprintf("dns: 服务商 %s 重复注册", b.Provider))
+	}
+	backend := b
+	backends[b.Provider] = &backend
+	config.RegisterDNSAccountValidator(b.Provider, backend.validate)
+}
+
+// validate 通用校验：检查必填 properties，再调用服务商自身的校验函数
+func (b *Backend) validate(a *config.DNSAccount) error {
+	for _, p := range b.Properties {
+		if p.Required && a.Properties[p.Name] == "" {
+			if p.Default == "" {
+				return fmt.Errorf("%s required", p.Name)
+			}
+			if a.Properties == nil {
+				a.Properties = map[string]string{}
+			}
+			a.Properties[p.Name] = p.Default
+		}
+	}
+	if b.Validate != nil {
+		return b.Validate(a)
+	}
+	return nil
+}
+
+// Backends 返回所有已注册的服务商（按类型名排序）
+func Backends() []Backend {
+	list := make([]Backend, 0, len(backends))
+	for _, b := range backends {
+		list = append(list, *b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Provider < list[j].Provider })
+	return list
+}
+
+// NewUpdater 根据账户配置构造对应服务商的 Updater
+func NewUpdater(account config.DNSAccount) (Updater, error) {
+	b, ok := backends[account.ProviderName()]
+	if !ok {
+		return nil, fmt.Errorf("unknown DNS provider: %s", account.ProviderName())
+	}
+	return b.New(account)
+}
+
+// quoteTXT 将 TXT 内容转换为需要引号的服务商（RFC 2136、PowerDNS、Route 53）使用的格式
+func quoteTXT(rec Record) string {
+	if rec.Type == "TXT" {
+		return strconv.Quote(rec.Content)
+	}
+	return rec.Content
+}
+
+// unquoteTXT 去掉 TXT 内容两侧的引号，多段字符串拼接为一段
+func unquoteTXT(recordType, content string) string {
+	if recordType != "TXT" {
+		return content
+	}
+	var parts []string
+	for rest := strings.TrimSpace(content); rest != ""; rest = strings.TrimSpace(rest) {
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return content
+		}
+		part, _ := strconv.Unquote(quoted)
+		parts = append(parts, part)
+		rest = rest[len(quoted):]
+	}
+	return strings.Join(parts, "")
+}
+
+// RecordTypeFor 根据 IP 版本返回记录类型（A 或 AAAA）
+func RecordTypeFor(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return "AAAA"
+	}
+	return "A"
+}
+
+// FQDN 将 zone 中的记录名（@ 表示根域名）转换为完整域名
+func FQDN(record, zone string) string {
+	if record == "@" || record == "" {
+		return zone
+	}
+	return fmt.Sprintf("%s.%s", record, zone)
+}
+
+// RelativeName 将完整域名转换为 zone 内的记录名（根域名返回 @）
+func RelativeName(name, zone string) string {
+	name = strings.TrimSuffix(name, ".")
+	zone = strings.TrimSuffix(zone, ".")
+	if strings.EqualFold(name, zone) {
+		return "@"
+	}
+	return strings.TrimSuffix(name, "."+zone)
+}
+
+// 错误分类：只有 transient 和 rate_limit 会重试
+const (
+	ErrorClassAuth       = "auth"       // 凭据无效或权限不足
+	ErrorClassNotFound   = "not_found"  // zone 或记录不存在
+	ErrorClassValidation = "validation" // 请求参数或配置错误
+	ErrorClassRateLimit  = "rate_limit" // 超出服务商速率限制，按 RetryAfter 等待后重试
+	ErrorClassTransient  = "transient"  // 网络错误、服务商内部错误等临时故障
+)
+
+// ProviderError 服务商 API 返回的业务错误
+type ProviderError struct {
+	Provider   string
+	Code       string
+	Message    string
+	Class      string        // 错误分类（ErrorClass*），为空时按 Permanent 判断
+	Permanent  bool          // 重试无意义的错误（如凭据无效），Manager 不会重试
+	RetryAfter time.Duration // 速率限制时服务商要求的等待时间（Retry-After），0 表示未指定
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s API error %s: %s", e.Provider, e.Code, e.Message)
+}
+
+// ErrorCode 提取错误链中的服务商错误码，没有时返回空
+func ErrorCode(err error) string {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe.Code
+	}
+	if isOwnershipError(err) {
+		return "not_owned"
+	}
+	return ""
+}
+
+// ErrorClass 返回错误的分类，未分类的错误（网络错误等）视为 transient
+func ErrorClass(err error) string {
+	var pe *ProviderError
+	var perm *permanentError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &pe) && pe.Class != "":
+		return pe.Class
+	case errors.As(err, &pe) && pe.Permanent, errors.As(err, &perm), isOwnershipError(err):
+		return ErrorClassValidation
+	}
+	return ErrorClassTransient
+}
+
+// httpErrorClass 按 HTTP 状态码分类服务商错误
+func httpErrorClass(status int) string {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrorClassAuth
+	case status == http.StatusNotFound:
+		return ErrorClassNotFound
+	case status == http.StatusTooManyRequests:
+		return ErrorClassRateLimit
+	case status >= 500:
+		return ErrorClassTransient
+	}
+	return ErrorClassValidation
+}
+
+// httpStatusError 服务商返回无法解析的错误响应时，按 HTTP 状态码构造错误，request 说明请求（如方法和路径）
+func httpStatusError(provider, request string, status int) error {
+	return &ProviderError{Provider: provider, Code: strconv.Itoa(status), Message: request + ": " + http.StatusText(status), Class: httpErrorClass(status)}
+}
+
+// retryAfter 返回错误链中服务商要求的等待时间
+func retryAfter(err error) time.Duration {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe.RetryAfter
+	}
+	return 0
+}
+
+// IsPermanent 判断错误是否不应重试：除 transient 和 rate_limit 以外的分类（凭据、不存在、参数错误、所有权冲突等）
+func IsPermanent(err error) bool {
+	class := ErrorClass(err)
+	return err != nil && class != ErrorClassTransient && class != ErrorClassRateLimit
+}
+
+// permanentError 标记为不应重试的错误
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent 将错误标记为不应重试（如配置错误），Manager 遇到时立即放弃
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// isOwnershipError 判断错误链中是否包含所有权冲突
+func isOwnershipError(err error) bool {
+	var oe *OwnershipError
+	return errors.As(err, &oe)
+}
@@ -0,0 +1,203 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"idrd/config"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ZoneDiscoverer 可选接口：支持列出凭据可访问的 zone 的服务商
+type ZoneDiscoverer interface {
+	Updater
+	// Zones 列出凭据可访问的 zone 及其权限
+	Zones(ctx context.Context) ([]ZoneAccess, error)
+}
+
+// 验证结果中使用的权限名称（沿用 Cloudflare Token 的权限名称）
+const (
+	PermissionDNSRead = "Zone:DNS:Read"
+	PermissionDNSEdit = "Zone:DNS:Edit"
+)
+
+// ZoneAccess 凭据可访问的 zone、权限和已有的 A/AAAA 记录
+type ZoneAccess struct {
+	Name               string             `json:"name"`
+	Status             string             `json:"status,omitempty"`              // 服务商的 zone 状态（如 Cloudflare 的 active、pending）
+	Editable           *bool              `json:"editable,omitempty"`            // 能否修改 DNS 记录，nil 表示服务商无法提供权限信息
+	Missing            []string           `json:"missing,omitempty"`             // 缺少的权限（PermissionDNSRead、PermissionDNSEdit）
+	PermissionsUnknown bool               `json:"permissions_unknown,omitempty"` // 服务商支持权限检查，但无法确定该 zone 的 DNS 权限（如 Token 无权读取自身的权限策略）
+	Configured         bool               `json:"configured,omitempty"`          // zone 已在账户配置中
+	Records            []DiscoveredRecord `json:"records"`
+	Error              string             `json:"error,omitempty"` // 列出记录失败的原因
+}
+
+// setPermissions 按能否读取和修改 DNS 记录设置 Editable 和 Missing
+func (z *ZoneAccess) setPermissions(readable, editable bool) {
+	z.Editable = &editable
+	if !readable {
+		z.Missing = append(z.Missing, PermissionDNSRead)
+	}
+	if !editable {
+		z.Missing = append(z.Missing, PermissionDNSEdit)
+	}
+}
+
+// DiscoveredRecord zone 中已有的 A/AAAA 记录
+type DiscoveredRecord struct {
+	Name    string `json:"name"`   // 完整域名
+	Record  string `json:"record"` // zone 内的记录名（根域名为 @），用于填入配置
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl,omitempty"`
+	Proxied *bool  `json:"proxied,omitempty"`
+}
+
+// AccountVerification 验证账户凭据并发现可访问的 zone 和记录的结果
+type AccountVerification struct {
+	Provider   string       `json:"provider"`
+	Account    string       `json:"account"`
+	Valid      bool         `json:"valid"` // 凭据有效
+	Error      string       `json:"error,omitempty"`
+	ErrorClass string       `json:"error_class,omitempty"`
+	Discovered bool         `json:"discovered"` // zone 由服务商列出；为 false 时只检查账户配置中的 zone
+	Zones      []ZoneAccess `json:"zones"`
+	LatencyMs  int64        `json:"latency_ms"`
+}
+
+// verifyConcurrency 验证时并行列出记录的 zone 数
+const verifyConcurrency = 4
+
+// VerifyAccount 使用给定的账户配置验证凭据，列出可访问的 zone 及其 A/AAAA 记录，不修改任何记录
+// 服务商不支持列出 zone 时检查账户配置中的 zone
+func VerifyAccount(account config.DNSAccount, timeout time.Duration) (result AccountVerification) {
+	start := time.Now()
+	result = AccountVerification{Provider: account.ProviderName(), Account: account.Name, Zones: []ZoneAccess{}}
+	defer func() { result.LatencyMs = time.Since(start).Milliseconds() }()
+
+	b, ok := backends[account.ProviderName()]
+	if !ok {
+		result.Error = fmt.Sprintf("unknown DNS provider: %s", account.ProviderName())
+		return result
+	}
+	// 在副本上校验，避免修改调用方的属性
+	account.Properties = maps.Clone(account.Properties)
+	if err := b.validate(&account); err != nil {
+		result.Error = "配置验证失败: " + err.Error()
+		return result
+	}
+	u, err := b.New(account)
+	if err != nil {
+		result.Error = "初始化失败: " + err.Error()
+		return result
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := u.Verify(ctx); err != nil {
+		result.Error = err.Error()
+		result.ErrorClass = ErrorClass(err)
+		return result
+	}
+	result.Valid = true
+
+	// 账户配置中每个 zone 的记录名（完整域名）
+	configured := map[string][]string{}
+	for _, z := range account.Zones {
+		names := []string{}
+		for _, r := range z.Records {
+			names = append(names, FQDN(r.Name, z.ZoneName))
+		}
+		configured[strings.ToLower(z.ZoneName)] = names
+	}
+
+	if d, ok := u.(ZoneDiscoverer); ok {
+		zones, err := d.Zones(ctx)
+		if err != nil {
+			result.Error = "列出 zone 失败: " + err.Error()
+			result.ErrorClass = ErrorClass(err)
+			return result
+		}
+		result.Discovered = true
+		result.Zones = zones
+	} else {
+		for _, z := range account.Zones {
+			result.Zones = append(result.Zones, ZoneAccess{Name: z.ZoneName})
+		}
+	}
+
+	sem := make(chan struct{}, verifyConcurrency)
+	var wg sync.WaitGroup
+	for i := range result.Zones {
+		zone := &result.Zones[i]
+		names, ok := configured[strings.ToLower(zone.Name)]
+		zone.Configured = ok
+		zone.Records = []DiscoveredRecord{}
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			discoverRecords(ctx, u, zone, names)
+		})
+	}
+	wg.Wait()
+
+	slices.SortFunc(result.Zones, func(a, b ZoneAccess) int { return strings.Compare(a.Name, b.Name) })
+	return result
+}
+
+// discoverRecords 列出 zone 中已有的 A/AAAA 记录，无读取权限时记录缺少的权限
+// 服务商不支持列出整个 zone 时逐个查询根域名和 names 中的记录
+func discoverRecords(ctx context.Context, u Updater, zone *ZoneAccess, names []string) {
+	var (
+		recs []Record
+		err  error
+	)
+	if lister, ok := u.(ZoneLister); ok {
+		recs, err = lister.ListZoneRecords(ctx, zone.Name)
+	} else {
+		queries := append([]string{zone.Name}, names...)
+		slices.Sort(queries)
+	query:
+		for _, name := range slices.Compact(queries) {
+			for _, recordType := range []string{"A", "AAAA"} {
+				var found []Record
+				if found, err = u.ListRecords(ctx, zone.Name, name, recordType); err != nil {
+					break query
+				}
+				recs = append(recs, found...)
+			}
+		}
+	}
+	if err != nil {
+		zone.Error = err.Error()
+		if ErrorClass(err) == ErrorClassAuth && !slices.Contains(zone.Missing, PermissionDNSRead) {
+			zone.Missing = append(zone.Missing, PermissionDNSRead)
+		}
+		return
+	}
+
+	for _, r := range recs {
+		if r.Type != "A" && r.Type != "AAAA" {
+			continue
+		}
+		zone.Records = append(zone.Records, DiscoveredRecord{
+			Name:    r.Name,
+			Record:  RelativeName(r.Name, zone.Name),
+			Type:    r.Type,
+			Content: r.Content,
+			TTL:     r.TTL,
+			Proxied: r.Proxied,
+		})
+	}
+	slices.SortFunc(zone.Records, func(a, b DiscoveredRecord) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Type, b.Type)
+	})
+}
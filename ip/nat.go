@@ -0,0 +1,236 @@
+package ip
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pion/stun"
+)
+
+// NAT 映射/过滤行为（RFC 4787 / RFC 5780 术语）
+const (
+	NATBehaviorNoNAT                   = "no-nat"
+	NATBehaviorEndpointIndependent     = "endpoint-independent"
+	NATBehaviorAddressDependent        = "address-dependent"
+	NATBehaviorAddressAndPortDependent = "address-and-port-dependent"
+	NATBehaviorUnknown                 = "unknown"
+)
+
+// CHANGE-REQUEST 标志位（RFC 5780 §7.2）
+const (
+	changeIPFlag   = 0x04
+	changePortFlag = 0x02
+)
+
+// sharedAddressSpace RFC 6598 运营商级 NAT 共享地址段
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsSharedAddress 判断地址是否位于 100.64.0.0/10（CGNAT 共享地址段）
+func IsSharedAddress(addr string) bool {
+	ip := net.ParseIP(addr)
+	return ip != nil && sharedAddressSpace.Contains(ip)
+}
+
+// NATBehavior RFC 5780 NAT 行为探测结果
+type NATBehavior struct {
+	Server            string    `json:"server"`
+	LocalAddr         string    `json:"local_addr"`
+	MappedAddr        string    `json:"mapped_addr"`
+	OtherAddr         string    `json:"other_addr,omitempty"`
+	MappingBehavior   string    `json:"mapping_behavior"`
+	FilteringBehavior string    `json:"filtering_behavior"`
+	Symmetric         bool      `json:"symmetric"` // 映射依赖目的地址，即常说的对称型 NAT
+	CGNAT             bool      `json:"cgnat"`     // 本机出口地址位于 100.64.0.0/10
+	Error             string    `json:"error,omitempty"`
+	CheckedAt         time.Time `json:"checked_at"`
+}
+
+// natTester 在同一个本地 UDP 端口上向不同目的地址发送 Binding 请求
+type natTester struct {
+	conn    *net.UDPConn
+	timeout time.Duration
+}
+
+// stunErrorResponse 服务器返回的错误响应（如不支持 CHANGE-REQUEST 时的 420 Unknown Attribute）
+type stunErrorResponse struct {
+	code stun.ErrorCodeAttribute
+}
+
+func (e *stunErrorResponse) Error() string {
+	return fmt.Sprintf("STUN 错误响应 %d %s", e.code.Code, e.code.Reason)
+}
+
+// isUnsupported 判断错误是否为服务器的错误响应（服务器不支持请求的功能）
+func isUnsupported(err error) bool {
+	var res *stunErrorResponse
+	return errors.As(err, &res)
+}
+
+// request 发送请求并等待匹配事务 ID 的成功响应；丢包时重传一次，错误响应返回 *stunErrorResponse
+func (t *natTester) request(dst *net.UDPAddr, changeFlags byte) (*stun.Message, error) {
+	setters := []stun.Setter{stun.TransactionID, stun.BindingRequest}
+	if changeFlags != 0 {
+		setters = append(setters, stun.RawAttribute{Type: stun.AttrChangeRequest, Value: []byte{0, 0, 0, changeFlags}})
+	}
+	req, err := stun.Build(append(setters, stun.Fingerprint)...)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1500)
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		if _, err := t.conn.WriteToUDP(req.Raw, dst); err != nil {
+			return nil, err
+		}
+		t.conn.SetReadDeadline(time.Now().Add(t.timeout / 2))
+		for {
+			n, _, err := t.conn.ReadFromUDP(buf)
+			if err != nil {
+				lastErr = err
+				break
+			}
+			res := &stun.Message{Raw: append([]byte(nil), buf[:n]...)}
+			if res.Decode() != nil || res.TransactionID != req.TransactionID {
+				continue // 忽略迟到的旧响应
+			}
+			if res.Type.Class != stun.ClassSuccessResponse {
+				errRes := &stunErrorResponse{}
+				errRes.code.GetFrom(res)
+				return nil, errRes
+			}
+			return res, nil
+		}
+	}
+	return nil, lastErr
+}
+
+// DiscoverNATBehavior 使用支持 RFC 5780 的 STUN 服务器探测 NAT 映射与过滤行为
+func DiscoverNATBehavior(server string, timeout time.Duration) *NATBehavior {
+	result := &NATBehavior{
+		Server:            server,
+		MappingBehavior:   NATBehaviorUnknown,
+		FilteringBehavior: NATBehaviorUnknown,
+		CheckedAt:         time.Now(),
+	}
+
+	primary, err := net.ResolveUDPAddr("udp4", server)
+	if err != nil {
+		result.Error = fmt.Sprintf("解析 STUN 服务器失败: %v", err)
+		return result
+	}
+
+	// 通过一次“连接”获取路由选择的本机出口地址
+	probe, err := net.DialUDP("udp4", nil, primary)
+	if err != nil {
+		result.Error = fmt.Sprintf("获取本机地址失败: %v", err)
+		return result
+	}
+	localIP := probe.LocalAddr().(*net.UDPAddr).IP
+	probe.Close()
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: localIP})
+	if err != nil {
+		result.Error = fmt.Sprintf("监听本地端口失败: %v", err)
+		return result
+	}
+	defer conn.Close()
+	local := conn.LocalAddr().(*net.UDPAddr)
+	result.LocalAddr = local.String()
+	result.CGNAT = sharedAddressSpace.Contains(local.IP)
+
+	t := &natTester{conn: conn, timeout: timeout}
+
+	// 测试 I：向主地址发送普通 Binding 请求
+	res, err := t.request(primary, 0)
+	if err != nil {
+		result.Error = fmt.Sprintf("测试 I 无响应: %v", err)
+		return result
+	}
+	mapped1, err := mappedAddress(res)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.MappedAddr = mapped1.String()
+	noNAT := mapped1.IP.Equal(local.IP) && mapped1.Port == local.Port
+	if noNAT {
+		result.MappingBehavior = NATBehaviorNoNAT
+	}
+
+	var other stun.MappedAddress
+	if err := other.GetFromAs(res, stun.AttrOtherAddress); err != nil {
+		result.Error = "服务器不支持 RFC 5780（响应中无 OTHER-ADDRESS）"
+		return result
+	}
+	otherAddr := &net.UDPAddr{IP: other.IP, Port: other.Port}
+	result.OtherAddr = otherAddr.String()
+
+	// 映射行为
+	if !noNAT {
+		// 测试 II：向备用 IP + 主端口发送
+		res, err = t.request(&net.UDPAddr{IP: otherAddr.IP, Port: primary.Port}, 0)
+		if err != nil {
+			result.Error = fmt.Sprintf("映射测试 II 无响应: %v", err)
+		} else if mapped2, err := mappedAddress(res); err == nil {
+			if mapped2.String() == mapped1.String() {
+				result.MappingBehavior = NATBehaviorEndpointIndependent
+			} else {
+				// 测试 III：向备用 IP + 备用端口发送
+				res, err = t.request(otherAddr, 0)
+				if err != nil {
+					result.Error = fmt.Sprintf("映射测试 III 无响应: %v", err)
+				} else if mapped3, err := mappedAddress(res); err == nil {
+					if mapped3.String() == mapped2.String() {
+						result.MappingBehavior = NATBehaviorAddressDependent
+					} else {
+						result.MappingBehavior = NATBehaviorAddressAndPortDependent
+					}
+				}
+			}
+		}
+	}
+	result.Symmetric = result.MappingBehavior == NATBehaviorAddressDependent ||
+		result.MappingBehavior == NATBehaviorAddressAndPortDependent
+
+	// 过滤行为：只有超时（NAT 丢弃了来自其他地址的响应）才说明过滤，错误响应表示服务器不支持 CHANGE-REQUEST
+	// 测试 II：请求服务器从备用 IP 和端口回复
+	_, err = t.request(primary, changeIPFlag|changePortFlag)
+	if err == nil {
+		result.FilteringBehavior = NATBehaviorEndpointIndependent
+	} else if !isUnsupported(err) {
+		// 测试 III：仅变更端口
+		_, err = t.request(primary, changePortFlag)
+		if err == nil {
+			result.FilteringBehavior = NATBehaviorAddressDependent
+		} else if !isUnsupported(err) {
+			result.FilteringBehavior = NATBehaviorAddressAndPortDependent
+		}
+	}
+	if isUnsupported(err) {
+		msg := fmt.Sprintf("服务器不支持 CHANGE-REQUEST，无法探测过滤行为（%v）", err)
+		if result.Error != "" {
+			msg = result.Error + "; " + msg
+		}
+		result.Error = msg
+	}
+
+	return result
+}
+
+// DiscoverNAT 使用首个启用了 nat_discovery 的 STUN 提供者执行 NAT 行为探测
+// 未配置时返回 nil, false
+func (d *DynamicProvider) DiscoverNAT() (*NATBehavior, bool) {
+	cfg := d.Config.Get()
+	for _, pCfg := range cfg.IPProviders {
+		if !pCfg.Enabled || pCfg.Type != "stun" {
+			continue
+		}
+		if server, timeout, ok := natDiscoveryTarget(pCfg.Properties); ok {
+			return DiscoverNATBehavior(server, timeout), true
+		}
+	}
+	return nil, false
+}
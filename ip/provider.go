@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"idrd/config"
 	"log"
+	"sync"
 )
 
 // Provider 定义获取公网 IP 的接口
@@ -12,9 +13,27 @@ type Provider interface {
 	GetIPv6() (string, string, error) // 新增 IPv6 支持
 }
 
+// MultiProvider 可以同时获取多个出口（如多 WAN）IP 的提供者
+type MultiProvider interface {
+	GetAllIPs() ([]string, error)
+}
+
+// maxMissedChecks 提供者连续获取失败达到该次数后，其上次的地址才从地址组中移除
+// 避免单次检查失败导致 rrset 模式删除仍在使用的线路记录
+const maxMissedChecks = 3
+
 // DynamicProvider 根据配置动态选择 IP 提供者
 type DynamicProvider struct {
 	Config *config.SafeConfig
+
+	lastMu  sync.Mutex
+	lastIPs map[string]*lastIP // 提供者（类型和属性）-> 最近一次获取到的地址，GetAllIPs 使用
+}
+
+// lastIP 提供者最近一次获取到的地址及之后连续失败的次数
+type lastIP struct {
+	ip     string
+	missed int
 }
 
 // GetIP 根据配置获取 IPv4
@@ -29,37 +48,14 @@ func (d *DynamicProvider) GetIP() (string, string, error) {
 			continue
 		}
 		
-		var p Provider
-		
-		switch pCfg.Type {
-		case "stun":
-			server := pCfg.Properties["server"]
-			if server == "" {
-				server = "stun.l.google.com:19302"
-			}
-			p = &STUNProvider{Server: server}
-		case "router_ssh":
-			// 解析端口
-			port := 22
-			if pCfg.Properties["port"] != "" {
-				fmt.Sscanf(pCfg.Properties["port"], "%d", &port)
-			}
-			p = &RouterProvider{
-				Type:      pCfg.Properties["type"],
-				Host:      pCfg.Properties["host"],
-				Port:      port,
-				User:      pCfg.Properties["user"],
-				Password:  pCfg.Properties["password"],
-				Key:       pCfg.Properties["key"],
-				KeyPath:   pCfg.Properties["key_path"],
-				Interface: pCfg.Properties["interface"],
-				HostKey:   pCfg.Properties["host_key"],
-			}
-		// TODO: 添加 http 和 interface 支持
-		default:
+		p, err := NewProvider(pCfg)
+		if err != nil {
+			errMsg := fmt.Sprintf("[%s] 初始化失败: %v", pCfg.Type, err)
+			log.Printf("⚠️  %s", errMsg)
+			errors = append(errors, errMsg)
 			continue
 		}
-		
+
 		if p != nil {
 			log.Printf("🔍 尝试使用 IP 提供者 [%s] 获取 IP...", pCfg.Type)
 			ip, source, err := p.GetIP()
@@ -85,6 +81,78 @@ func (d *DynamicProvider) GetIP() (string, string, error) {
 	return "", "", fmt.Errorf("没有启用的 IP 提供者")
 }
 
+// GetAllIPs 返回所有启用的提供者各自获取到的 IPv4（去重，按配置顺序）
+// GetIP 只使用第一个成功的提供者；多 WAN 时每条线路配置一个提供者，rrset 模式的记录发布全部地址
+// 获取失败的提供者在连续失败 maxMissedChecks 次之前沿用上次的地址
+func (d *DynamicProvider) GetAllIPs() ([]string, error) {
+	cfg := d.Config.Get()
+
+	// 先在锁外获取各提供者的地址（网络请求可能较慢），再在锁内更新 lastIPs
+	type fetched struct {
+		key, providerType, ip string
+		err                   error
+	}
+	var results []fetched
+	current := make(map[string]bool)
+	for _, pCfg := range cfg.IPProviders {
+		if !pCfg.Enabled {
+			continue
+		}
+		key := pCfg.Type + "|" + fmt.Sprint(pCfg.Properties) // fmt 按键排序输出 map
+		current[key] = true
+		p, err := NewProvider(pCfg)
+		if err != nil || p == nil {
+			continue
+		}
+		ip, _, err := p.GetIP()
+		results = append(results, fetched{key: key, providerType: pCfg.Type, ip: ip, err: err})
+	}
+
+	d.lastMu.Lock()
+	defer d.lastMu.Unlock()
+	if d.lastIPs == nil {
+		d.lastIPs = make(map[string]*lastIP)
+	}
+
+	var ips []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		ip := r.ip
+		if r.err != nil || ip == "" {
+			last := d.lastIPs[r.key]
+			if last == nil {
+				log.Printf("⚠️  [%s] 获取 IP 失败，不计入地址组: %v", r.providerType, r.err)
+				continue
+			}
+			if last.missed++; last.missed >= maxMissedChecks {
+				log.Printf("⚠️  [%s] 连续 %d 次获取 IP 失败，从地址组移除 %s: %v", r.providerType, last.missed, last.ip, r.err)
+				delete(d.lastIPs, r.key)
+				continue
+			}
+			log.Printf("⚠️  [%s] 获取 IP 失败，暂时保留上次的地址 %s (%d/%d): %v", r.providerType, last.ip, last.missed, maxMissedChecks, r.err)
+			ip = last.ip
+		} else {
+			d.lastIPs[r.key] = &lastIP{ip: ip}
+		}
+		if !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+
+	// 已删除或停用的提供者不再保留地址
+	for key := range d.lastIPs {
+		if !current[key] {
+			delete(d.lastIPs, key)
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("没有提供者获取到 IP")
+	}
+	return ips, nil
+}
+
 // GetIPv6 根据配置获取 IPv6
 func (d *DynamicProvider) GetIPv6() (string, string, error) {
 	// 暂时未实现，返回空
@@ -0,0 +1,92 @@
+package ip
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PushedIP 设备通过 dyndns2 推送的 IP
+type PushedIP struct {
+	IP     string    `json:"ip"`
+	Device string    `json:"device"`
+	Time   time.Time `json:"time"`
+}
+
+// pushStore 保存每个设备最近一次推送的 IP（仅内存，重启后等待设备重新推送）
+var pushStore = struct {
+	mu     sync.RWMutex
+	latest map[string]PushedIP // 设备名 -> 最近推送
+}{latest: map[string]PushedIP{}}
+
+// RecordPush 记录设备推送的 IP
+func RecordPush(device, ip string) {
+	pushStore.mu.Lock()
+	defer pushStore.mu.Unlock()
+	pushStore.latest[device] = PushedIP{IP: ip, Device: device, Time: time.Now()}
+}
+
+// LatestPush 返回指定设备最近一次推送；device 为空时返回所有设备中最新的一次
+func LatestPush(device string) (PushedIP, bool) {
+	pushStore.mu.RLock()
+	defer pushStore.mu.RUnlock()
+	if device != "" {
+		p, ok := pushStore.latest[device]
+		return p, ok
+	}
+	var latest PushedIP
+	for _, p := range pushStore.latest {
+		if p.Time.After(latest.Time) {
+			latest = p
+		}
+	}
+	return latest, !latest.Time.IsZero()
+}
+
+// PushProvider 使用路由器等设备通过 /nic/update 推送的 IP
+type PushProvider struct {
+	Device string        // 为空时使用任意设备的最新推送
+	MaxAge time.Duration // 推送超过该时长视为过期，0 表示不过期
+}
+
+func init() {
+	Register(ProviderType{
+		Type:        "dyndns_push",
+		Name:        "DynDNS Push",
+		Description: "使用路由器通过 dyndns2 协议推送到 /nic/update 的 IP（设备在 DynDNS Server 中配置）",
+		Properties: []PropertySchema{
+			{Name: "device", Label: "Device", Type: "string", Help: "只接受该设备的推送，留空则使用任意设备的最新推送"},
+			{Name: "max_age", Label: "Max Age", Type: "string", Default: "1h", Help: "超过该时长未收到推送则视为失败，0 表示不过期"},
+		},
+		New: func(props map[string]string) (Provider, error) {
+			maxAge, err := time.ParseDuration(props["max_age"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid max_age %s: %w", props["max_age"], err)
+			}
+			return &PushProvider{Device: props["device"], MaxAge: maxAge}, nil
+		},
+		Validate: func(props map[string]string) error {
+			if d, err := time.ParseDuration(props["max_age"]); err != nil || d < 0 {
+				return fmt.Errorf("invalid max_age %s", props["max_age"])
+			}
+			return nil
+		},
+	})
+}
+
+// GetIP 返回最近一次推送的 IP
+func (p *PushProvider) GetIP() (string, string, error) {
+	pushed, ok := LatestPush(p.Device)
+	if !ok {
+		return "", "", fmt.Errorf("尚未收到设备推送")
+	}
+	if p.MaxAge > 0 && time.Since(pushed.Time) > p.MaxAge {
+		return "", "", fmt.Errorf("设备 %s 的推送已过期 (%s 前)", pushed.Device, time.Since(pushed.Time).Round(time.Second))
+	}
+	return pushed.IP, "dyndns_push:" + pushed.Device, nil
+}
+
+// GetIPv6 推送的 IPv6 暂不作为 IPv6 来源
+func (p *PushProvider) GetIPv6() (string, string, error) {
+	return "", "", nil
+}
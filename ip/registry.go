@@ -0,0 +1,130 @@
+package ip
+
+import (
+	"fmt"
+	"idrd/config"
+	"sort"
+	"strconv"
+)
+
+// PropertySchema 描述提供者的单个配置属性，供前端动态渲染表单
+type PropertySchema struct {
+	Name     string   `json:"name"`              // 属性键名（对应 Properties 中的 key）
+	Label    string   `json:"label"`             // 展示名称
+	Type     string   `json:"type"`              // string, number, bool, select, text
+	Required bool     `json:"required"`          // 是否必填
+	Secret   bool     `json:"secret"`            // 是否为敏感信息（密码、私钥等）
+	Default  string   `json:"default,omitempty"` // 默认值
+	Options  []string `json:"options,omitempty"` // select 类型的可选值
+	Help     string   `json:"help,omitempty"`    // 帮助说明
+}
+
+// ProviderType 描述一种 IP 提供者类型：构造函数、校验函数和属性定义
+type ProviderType struct {
+	Type        string           `json:"type"`
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Properties  []PropertySchema `json:"properties"`
+
+	// New 根据属性构造提供者（属性已填充默认值）
+	New func(props map[string]string) (Provider, error) `json:"-"`
+	// Validate 校验属性（可选，必填项和 select 取值由通用逻辑校验）
+	Validate func(props map[string]string) error `json:"-"`
+}
+
+var registry = map[string]*ProviderType{}
+
+// Register 注册 IP 提供者类型，通常在各提供者文件的 init 中调用
+// 同时将校验函数注册到 config 包，使配置校验无需硬编码类型
+func Register(t ProviderType) {
+	if t.Type == "" || t.New == nil {
+		panic("ip: Register 需要 Type 和 New")
+	}
+	if _, exists := registry[t.Type]; exists {
+		panic(fmt.Sprintf("ip: 提供者类型 %s 重复注册", t.Type))
+	}
+	pt := t
+	registry[t.Type] = &pt
+	config.RegisterIPProviderValidator(t.Type, pt.validate)
+}
+
+// Lookup 查找已注册的提供者类型
+func Lookup(providerType string) (*ProviderType, bool) {
+	t, ok := registry[providerType]
+	return t, ok
+}
+
+// Types 返回所有已注册的提供者类型（按类型名排序）
+func Types() []ProviderType {
+	types := make([]ProviderType, 0, len(registry))
+	for _, t := range registry {
+		types = append(types, *t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].Type < types[j].Type })
+	return types
+}
+
+// NewProvider 根据配置构造提供者实例
+func NewProvider(pCfg config.IPProviderConfig) (Provider, error) {
+	t, ok := Lookup(pCfg.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown provider type: %s", pCfg.Type)
+	}
+	props := make(map[string]string, len(pCfg.Properties))
+	for k, v := range pCfg.Properties {
+		props[k] = v
+	}
+	t.applyDefaults(props)
+	return t.New(props)
+}
+
+// applyDefaults 为空属性填充默认值
+func (t *ProviderType) applyDefaults(props map[string]string) {
+	for _, p := range t.Properties {
+		if p.Default != "" && props[p.Name] == "" {
+			props[p.Name] = p.Default
+		}
+	}
+}
+
+// validate 通用校验：填充默认值、检查必填项和取值类型，再调用类型自身的校验函数
+func (t *ProviderType) validate(props map[string]string) error {
+	t.applyDefaults(props)
+
+	for _, p := range t.Properties {
+		value := props[p.Name]
+		if value == "" {
+			if p.Required {
+				return fmt.Errorf("%s required", p.Name)
+			}
+			continue
+		}
+
+		switch p.Type {
+		case "number":
+			if _, err := strconv.Atoi(value); err != nil {
+				return fmt.Errorf("invalid %s %s: must be a number", p.Name, value)
+			}
+		case "bool":
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("invalid %s %s: must be true or false", p.Name, value)
+			}
+		case "select":
+			valid := false
+			for _, opt := range p.Options {
+				if value == opt {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return fmt.Errorf("unsupported %s: %s (must be one of %v)", p.Name, value, p.Options)
+			}
+		}
+	}
+
+	if t.Validate != nil {
+		return t.Validate(props)
+	}
+	return nil
+}
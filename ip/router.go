@@ -9,6 +9,7 @@ import (
 	"log"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,6 +28,85 @@ type RouterProvider struct {
 	Interface       string
 	HostKey         string // 预期的主机公钥 (base64 编码)
 	StrictHostCheck bool   // 是否严格检查主机密钥
+
+	trace func(format string, args ...any) // 诊断跟踪（测试提供者时记录认证过程）
+}
+
+// SetTrace 实现 Tracer 接口
+func (r *RouterProvider) SetTrace(fn func(format string, args ...any)) {
+	r.trace = fn
+}
+
+// logf 输出日志，并在设置了跟踪函数时同步记录
+func (r *RouterProvider) logf(format string, args ...any) {
+	log.Printf(format, args...)
+	if r.trace != nil {
+		r.trace(format, args...)
+	}
+}
+
+func init() {
+	Register(ProviderType{
+		Type:        "router_ssh",
+		Name:        "Router SSH",
+		Description: "通过 SSH 登录路由器读取 WAN 接口地址",
+		Properties: []PropertySchema{
+			{Name: "type", Label: "Router Type", Type: "select", Required: true, Default: "routeros", Options: []string{"routeros", "openwrt"}, Help: "路由器系统类型"},
+			{Name: "host", Label: "Host", Type: "string", Required: true, Help: "路由器地址 (IP 或域名)"},
+			{Name: "port", Label: "Port", Type: "number", Default: "22"},
+			{Name: "user", Label: "User", Type: "string", Required: true, Default: "admin"},
+			{Name: "interface", Label: "Interface", Type: "string", Required: true, Default: "wan", Help: "WAN 接口名称 (OpenWrt 如 wan，RouterOS 如 ether1)"},
+			{Name: "password", Label: "Password", Type: "string", Secret: true, Help: "密码认证（与私钥二选一）"},
+			{Name: "key", Label: "SSH Private Key", Type: "text", Secret: true, Help: "私钥内容，支持 OpenSSH/PEM/PKCS#8 格式"},
+			{Name: "key_path", Label: "Key Path", Type: "string", Help: "私钥文件路径（容器内路径）"},
+			{Name: "host_key", Label: "Host Key", Type: "string", Help: "预期的主机公钥 (base64)，留空则不校验"},
+		},
+		New: func(props map[string]string) (Provider, error) {
+			port, err := strconv.Atoi(props["port"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid port %s: %w", props["port"], err)
+			}
+			return &RouterProvider{
+				Type:      props["type"],
+				Host:      props["host"],
+				Port:      port,
+				User:      props["user"],
+				Password:  props["password"],
+				Key:       props["key"],
+				KeyPath:   props["key_path"],
+				Interface: props["interface"],
+				HostKey:   props["host_key"],
+			}, nil
+		},
+		Validate: validateRouterProperties,
+	})
+}
+
+// validateRouterProperties 校验 router_ssh 提供者属性（必填项与类型已由通用逻辑校验）
+func validateRouterProperties(props map[string]string) error {
+	// 只验证格式，不做 DNS 查询（网络可能未就绪）
+	// 接受 IP 地址或域名格式
+	host := props["host"]
+	if net.ParseIP(host) == nil {
+		// 不是 IP，验证是否是合理的域名格式（包含字母或点）
+		if !strings.Contains(host, ".") && !strings.ContainsAny(host, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
+			return fmt.Errorf("invalid router host %s (must be IP or domain)", host)
+		}
+	}
+
+	// 验证端口
+	if portNum, _ := strconv.Atoi(props["port"]); portNum < 1 || portNum > 65535 {
+		return fmt.Errorf("invalid port %d (must be 1-65535)", portNum)
+	}
+
+	// 验证认证方式：必须有密码或密钥之一
+	hasPassword := props["password"] != ""
+	hasKey := props["key"] != "" || props["key_path"] != ""
+	if !hasPassword && !hasKey {
+		return fmt.Errorf("router authentication required (password or key)")
+	}
+
+	return nil
 }
 
 // GetIPv6 获取 IPv6 地址（暂未实现）
@@ -41,11 +121,13 @@ func (r *RouterProvider) GetIP() (string, string, error) {
 
 	// 连接 SSH
 	addr := fmt.Sprintf("%s:%d", r.Host, r.Port)
+	r.logf("🔌 SSH: 连接 %s (user=%s)", addr, config.User)
 	client, err := ssh.Dial("tcp", addr, config)
 	if err != nil {
 		return "", "", fmt.Errorf("SSH 连接失败 %s: %w", addr, err)
 	}
 	defer client.Close()
+	r.logf("✅ SSH: 认证成功，服务端版本 %s", client.ServerVersion())
 
 	// 创建会话
 	session, err := client.NewSession()
@@ -68,10 +150,12 @@ func (r *RouterProvider) GetIP() (string, string, error) {
 	}
 
 	// 执行命令
+	r.logf("▶️  SSH: 执行命令 %s", cmd)
 	output, err := session.CombinedOutput(cmd)
 	if err != nil {
 		return "", "", fmt.Errorf("执行命令失败: %w, 输出: %s", err, string(output))
 	}
+	r.logf("📄 SSH: 命令输出 %q", strings.TrimSpace(string(output)))
 
 	// 解析输出
 	ip, err := r.parseIP(string(output))
@@ -91,10 +175,10 @@ func (r *RouterProvider) getAuthMethods() []ssh.AuthMethod {
 		cleanKey := cleanPEMKey(r.Key)
 		signer, err := parsePrivateKey([]byte(cleanKey))
 		if err == nil {
-			auth = append(auth, ssh.PublicKeys(signer))
-			log.Printf("🔑 SSH: 添加公钥认证方法")
+			auth = append(auth, r.publicKeysMethod(signer, "配置的私钥"))
+			r.logf("🔑 SSH: 添加公钥认证方法 (%s)", signer.PublicKey().Type())
 		} else {
-			log.Printf("⚠️ SSH 私钥解析失败: %v. 请检查格式是否正确 (支持 OpenSSH/PEM/PKCS#8 格式的 RSA/ECDSA/ed25519 密钥)", err)
+			r.logf("⚠️ SSH 私钥解析失败: %v. 请检查格式是否正确 (支持 OpenSSH/PEM/PKCS#8 格式的 RSA/ECDSA/ed25519 密钥)", err)
 		}
 	}
 
@@ -104,34 +188,49 @@ func (r *RouterProvider) getAuthMethods() []ssh.AuthMethod {
 		if err == nil {
 			signer, err := parsePrivateKey(key)
 			if err == nil {
-				auth = append(auth, ssh.PublicKeys(signer))
-				log.Printf("🔑 SSH: 添加公钥认证方法 (从文件: %s)", r.KeyPath)
+				auth = append(auth, r.publicKeysMethod(signer, r.KeyPath))
+				r.logf("🔑 SSH: 添加公钥认证方法 (从文件: %s)", r.KeyPath)
+			} else {
+				r.logf("⚠️ SSH 私钥文件解析失败 (%s): %v", r.KeyPath, err)
 			}
+		} else {
+			r.logf("⚠️ SSH 私钥文件读取失败: %v", err)
 		}
 	}
 
 	// 方式 3：密码认证
 	if r.Password != "" {
 		// 优先尝试标准 password 认证 (RFC 4252)
-		auth = append(auth, ssh.Password(r.Password))
-		log.Printf("🔑 SSH: 添加 password 认证方法")
+		auth = append(auth, ssh.PasswordCallback(func() (string, error) {
+			r.logf("🔐 SSH: 尝试 password 认证")
+			return r.Password, nil
+		}))
+		r.logf("🔑 SSH: 添加 password 认证方法")
 
 		// 其次尝试 keyboard-interactive (RFC 4256)，作为兼容备选
 		auth = append(auth, ssh.KeyboardInteractive(func(user, instruction string, questions []string, echos []bool) ([]string, error) {
-			log.Printf("🔑 SSH keyboard-interactive: user=%s, questions=%d", user, len(questions))
+			r.logf("🔑 SSH keyboard-interactive: user=%s, questions=%d", user, len(questions))
 			answers := make([]string, len(questions))
 			for i := range questions {
 				answers[i] = r.Password
 			}
 			return answers, nil
 		}))
-		log.Printf("🔑 SSH: 添加 keyboard-interactive 认证方法")
+		r.logf("🔑 SSH: 添加 keyboard-interactive 认证方法")
 	}
 
-	log.Printf("🔑 SSH: 共配置 %d 种认证方法", len(auth))
+	r.logf("🔑 SSH: 共配置 %d 种认证方法", len(auth))
 	return auth
 }
 
+// publicKeysMethod 返回公钥认证方法，被服务端调用时记录尝试
+func (r *RouterProvider) publicKeysMethod(signer ssh.Signer, from string) ssh.AuthMethod {
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		r.logf("🔐 SSH: 尝试 publickey 认证 (%s, %s)", from, signer.PublicKey().Type())
+		return []ssh.Signer{signer}, nil
+	})
+}
+
 // getSSHConfig 构建兼容性更好的 SSH 配置
 func (r *RouterProvider) getSSHConfig() *ssh.ClientConfig {
 	config := &ssh.ClientConfig{
@@ -166,13 +265,13 @@ func (r *RouterProvider) getHostKeyCallback() ssh.HostKeyCallback {
 	if r.HostKey != "" {
 		expectedKeyBytes, err := base64.StdEncoding.DecodeString(r.HostKey)
 		if err != nil {
-			log.Printf("⚠️ 主机密钥格式错误: %v，将使用不安全模式（接受任何主机密钥）", err)
+			r.logf("⚠️ 主机密钥格式错误: %v，将使用不安全模式（接受任何主机密钥）", err)
 			return ssh.InsecureIgnoreHostKey()
 		}
 
 		expectedKey, err := ssh.ParsePublicKey(expectedKeyBytes)
 		if err != nil {
-			log.Printf("⚠️ 主机密钥解析失败: %v，将使用不安全模式（接受任何主机密钥）", err)
+			r.logf("⚠️ 主机密钥解析失败: %v，将使用不安全模式（接受任何主机密钥）", err)
 			return ssh.InsecureIgnoreHostKey()
 		}
 
@@ -1,59 +1,243 @@
 package ip
 
 import (
+	"crypto/tls"
+	"encoding/binary"
 	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pion/stun"
 )
 
+// STUN 传输方式的默认端口（RFC 5389 §7.2, §9）
+const (
+	defaultSTUNPort    = "3478"
+	defaultSTUNTLSPort = "5349"
+
+	stunHeaderSize = 20 // STUN 消息头长度（类型 2 + 长度 2 + magic cookie 4 + 事务 ID 12）
+)
+
 // STUNProvider 通过 STUN 服务器获取公网 IP
+// 支持多个服务器按顺序回退或并行查询，以及 UDP/TCP/TLS 三种传输方式
 type STUNProvider struct {
-	Server string
+	Servers   []string      // host:port 列表
+	Transport string        // udp, tcp, tls
+	Mode      string        // fallback（依次尝试）或 parallel（并行，取最先成功的结果）
+	Timeout   time.Duration // 单个服务器的请求超时
+}
+
+func init() {
+	Register(ProviderType{
+		Type:        "stun",
+		Name:        "STUN Server",
+		Description: "通过 STUN Binding 请求获取 NAT 映射后的公网 IP",
+		Properties: []PropertySchema{
+			{Name: "server", Label: "STUN Server", Type: "string", Required: true, Default: "stun.l.google.com:19302", Help: "STUN 服务器地址 (host:port)，多个服务器用逗号分隔"},
+			{Name: "transport", Label: "Transport", Type: "select", Default: "udp", Options: []string{"udp", "tcp", "tls"}, Help: "UDP 受限的网络可使用 TCP 或 TLS (RFC 5389 §7.2)"},
+			{Name: "mode", Label: "Mode", Type: "select", Default: "fallback", Options: []string{"fallback", "parallel"}, Help: "fallback 依次尝试，parallel 同时查询并取最快结果"},
+			{Name: "timeout", Label: "Timeout", Type: "string", Default: "3s", Help: "单个服务器的请求超时"},
+			{Name: "nat_discovery", Label: "NAT Discovery", Type: "bool", Default: "false", Help: "使用首个服务器执行 RFC 5780 NAT 行为探测（需服务器支持 OTHER-ADDRESS）"},
+		},
+		New: func(props map[string]string) (Provider, error) {
+			timeout, err := time.ParseDuration(props["timeout"])
+			if err != nil {
+				return nil, fmt.Errorf("invalid timeout %s: %w", props["timeout"], err)
+			}
+			return &STUNProvider{
+				Servers:   splitServers(props["server"], props["transport"]),
+				Transport: props["transport"],
+				Mode:      props["mode"],
+				Timeout:   timeout,
+			}, nil
+		},
+		Validate: func(props map[string]string) error {
+			for _, server := range splitServers(props["server"], props["transport"]) {
+				// 验证 server 格式 (host:port)
+				if _, _, err := net.SplitHostPort(server); err != nil {
+					return fmt.Errorf("invalid STUN server address %s: %w", server, err)
+				}
+			}
+			if d, err := time.ParseDuration(props["timeout"]); err != nil || d <= 0 {
+				return fmt.Errorf("invalid timeout %s", props["timeout"])
+			}
+			return nil
+		},
+	})
+}
+
+// splitServers 解析逗号分隔的服务器列表，未指定端口时按传输方式补全默认端口
+func splitServers(raw, transport string) []string {
+	var servers []string
+	for _, s := range strings.Split(raw, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(s); err != nil && !strings.Contains(s, ":") {
+			port := defaultSTUNPort
+			if transport == "tls" {
+				port = defaultSTUNTLSPort
+			}
+			s = net.JoinHostPort(s, port)
+		}
+		servers = append(servers, s)
+	}
+	return servers
 }
 
 // GetIP 从 STUN 服务器获取公网 IP
 func (s *STUNProvider) GetIP() (string, string, error) {
-	// 创建 STUN 客户端
-	// 注意：stun.Dial 默认使用 UDP
-	c, err := stun.Dial("udp", s.Server)
+	if len(s.Servers) == 0 {
+		return "", "", fmt.Errorf("未配置 STUN 服务器")
+	}
+	if s.Mode == "parallel" && len(s.Servers) > 1 {
+		return s.getIPParallel()
+	}
+
+	var errs []string
+	for _, server := range s.Servers {
+		ip, err := s.query(server)
+		if err == nil {
+			return ip, "STUN", nil
+		}
+		log.Printf("⚠️  STUN 服务器 %s 查询失败: %v", server, err)
+		errs = append(errs, fmt.Sprintf("%s: %v", server, err))
+	}
+	return "", "", fmt.Errorf("所有 STUN 服务器均查询失败: %s", strings.Join(errs, "; "))
+}
+
+// getIPParallel 并行查询所有服务器，返回最先成功的结果
+func (s *STUNProvider) getIPParallel() (string, string, error) {
+	type result struct {
+		server, ip string
+		err        error
+	}
+	results := make(chan result, len(s.Servers))
+	for _, server := range s.Servers {
+		go func(server string) {
+			ip, err := s.query(server)
+			results <- result{server, ip, err}
+		}(server)
+	}
+
+	var errs []string
+	for range s.Servers {
+		r := <-results
+		if r.err == nil {
+			return r.ip, "STUN", nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", r.server, r.err))
+	}
+	return "", "", fmt.Errorf("所有 STUN 服务器均查询失败: %s", strings.Join(errs, "; "))
+}
+
+// query 向单个服务器发送 Binding 请求
+func (s *STUNProvider) query(server string) (string, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+
+	conn, err := dialSTUN(s.Transport, server, timeout)
+	if err != nil {
+		return "", fmt.Errorf("连接 STUN 服务器失败: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	res, err := stunRoundTrip(conn, s.Transport != "udp" && s.Transport != "")
+	if err != nil {
+		return "", fmt.Errorf("STUN 请求失败: %w", err)
+	}
+
+	mapped, err := mappedAddress(res)
 	if err != nil {
-		return "", "", fmt.Errorf("连接 STUN 服务器失败: %w", err)
+		return "", fmt.Errorf("STUN 响应处理失败: %w", err)
+	}
+	return mapped.IP.String(), nil
+}
+
+// dialSTUN 按传输方式建立到服务器的连接
+func dialSTUN(transport, server string, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	switch transport {
+	case "", "udp":
+		return dialer.Dial("udp", server)
+	case "tcp":
+		return dialer.Dial("tcp", server)
+	case "tls":
+		host, _, _ := net.SplitHostPort(server)
+		return tls.DialWithDialer(dialer, "tcp", server, &tls.Config{ServerName: host})
+	default:
+		return nil, fmt.Errorf("不支持的传输方式: %s", transport)
 	}
-	defer c.Close()
+}
 
-	// 构建请求
-	message := stun.MustBuild(stun.TransactionID, stun.BindingRequest)
+// stunRoundTrip 发送 Binding 请求并读取响应
+// 流式传输（TCP/TLS）按消息头中的长度读取完整消息
+func stunRoundTrip(conn net.Conn, stream bool) (*stun.Message, error) {
+	req, err := stun.Build(stun.TransactionID, stun.BindingRequest, stun.Fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(req.Raw); err != nil {
+		return nil, err
+	}
 
-	var ip string
-	var callbackErr error // 使用单独的变量存储回调中的错误
-	
-	// 发送请求并等待响应
-	if err := c.Do(message, func(res stun.Event) {
-		if res.Error != nil {
-			callbackErr = res.Error
-			return
+	var raw []byte
+	if stream {
+		header := make([]byte, stunHeaderSize)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return nil, err
+		}
+		body := make([]byte, binary.BigEndian.Uint16(header[2:4]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return nil, err
 		}
-		// 解析 XOR-MAPPED-ADDRESS
-		var xorAddr stun.XORMappedAddress
-		if getErr := xorAddr.GetFrom(res.Message); getErr != nil {
-			callbackErr = getErr
-			return
+		raw = append(header, body...)
+	} else {
+		buf := make([]byte, 1500)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
 		}
-		ip = xorAddr.IP.String()
-	}); err != nil {
-		return "", "", fmt.Errorf("STUN 请求失败: %w", err)
+		raw = buf[:n]
 	}
 
-	// 检查回调中是否有错误
-	if callbackErr != nil {
-		return "", "", fmt.Errorf("STUN 响应处理失败: %w", callbackErr)
+	res := &stun.Message{Raw: raw}
+	if err := res.Decode(); err != nil {
+		return nil, err
 	}
+	if res.TransactionID != req.TransactionID {
+		return nil, fmt.Errorf("事务 ID 不匹配")
+	}
+	return res, nil
+}
 
-	if ip == "" {
-		return "", "", fmt.Errorf("未能从 STUN 响应中获取 IP")
+// mappedAddress 解析响应中的 XOR-MAPPED-ADDRESS，兼容只返回 MAPPED-ADDRESS 的旧服务器
+func mappedAddress(m *stun.Message) (*net.UDPAddr, error) {
+	if m.Type.Class == stun.ClassErrorResponse {
+		var code stun.ErrorCodeAttribute
+		if err := code.GetFrom(m); err == nil {
+			return nil, fmt.Errorf("服务器返回错误: %s", code.String())
+		}
+		return nil, fmt.Errorf("服务器返回错误响应")
 	}
 
-	return ip, "STUN", nil
+	var xorAddr stun.XORMappedAddress
+	if err := xorAddr.GetFrom(m); err == nil {
+		return &net.UDPAddr{IP: xorAddr.IP, Port: xorAddr.Port}, nil
+	}
+	var addr stun.MappedAddress
+	if err := addr.GetFrom(m); err != nil {
+		return nil, fmt.Errorf("未能从 STUN 响应中获取 IP: %w", err)
+	}
+	return &net.UDPAddr{IP: addr.IP, Port: addr.Port}, nil
 }
 
 // GetIPv6 获取 IPv6 地址（STUN 通常不支持，返回空）
@@ -61,3 +245,19 @@ func (s *STUNProvider) GetIPv6() (string, string, error) {
 	return "", "", nil
 }
 
+// natDiscoveryTarget 返回启用了 NAT 行为探测的 STUN 配置（首个服务器与超时）
+func natDiscoveryTarget(props map[string]string) (string, time.Duration, bool) {
+	if enabled, _ := strconv.ParseBool(props["nat_discovery"]); !enabled {
+		return "", 0, false
+	}
+	// NAT 行为探测只能通过 UDP 进行
+	servers := splitServers(props["server"], "udp")
+	if len(servers) == 0 {
+		return "", 0, false
+	}
+	timeout, err := time.ParseDuration(props["timeout"])
+	if err != nil || timeout <= 0 {
+		timeout = 3 * time.Second
+	}
+	return servers[0], timeout, true
+}
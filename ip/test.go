@@ -0,0 +1,97 @@
+package ip
+
+import (
+	"fmt"
+	"idrd/config"
+	"time"
+)
+
+// Tracer 由支持诊断跟踪的提供者实现（如 router_ssh 记录 SSH 认证过程）
+type Tracer interface {
+	SetTrace(fn func(format string, args ...any))
+}
+
+// TestResult 单次测试提供者配置的结果
+type TestResult struct {
+	Success   bool     `json:"success"`
+	IP        string   `json:"ip,omitempty"`
+	Source    string   `json:"source,omitempty"`
+	LatencyMs int64    `json:"latency_ms"`
+	Error     string   `json:"error,omitempty"`
+	Trace     []string `json:"trace,omitempty"`
+}
+
+// TestProvider 使用给定配置执行一次 IP 获取，不读写任何全局状态
+// 超时后立即返回；底层提供者自身的超时（如 SSH 10 秒）保证后台协程最终退出
+func TestProvider(pCfg config.IPProviderConfig, timeout time.Duration) TestResult {
+	var result TestResult
+
+	t, ok := Lookup(pCfg.Type)
+	if !ok {
+		result.Error = fmt.Sprintf("unknown provider type: %s", pCfg.Type)
+		return result
+	}
+
+	// 在副本上校验，避免修改调用方的属性
+	props := make(map[string]string, len(pCfg.Properties))
+	for k, v := range pCfg.Properties {
+		props[k] = v
+	}
+	if err := t.validate(props); err != nil {
+		result.Error = "配置验证失败: " + err.Error()
+		return result
+	}
+
+	p, err := t.New(props)
+	if err != nil {
+		result.Error = "初始化失败: " + err.Error()
+		return result
+	}
+
+	traceCh := make(chan string, 64)
+	if tracer, ok := p.(Tracer); ok {
+		tracer.SetTrace(func(format string, args ...any) {
+			select {
+			case traceCh <- fmt.Sprintf(format, args...):
+			default:
+			}
+		})
+	}
+
+	type outcome struct {
+		ip, source string
+		err        error
+	}
+	done := make(chan outcome, 1)
+	start := time.Now()
+	go func() {
+		ip, source, err := p.GetIP()
+		done <- outcome{ip, source, err}
+	}()
+
+	select {
+	case o := <-done:
+		result.IP, result.Source = o.ip, o.source
+		if o.err != nil {
+			result.Error = o.err.Error()
+		} else if o.ip == "" {
+			result.Error = "提供者未返回 IP"
+		}
+	case <-time.After(timeout):
+		result.Error = fmt.Sprintf("测试超时 (%s)", timeout)
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Success = result.Error == ""
+	if result.Success && result.Source == "" {
+		result.Source = pCfg.Type
+	}
+
+	for {
+		select {
+		case line := <-traceCh:
+			result.Trace = append(result.Trace, line)
+		default:
+			return result
+		}
+	}
+}
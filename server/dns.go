@@ -0,0 +1,220 @@
+package server
+
+import (
+	"fmt"
+	"idrd/config"
+	"idrd/dns"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// planRequest /api/dns/plan 请求体，字段均可省略
+type planRequest struct {
+	Config *config.AppConfig `json:"config"` // 待保存的候选配置，为空时使用当前配置
+	IP     string            `json:"ip"`     // 目标 IP，为空时使用当前 IP
+}
+
+// handlePlanDNS 计算将配置中的记录同步到指定 IP 所需的变更（只读，不修改任何记录）
+func (s *Server) handlePlanDNS(c echo.Context) error {
+	var req planRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "请求格式错误: " + err.Error()})
+	}
+
+	cfg := s.Config.Get()
+	if req.Config != nil {
+		if err := config.ValidateConfig(req.Config); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "配置验证失败: " + err.Error()})
+		}
+		cfg = *req.Config
+	}
+
+	target := req.IP
+	if target == "" {
+		target = s.GetCurrentIP()
+	}
+	if target == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "尚未获取到当前 IP，请在请求中指定 ip"})
+	}
+	if net.ParseIP(target) == nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "无效的 IP: " + target})
+	}
+
+	changes := s.DNSUpdater.Plan(&cfg, target)
+	summary := map[string]int{}
+	for _, change := range changes {
+		summary[change.Action]++
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"ip":      target,
+		"changes": changes,
+		"summary": summary,
+	})
+}
+
+// handleGetOrphans 列出已从配置中移除、但 idrd 曾创建或管理过的记录
+func (s *Server) handleGetOrphans(c echo.Context) error {
+	orphans, err := s.DNSUpdater.Orphans()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "查询孤儿记录失败: " + err.Error()})
+	}
+	policy := s.Config.Get().OrphanPolicy
+	if policy == "" {
+		policy = config.OrphanPolicyAsk
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"orphans": orphans,
+		"policy":  policy,
+	})
+}
+
+// orphansRequest 孤儿记录操作的请求体
+type orphansRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// handleDeleteOrphans 删除用户确认的孤儿记录（只删除仍为孤儿的记录）
+func (s *Server) handleDeleteOrphans(c echo.Context) error {
+	var req orphansRequest
+	if err := c.Bind(&req); err != nil || len(req.IDs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "请求格式错误: 需要 ids"})
+	}
+	deleted, err := s.DNSUpdater.DeleteOrphans(req.IDs)
+	result := map[string]interface{}{"deleted": deleted}
+	if err != nil {
+		result["error"] = "部分记录删除失败: " + err.Error()
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// handleForgetOrphans 停止跟踪孤儿记录，服务商中的记录保持不变
+func (s *Server) handleForgetOrphans(c echo.Context) error {
+	var req orphansRequest
+	if err := c.Bind(&req); err != nil || len(req.IDs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "请求格式错误: 需要 ids"})
+	}
+	forgotten, err := s.DNSUpdater.ForgetOrphans(req.IDs)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "停止跟踪失败: " + err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]int{"forgotten": forgotten})
+}
+
+// handleVerifyDNSAccount 使用请求中的账户配置验证凭据，列出可访问的 zone 和已有的 A/AAAA 记录（只读）
+func (s *Server) handleVerifyDNSAccount(c echo.Context) error {
+	var account config.DNSAccount
+	if err := c.Bind(&account); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "账户配置格式错误: " + err.Error()})
+	}
+
+	timeout := 30 * time.Second
+	if t := c.QueryParam("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d <= 0 || d > 2*time.Minute {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid timeout (must be between 0 and 2m)"})
+		}
+		timeout = d
+	}
+
+	return c.JSON(http.StatusOK, dns.VerifyAccount(account, timeout))
+}
+
+// adoptRequest 接管已有记录的请求体
+type adoptRequest struct {
+	dns.AdoptQuery
+	Config *config.AppConfig `json:"config"` // 待保存的候选配置（仅查询候选记录时使用），为空时使用当前配置
+	IDs    []string          `json:"ids"`    // 要加入配置的候选记录 ID（同名的 A/AAAA 或多条记录各有不同的 ID）
+}
+
+// adoptQuery 返回查询条件，IP 和名称模式都未指定时使用当前 IP
+func (s *Server) adoptQuery(req adoptRequest) (dns.AdoptQuery, error) {
+	q := req.AdoptQuery
+	if q.IP == "" && q.Pattern == "" {
+		q.IP = s.GetCurrentIP()
+		if q.IP == "" {
+			return q, fmt.Errorf("尚未获取到当前 IP，请在请求中指定 ip 或 pattern")
+		}
+	}
+	return q, q.Validate()
+}
+
+// handleAdoptCandidates 列出 zone 中指向当前 IP（或指定 IP）、或名称匹配 pattern 的已有记录（只读）
+func (s *Server) handleAdoptCandidates(c echo.Context) error {
+	var req adoptRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "请求格式错误: " + err.Error()})
+	}
+	q, err := s.adoptQuery(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "查询条件错误: " + err.Error()})
+	}
+
+	cfg := s.Config.Get()
+	if req.Config != nil {
+		cfg = *req.Config
+	}
+	candidates, err := s.DNSUpdater.AdoptCandidates(&cfg, q)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "列出已有记录失败: " + err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"ip":         q.IP,
+		"pattern":    q.Pattern,
+		"candidates": candidates,
+	})
+}
+
+// handleAdoptRecords 将选中的已有记录加入当前配置并保存，保留记录现有的 proxied、TTL、备注和标签
+func (s *Server) handleAdoptRecords(c echo.Context) error {
+	var req adoptRequest
+	if err := c.Bind(&req); err != nil || len(req.IDs) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "请求格式错误: 需要 account、zone 和 ids"})
+	}
+	q, err := s.adoptQuery(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "查询条件错误: " + err.Error()})
+	}
+
+	cfg := s.Config.Get()
+	candidates, err := s.DNSUpdater.AdoptCandidates(&cfg, q)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "列出已有记录失败: " + err.Error()})
+	}
+	selected := []dns.AdoptCandidate{}
+	for _, id := range req.IDs {
+		i := slices.IndexFunc(candidates, func(cand dns.AdoptCandidate) bool { return cand.ID == id })
+		if i < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("记录 ID %s 不在候选记录中", id)})
+		}
+		selected = append(selected, candidates[i])
+	}
+
+	adopted, err := dns.AdoptRecords(&cfg, q.Account, q.Zone, selected)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "接管记录失败: " + err.Error()})
+	}
+	if len(adopted) == 0 {
+		return c.JSON(http.StatusOK, map[string]interface{}{"adopted": adopted})
+	}
+	if err := config.ValidateConfig(&cfg); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "配置验证失败: " + err.Error()})
+	}
+	if err := config.SaveConfig(&cfg, s.DB); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "保存配置失败: " + err.Error()})
+	}
+	s.Config.Update(&cfg)
+
+	log.Printf("📝 已接管 %s 中的 %d 条已有记录: %s", q.Zone, len(adopted), strings.Join(adopted, ", "))
+	s.DB.AddErrorLog("info", fmt.Sprintf("Adopted %d existing records in %s: %s", len(adopted), q.Zone, strings.Join(adopted, ", ")))
+
+	s.notifyConfigUpdate()
+
+	return c.JSON(http.StatusOK, map[string]interface{}{"adopted": adopted})
+}
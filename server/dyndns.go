@@ -0,0 +1,237 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"idrd/config"
+	"idrd/dns"
+	"idrd/ip"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// dyndns2MaxHostnames 单次请求允许的最大主机名数量
+const dyndns2MaxHostnames = 20
+
+// 同一来源 IP 在 authFailureWindow 内认证失败 maxAuthFailures 次后，在窗口结束前直接拒绝（返回 abuse）
+const (
+	maxAuthFailures   = 5
+	authFailureWindow = 15 * time.Minute
+)
+
+// handleNicUpdate 实现 dyndns2 协议的服务端 (/nic/update)
+// 路由器等设备使用独立的设备凭据推送 IP，idrd 通过已配置的 DNS 后端更新对应记录
+// 凭据优先取 HTTP Basic Auth，其次取 username/password 查询参数（简化 GET 形式，便于只支持 URL 模板的设备）
+// 返回纯文本 dyndns2 响应码：good、nochg、badauth、abuse、nohost、notfqdn、numhost、911
+func (s *Server) handleNicUpdate(c echo.Context) error {
+	cfg := s.Config.Get()
+	if !cfg.DynDNSServer.Enabled {
+		return echo.NewHTTPError(http.StatusNotFound)
+	}
+
+	source := c.RealIP()
+	if s.authFailures.blocked(source) {
+		return c.String(http.StatusTooManyRequests, "abuse")
+	}
+	username, password, ok := c.Request().BasicAuth()
+	if !ok {
+		username, password = c.QueryParam("username"), c.QueryParam("password")
+	}
+	device := findDevice(cfg.DynDNSServer.Devices, username, password)
+	if device == nil {
+		if s.authFailures.fail(source) {
+			log.Printf("⛔ dyndns2: 来源 %s 认证失败次数过多，%v 内拒绝其请求", source, authFailureWindow)
+		}
+		log.Printf("⛔ dyndns2: 设备认证失败 (用户名: %q, 来源: %s)", username, source)
+		return c.String(http.StatusUnauthorized, "badauth")
+	}
+	s.authFailures.reset(source)
+
+	myip := strings.TrimSpace(c.QueryParam("myip"))
+	if myip == "" {
+		myip = source
+	}
+	parsed := net.ParseIP(myip)
+	if parsed == nil {
+		return c.String(http.StatusOK, "911")
+	}
+	myip = parsed.String()
+
+	hostnames, code := s.resolvePushHostnames(device, c.QueryParam("hostname"))
+	if code != "" {
+		return c.String(http.StatusOK, code)
+	}
+
+	// 推送的 IPv4 作为 dyndns_push IP 来源
+	if parsed.To4() != nil {
+		ip.RecordPush(device.Name, myip)
+	}
+
+	// 同一设备对同一组主机名重复推送相同 IP 时不再调用 DNS 后端
+	key := device.Name + "|" + strings.Join(hostnames, ",")
+	s.pushMutex.Lock()
+	synced := s.pushedUpdates[key] == myip
+	s.pushMutex.Unlock()
+	if synced {
+		return c.String(http.StatusOK, "nochg "+myip)
+	}
+
+	log.Printf("📥 dyndns2: 设备 %s 推送 %s (%s)", device.Name, myip, strings.Join(hostnames, ", "))
+	if s.DNSUpdater != nil {
+		if _, err := s.DNSUpdater.UpdateHostnames(myip, hostnames); err != nil {
+			log.Printf("❌ dyndns2: 设备 %s 推送的更新失败: %v", device.Name, err)
+			s.DB.AddErrorLog("error", fmt.Sprintf("dyndns2 设备 %s 推送 %s 更新失败: %v", device.Name, myip, err))
+			return c.String(http.StatusOK, "911")
+		}
+	}
+
+	s.pushMutex.Lock()
+	s.pushedUpdates[key] = myip
+	s.pushMutex.Unlock()
+
+	return c.String(http.StatusOK, "good "+myip)
+}
+
+// forgetPushes 同步修改或未能确认记录时清除相关主机名的推送缓存，下次推送重新调用 DNS 后端
+// （记录可能已被其他来源改为别的 IP，缓存不能再作为 nochg 的依据）
+func (s *Server) forgetPushes(report *dns.Report) {
+	changed := make(map[string]bool)
+	for _, r := range report.Records {
+		if r.Action != dns.ActionNoop {
+			changed[strings.ToLower(r.Domain)] = true
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	s.pushMutex.Lock()
+	defer s.pushMutex.Unlock()
+	for key := range s.pushedUpdates {
+		_, hosts, _ := strings.Cut(key, "|")
+		for _, h := range strings.Split(hosts, ",") {
+			if changed[strings.ToLower(h)] {
+				delete(s.pushedUpdates, key)
+				break
+			}
+		}
+	}
+}
+
+// authLimiter 按来源 IP 统计 dyndns2 认证失败次数
+type authLimiter struct {
+	mu       sync.Mutex
+	failures map[string]*authFailure
+}
+
+type authFailure struct {
+	count int
+	since time.Time // 窗口开始时间
+}
+
+func newAuthLimiter() *authLimiter {
+	return &authLimiter{failures: make(map[string]*authFailure)}
+}
+
+// blocked 返回来源是否因认证失败过多被暂时拒绝
+func (l *authLimiter) blocked(source string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failures[source]
+	if !ok {
+		return false
+	}
+	if time.Since(f.since) >= authFailureWindow {
+		delete(l.failures, source)
+		return false
+	}
+	return f.count >= maxAuthFailures
+}
+
+// fail 记录一次认证失败，返回来源是否刚达到上限
+func (l *authLimiter) fail(source string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	// 顺带清理过期的条目，避免扫描类请求使 map 无限增长
+	for k, f := range l.failures {
+		if now.Sub(f.since) >= authFailureWindow {
+			delete(l.failures, k)
+		}
+	}
+	f, ok := l.failures[source]
+	if !ok {
+		f = &authFailure{since: now}
+		l.failures[source] = f
+	}
+	f.count++
+	return f.count == maxAuthFailures
+}
+
+// reset 认证成功后清除来源的失败计数
+func (l *authLimiter) reset(source string) {
+	l.mu.Lock()
+	delete(l.failures, source)
+	l.mu.Unlock()
+}
+
+// findDevice 按用户名查找设备并以常量时间比较密码
+func findDevice(devices []config.DynDNSDevice, username, password string) *config.DynDNSDevice {
+	if username == "" || password == "" {
+		return nil
+	}
+	for i := range devices {
+		d := &devices[i]
+		if d.Username == username && subtle.ConstantTimeCompare([]byte(d.Password), []byte(password)) == 1 {
+			return d
+		}
+	}
+	return nil
+}
+
+// resolvePushHostnames 解析并校验请求中的主机名，失败时返回 dyndns2 响应码
+// 主机名必须是已配置的记录，且在设备允许的列表内（列表为空表示允许所有记录）
+func (s *Server) resolvePushHostnames(device *config.DynDNSDevice, raw string) ([]string, string) {
+	var requested []string
+	for _, h := range strings.Split(raw, ",") {
+		if h = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(h), ".")); h != "" {
+			requested = append(requested, h)
+		}
+	}
+	if len(requested) == 0 {
+		// 未指定主机名时使用设备允许的全部主机名
+		requested = device.Hostnames
+		if len(requested) == 0 {
+			return nil, "nohost"
+		}
+	}
+	if len(requested) > dyndns2MaxHostnames {
+		return nil, "numhost"
+	}
+
+	configured := make(map[string]bool)
+	if s.DNSUpdater != nil {
+		for _, h := range s.DNSUpdater.Hostnames() {
+			configured[strings.ToLower(h)] = true
+		}
+	}
+	allowed := make(map[string]bool, len(device.Hostnames))
+	for _, h := range device.Hostnames {
+		allowed[strings.ToLower(strings.TrimSuffix(h, "."))] = true
+	}
+
+	for _, h := range requested {
+		if !strings.Contains(h, ".") {
+			return nil, "notfqdn"
+		}
+		if !configured[h] || (len(allowed) > 0 && !allowed[h]) {
+			return nil, "nohost"
+		}
+	}
+	return requested, ""
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"idrd/config"
+)
+
+// 伪造的 X-Forwarded-For 不能绕过认证失败限制，也不能让请求被视为来自可信子网
+func TestNicUpdateIgnoresForwardedFor(t *testing.T) {
+	cfg := config.NewSafeConfig(&config.AppConfig{
+		Server: config.ServerConfig{APIKey: "secret", TrustedSubnets: []string{"10.0.0.0/8"}},
+		DynDNSServer: config.DynDNSServerConfig{
+			Enabled: true,
+			Devices: []config.DynDNSDevice{{Name: "router", Username: "router", Password: "pass"}},
+		},
+	})
+	s := New(cfg, nil, nil, nil, time.Now())
+
+	request := func(path, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "192.0.2.10:51234"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Real-IP", forwardedFor)
+		rec := httptest.NewRecorder()
+		s.Echo.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := range maxAuthFailures {
+		rec := request("/nic/update?username=router&password=wrong&hostname=home.example.com", "198.51.100."+strconv.Itoa(i+1))
+		if rec.Code != http.StatusUnauthorized || rec.Body.String() != "badauth" {
+			t.Fatalf("attempt %d: %d %q, want 401 badauth", i+1, rec.Code, rec.Body.String())
+		}
+	}
+	// 失败按连接地址计数，更换 X-Forwarded-For 仍被拒绝
+	rec := request("/nic/update?username=router&password=pass&hostname=home.example.com", "198.51.100.99")
+	if rec.Code != http.StatusTooManyRequests || rec.Body.String() != "abuse" {
+		t.Errorf("after %d failures: %d %q, want 429 abuse", maxAuthFailures, rec.Code, rec.Body.String())
+	}
+
+	// 伪造可信子网内的地址不能免去 API Key
+	if rec := request("/api/ip", "10.0.0.1"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("/api/ip with forged X-Forwarded-For: %d, want 401", rec.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"embed"
 	"fmt"
 	"idrd/config"
@@ -9,7 +10,9 @@ import (
 	"idrd/ip"
 	"io"
 	"io/fs"
+	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -27,22 +30,31 @@ type Server struct {
 	Echo             *echo.Echo
 	Config           *config.SafeConfig
 	DB               *db.DB
-	DNSUpdater       *dns.CloudflareUpdater
+	DNSUpdater       *dns.Manager
 	IPProvider       *ip.DynamicProvider
 	Hub              *Hub // WebSocket Hub
 	StartTime        time.Time
 	LastCheckTime    time.Time
 	CurrentIP        string
 	CurrentSource    string
-	ConfigUpdateChan chan struct{} // 配置更新通知通道
+	NATBehavior      *ip.NATBehavior // 最近一次 NAT 行为探测结果
+	ConfigUpdateChan chan struct{}   // 配置更新通知通道
 	ipMutex          sync.RWMutex
+	natMutex         sync.Mutex // 避免重叠的 NAT 行为探测
+
+	pushedUpdates map[string]string // dyndns2 推送：设备|主机名 -> 已成功同步的 IP
+	pushMutex     sync.Mutex
+	authFailures  *authLimiter // dyndns2 按来源 IP 限制认证失败次数
 }
 
 // New 创建新的 Server 实例
-func New(cfg *config.SafeConfig, database *db.DB, dnsUpdater *dns.CloudflareUpdater, ipProvider *ip.DynamicProvider, startTime time.Time) *Server {
+func New(cfg *config.SafeConfig, database *db.DB, dnsUpdater *dns.Manager, ipProvider *ip.DynamicProvider, startTime time.Time) *Server {
 	e := echo.New()
 	e.HidePort = true
 	e.HideBanner = true
+	// 客户端 IP 只取连接的对端地址：X-Forwarded-For / X-Real-IP 可被任意伪造，
+	// 否则可绕过 trusted_subnets、dyndns2 认证失败限制，并伪造推送的默认 IP
+	e.IPExtractor = echo.ExtractIPDirect()
 
 	s := &Server{
 		Echo:             e,
@@ -54,13 +66,26 @@ func New(cfg *config.SafeConfig, database *db.DB, dnsUpdater *dns.CloudflareUpda
 		StartTime:        startTime,
 		LastCheckTime:    startTime,
 		ConfigUpdateChan: make(chan struct{}, 1),
+		pushedUpdates:    make(map[string]string),
+		authFailures:     newAuthLimiter(),
 	}
 
 	// 启动 WebSocket Hub
 	go s.Hub.Run()
 
-	// 通用中间件
-	e.Use(middleware.Logger())
+	// 每次 DNS 同步的结果推送给 WebSocket 客户端
+	if dnsUpdater != nil {
+		dnsUpdater.OnReport = func(report *dns.Report) {
+			s.forgetPushes(report)
+			s.BroadcastDNSUpdate(report)
+		}
+	}
+
+	// 通用中间件（访问日志中的 URI 去掉密码和 API Key 查询参数）
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format:        strings.Replace(middleware.DefaultLoggerConfig.Format, "${uri}", "${custom}", 1),
+		CustomTagFunc: func(c echo.Context, buf *bytes.Buffer) (int, error) { return buf.WriteString(redactedURI(c.Request().URL)) },
+	}))
 	e.Use(middleware.Recover())
 
 	// 静态文件（嵌入）
@@ -139,10 +164,26 @@ func New(cfg *config.SafeConfig, database *db.DB, dnsUpdater *dns.CloudflareUpda
 	authenticated.GET("/api/config/export", s.handleExportConfig)
 	authenticated.POST("/api/config/import", s.handleImportConfig)
 	authenticated.POST("/api/dns/update", s.handleTriggerDNSUpdate)
+	authenticated.GET("/api/dns/providers", s.handleGetDNSProviders)
+	authenticated.POST("/api/dns/accounts/verify", s.handleVerifyDNSAccount)
+	authenticated.POST("/api/dns/adopt/candidates", s.handleAdoptCandidates)
+	authenticated.POST("/api/dns/adopt", s.handleAdoptRecords)
+	authenticated.POST("/api/dns/plan", s.handlePlanDNS)
+	authenticated.GET("/api/dns/orphans", s.handleGetOrphans)
+	authenticated.POST("/api/dns/orphans/delete", s.handleDeleteOrphans)
+	authenticated.POST("/api/dns/orphans/forget", s.handleForgetOrphans)
+
+	// IP 提供者 API
+	authenticated.GET("/api/providers/types", s.handleGetProviderTypes)
+	authenticated.POST("/api/providers/test", s.handleTestProvider)
 
 	// WebSocket 实时推送（不需要认证，因为只推送公开数据）
 	e.GET("/ws", s.handleWebSocket)
 
+	// dyndns2 推送端点（使用独立的设备凭据，不经过 API Key / 可信子网认证）
+	e.GET("/nic/update", s.handleNicUpdate)
+	e.GET("/update", s.handleNicUpdate)
+
 	// 健康检查端点（不需要认证，供 Docker 健康检查使用）
 	e.GET("/health", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
@@ -208,6 +249,38 @@ func (s *Server) GetLastCheck() time.Time {
 	return s.LastCheckTime
 }
 
+// GetNATBehavior 获取最近一次 NAT 行为探测结果
+func (s *Server) GetNATBehavior() *ip.NATBehavior {
+	s.ipMutex.RLock()
+	defer s.ipMutex.RUnlock()
+	return s.NATBehavior
+}
+
+// RefreshNATBehavior 执行一次 NAT 行为探测（仅当 STUN 提供者启用了 nat_discovery）
+func (s *Server) RefreshNATBehavior() {
+	if s.IPProvider == nil || !s.natMutex.TryLock() {
+		return
+	}
+	defer s.natMutex.Unlock()
+	nat, ok := s.IPProvider.DiscoverNAT()
+	if !ok {
+		// 未启用（或已关闭）nat_discovery 时清除旧结果
+		s.ipMutex.Lock()
+		s.NATBehavior = nil
+		s.ipMutex.Unlock()
+		return
+	}
+	if nat.Error != "" {
+		log.Printf("⚠️  NAT 行为探测未完成: %s", nat.Error)
+	} else {
+		log.Printf("🧭 NAT 行为探测: 映射=%s, 过滤=%s", nat.MappingBehavior, nat.FilteringBehavior)
+	}
+
+	s.ipMutex.Lock()
+	defer s.ipMutex.Unlock()
+	s.NATBehavior = nat
+}
+
 // BroadcastIPChange 广播 IP 变化事件给所有 WebSocket 客户端
 func (s *Server) BroadcastIPChange(newIP, source string) {
 	if s.Hub != nil {
@@ -218,6 +291,49 @@ func (s *Server) BroadcastIPChange(newIP, source string) {
 	}
 }
 
+// BroadcastDNSUpdate 广播 DNS 同步结果给所有 WebSocket 客户端
+// 定期校准没有变更和失败时不广播
+func (s *Server) BroadcastDNSUpdate(report *dns.Report) {
+	if s.Hub == nil || (report.Reconcile && report.Changed == 0 && report.Failed == 0) {
+		return
+	}
+	s.Hub.Broadcast("dns_update", report)
+}
+
+// notifyConfigUpdate 配置保存后清除 dyndns2 推送缓存，并通知 monitoring loop（非阻塞）
+func (s *Server) notifyConfigUpdate() {
+	s.pushMutex.Lock()
+	clear(s.pushedUpdates)
+	s.pushMutex.Unlock()
+
+	select {
+	case s.ConfigUpdateChan <- struct{}{}:
+	default:
+		// 通道已满，说明已有挂起的变更信号，忽略
+	}
+}
+
+// redactedQueryParams 访问日志中隐藏的查询参数（dyndns2 密码、API Key）
+var redactedQueryParams = []string{"password", "key"}
+
+// redactedURI 返回隐藏敏感查询参数后的请求 URI
+func redactedURI(u *url.URL) string {
+	q := u.Query()
+	redacted := false
+	for _, name := range redactedQueryParams {
+		if q.Has(name) {
+			q.Set(name, "redacted")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.RequestURI()
+	}
+	r := *u
+	r.RawQuery = q.Encode()
+	return r.RequestURI()
+}
+
 // handleGetIP 返回纯文本 IP（返回系统监控的公网 IP）
 func (s *Server) handleGetIP(c echo.Context) error {
 	ip := s.GetCurrentIP()
@@ -235,6 +351,32 @@ func (s *Server) handleGetIPJSON(c echo.Context) error {
 }
 
 
+// handleGetProviderTypes 返回所有已注册的 IP 提供者类型及其属性定义
+func (s *Server) handleGetProviderTypes(c echo.Context) error {
+	return c.JSON(http.StatusOK, ip.Types())
+}
+
+// handleTestProvider 使用请求中的单个提供者配置试运行一次，不影响当前配置和历史记录
+func (s *Server) handleTestProvider(c echo.Context) error {
+	var pCfg config.IPProviderConfig
+	if err := c.Bind(&pCfg); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "提供者配置格式错误: " + err.Error()})
+	}
+	if pCfg.Type == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "provider type cannot be empty"})
+	}
+
+	timeout := 15 * time.Second
+	if t := c.QueryParam("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d <= 0 || d > time.Minute {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "invalid timeout (must be between 0 and 1m)"})
+		}
+		timeout = d
+	}
+
+	return c.JSON(http.StatusOK, ip.TestProvider(pCfg, timeout))
+}
 
 // handleGetConfig 获取配置（不脱敏，直接返回原始值）
 func (s *Server) handleGetConfig(c echo.Context) error {
@@ -268,12 +410,7 @@ func (s *Server) handleUpdateConfig(c echo.Context) error {
 	// 记录系统日志
 	s.DB.AddErrorLog("info", "Configuration updated via Web UI")
 
-	// 通知 monitoring loop 配置已变更（非阻塞）
-	select {
-	case s.ConfigUpdateChan <- struct{}{}:
-	default:
-		// 通道已满，说明已有挂起的变更信号，忽略
-	}
+	s.notifyConfigUpdate()
 
 	// 强制触发一次 DNS 更新（异步）
 	// 确保存储了配置后立即尝试同步，解决"显示已同步但无记录"的问题
@@ -295,7 +432,7 @@ func (s *Server) handleUpdateConfig(c echo.Context) error {
 		if ip != "" && s.DNSUpdater != nil {
 			fmt.Println("Configuration changed, triggering immediate DNS update...")
 			// 强制更新（即使 IP 没变也检查记录是否存在）
-			if err := s.DNSUpdater.UpdateIP(ip); err != nil {
+			if _, err := s.DNSUpdater.UpdateIP(ip); err != nil {
 				fmt.Printf("Immediate DNS update failed: %v\n", err)
 				s.DB.AddErrorLog("error", "Post-config DNS update failed: "+err.Error())
 			} else {
@@ -359,6 +496,7 @@ func (s *Server) handleImportConfig(c echo.Context) error {
 
 	// 更新内存中的配置
 	s.Config.Update(&testCfg)
+	s.notifyConfigUpdate()
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "配置导入成功并已生效",
@@ -436,13 +574,16 @@ func (s *Server) handleGetStatus(c echo.Context) error {
 
 	// 构建简化的DNS状态（去重，只显示每个域名的最新状态）
 	dnsRecordMap := make(map[string]struct{
-		domain  string
-		account string
-		success bool
+		domain     string
+		account    string
+		recordType string
+		success    bool
+		action     string
+		dryRun     bool
 	})
 	// 默认为 false，只有当至少有一个成功记录时才设为 true（除非根本没配置 DNS）
 	dnsSynced := false
-	if len(cfg.CloudflareAccounts) == 0 {
+	if len(cfg.DNSAccounts) == 0 {
 		// 如果没配置账户，状态显示为 synced（避免报错），但记录数为 0
 		dnsSynced = true
 	} else if len(dnsUpdates) > 0 {
@@ -450,24 +591,49 @@ func (s *Server) handleGetStatus(c echo.Context) error {
 		dnsSynced = true
 	}
 	
+	removed := make(map[string]bool) // 最新一条为删除孤儿记录的域名
 	for _, update := range dnsUpdates {
+		if removed[update.Domain] {
+			continue
+		}
 		// 使用域名作为唯一键（同一域名只保留最新的记录）
 		if _, exists := dnsRecordMap[update.Domain]; !exists {
+			if update.Action == dns.ActionDelete && !update.DryRun {
+				removed[update.Domain] = true
+				continue
+			}
 			dnsRecordMap[update.Domain] = struct {
-				domain  string
-				account string
-				success bool
+				domain     string
+				account    string
+				recordType string
+				success    bool
+				action     string
+				dryRun     bool
 			}{
-				domain:  update.Domain,
-				account: update.AccountName,
-				success: update.Success,
+				domain:     update.Domain,
+				account:    update.AccountName,
+				recordType: update.RecordType,
+				success:    update.Success,
+				action:     update.Action,
+				dryRun:     update.DryRun,
 			}
-			if !update.Success {
+			// 演练模式下计划创建或更新的记录尚未真正同步
+			if !update.Success || (update.DryRun && update.Action != dns.ActionNoop) {
 				dnsSynced = false
 			}
 		}
 	}
 	
+	// 最近更新的记录的传播状态（propagated 或 stale，检查中为 propagating），按账户、域名和类型区分
+	propagation := s.DNSUpdater.Propagation()
+	propagationStatus := make(map[string]string, len(propagation))
+	propagationKey := func(account, domain, recordType string) string {
+		return account + "|" + strings.ToLower(domain) + "|" + recordType
+	}
+	for _, p := range propagation {
+		propagationStatus[propagationKey(p.Account, p.Domain, p.Type)] = p.Status
+	}
+
 	// 将 map 转换为 slice
 	dnsRecords := []string{}
 	for _, record := range dnsRecordMap {
@@ -475,6 +641,15 @@ func (s *Server) handleGetStatus(c echo.Context) error {
 		if !record.success {
 			recordStr += " - failed"
 		}
+		if record.dryRun {
+			recordStr += " - planned " + record.action
+		}
+		switch status := propagationStatus[propagationKey(record.account, record.domain, record.recordType)]; status {
+		case dns.PropagationPending:
+			recordStr += " - propagating"
+		case dns.PropagationPropagated, dns.PropagationStale:
+			recordStr += " - " + status
+		}
 		dnsRecords = append(dnsRecords, recordStr)
 	}
 
@@ -486,6 +661,16 @@ func (s *Server) handleGetStatus(c echo.Context) error {
 	// 获取最近的检查日志
 	recentChecks, _ := s.DB.GetRecentCheckLogs(10)
 
+	// NAT 状态：当前公网 IP 本身位于 100.64.0.0/10（如路由器 WAN 口拿到共享地址）也视为 CGNAT
+	natStatus := map[string]interface{}{
+		"cgnat": ip.IsSharedAddress(s.GetCurrentIP()),
+	}
+	if nat := s.GetNATBehavior(); nat != nil {
+		natStatus["behavior"] = nat
+		natStatus["symmetric"] = nat.Symmetric
+		natStatus["cgnat"] = natStatus["cgnat"].(bool) || nat.CGNAT
+	}
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"current_ip":    s.GetCurrentIP(),
 		"source":        source,
@@ -499,6 +684,10 @@ func (s *Server) handleGetStatus(c echo.Context) error {
 		"dns_status": map[string]interface{}{
 			"synced":  dnsSynced,
 			"records": dnsRecords,
+			"dry_run": cfg.DryRun,
+			"conflicts": s.DNSUpdater.Conflicts(),
+			"quota":     dns.Quotas(),
+			"propagation": propagation,
 		},
 		"error_logs": errorLogs,
 		"check_stats": map[string]interface{}{
@@ -506,9 +695,10 @@ func (s *Server) handleGetStatus(c echo.Context) error {
 			"dns": dnsCheckStats,
 		},
 		"recent_checks": recentChecks,
+		"nat":           natStatus,
 		"config": map[string]interface{}{
-			"dns_enabled": len(cfg.CloudflareAccounts) > 0,
-			"accounts":    cfg.CloudflareAccounts,
+			"dns_enabled": len(cfg.DNSAccounts) > 0,
+			"accounts":    cfg.DNSAccounts,
 			"intervals":   cfg.Intervals,
 		},
 	})
@@ -580,7 +770,13 @@ func (s *Server) handleGetHistoryStats(c echo.Context) error {
 	})
 }
 
+// handleGetDNSProviders 返回所有已注册的 DNS 服务商及其账户属性定义
+func (s *Server) handleGetDNSProviders(c echo.Context) error {
+	return c.JSON(http.StatusOK, dns.Backends())
+}
+
 // handleTriggerDNSUpdate 手动触发 DNS 更新
+// 默认在后台执行；?wait=true 时等待同步完成并返回每条记录的结果
 func (s *Server) handleTriggerDNSUpdate(c echo.Context) error {
 	// 获取当前 IP
 	currentIP := s.GetCurrentIP()
@@ -595,15 +791,26 @@ func (s *Server) handleTriggerDNSUpdate(c echo.Context) error {
 		s.SetCurrentSource(source)
 	}
 
-	// 触发 DNS 更新
-	if s.DNSUpdater != nil {
-		go func() {
-			if err := s.DNSUpdater.UpdateIP(currentIP); err != nil {
-				fmt.Printf("DNS 更新失败: %v\n", err)
-			}
-		}()
+	if s.DNSUpdater == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "DNS 更新器未初始化"})
 	}
 
+	if c.QueryParam("wait") == "true" {
+		report, _ := s.DNSUpdater.UpdateIP(currentIP)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message": "DNS 更新已完成: " + report.Summary(),
+			"ip":      currentIP,
+			"report":  report,
+		})
+	}
+
+	// 触发 DNS 更新
+	go func() {
+		if _, err := s.DNSUpdater.UpdateIP(currentIP); err != nil {
+			fmt.Printf("DNS 更新失败: %v\n", err)
+		}
+	}()
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "DNS 更新已触发",
 		"ip":      currentIP,
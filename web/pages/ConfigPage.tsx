@@ -1,8 +1,8 @@
 import React, { useContext, useEffect, useState } from 'react';
 import { AppContext } from '../App';
 import { api } from '../services/api';
-import { Config, IpProvider, CloudflareAccount, Zone } from '../types';
-import { Save, Plus, Trash2, RefreshCw, Shield, Globe, Cloud, ChevronDown, ChevronUp, Settings, Check, Download } from 'lucide-react';
+import { Config, DynDNSDevice, IpProvider, CloudflareAccount, Zone, RecordConfig, ProviderTypeInfo, DNSProviderInfo, PropertySchema, ProviderTestResult, DNSPlan, ManagedRecord, OrphanPolicy, AccountVerification, DiscoveredRecord, AdoptCandidate, AdoptQuery } from '../types';
+import { Save, Plus, Trash2, RefreshCw, Shield, Globe, Cloud, ChevronDown, ChevronUp, Settings, Check, Download, Play, Router, Eye, X, AlertTriangle } from 'lucide-react';
 import { AuthModal } from '../App';
 import { motion, AnimatePresence } from 'framer-motion';
 
@@ -31,9 +31,95 @@ const StyledInput = (props: React.InputHTMLAttributes<HTMLInputElement>) => (
   />
 );
 
+// --- Schema-driven Fields ---
+// 根据后端 /api/providers/types 返回的属性定义渲染表单，新增提供者类型无需修改前端
+const SchemaFields: React.FC<{ info: { properties: PropertySchema[] }, properties: Record<string, string>, onChange: (key: string, val: string) => void }> = ({ info, properties, onChange }) => (
+  <div className="grid grid-cols-1 sm:grid-cols-2 gap-4">
+    {info.properties.map(prop => {
+      const value = properties[prop.name] ?? '';
+      const label = `${prop.label || prop.name}${prop.required ? ' *' : ''}`;
+      let input;
+      switch (prop.type) {
+        case 'select':
+          input = (
+            <select
+              value={value || prop.default || ''}
+              onChange={e => onChange(prop.name, e.target.value)}
+              className="w-full bg-surface-hover rounded-lg px-4 py-2.5 text-sm text-content focus:ring-1 focus:ring-primary outline-none cursor-pointer"
+            >
+              {(prop.options || []).map(opt => <option key={opt} value={opt}>{opt}</option>)}
+            </select>
+          );
+          break;
+        case 'bool':
+          input = (
+            <select
+              value={value || prop.default || 'false'}
+              onChange={e => onChange(prop.name, e.target.value)}
+              className="w-full bg-surface-hover rounded-lg px-4 py-2.5 text-sm text-content focus:ring-1 focus:ring-primary outline-none cursor-pointer"
+            >
+              <option value="true">true</option>
+              <option value="false">false</option>
+            </select>
+          );
+          break;
+        case 'text':
+          input = (
+            <textarea
+              value={value}
+              onChange={e => onChange(prop.name, e.target.value)}
+              placeholder={prop.default}
+              rows={4}
+              className="w-full bg-surface-hover rounded-lg px-4 py-2.5 text-sm text-content placeholder-muted focus:ring-1 focus:ring-primary outline-none transition-all font-mono resize-none"
+            />
+          );
+          break;
+        default:
+          input = (
+            <StyledInput
+              type={prop.secret ? 'password' : prop.type === 'number' ? 'number' : 'text'}
+              value={value}
+              onChange={e => onChange(prop.name, e.target.value)}
+              placeholder={prop.default}
+            />
+          );
+      }
+      return (
+        <div key={prop.name} className={prop.type === 'text' ? 'sm:col-span-2' : ''}>
+          <InputGroup label={label}>
+            {input}
+            {prop.help && <div className="text-xs text-muted mt-1">{prop.help}</div>}
+          </InputGroup>
+        </div>
+      );
+    })}
+  </div>
+);
+
+const defaultProperties = (info?: { properties: PropertySchema[] }): Record<string, string> => {
+  const props: Record<string, string> = {};
+  info?.properties.forEach(p => { if (p.default) props[p.name] = p.default; });
+  return props;
+};
+
 // --- Provider Form ---
-const ProviderItem: React.FC<{ provider: IpProvider, onChange: (p: IpProvider) => void, onRemove: () => void, isZh: boolean }> = ({ provider, onChange, onRemove, isZh }) => {
+const ProviderItem: React.FC<{ provider: IpProvider, providerTypes: ProviderTypeInfo[], onChange: (p: IpProvider) => void, onRemove: () => void, isZh: boolean }> = ({ provider, providerTypes, onChange, onRemove, isZh }) => {
   const [expanded, setExpanded] = useState(false); // Default collapsed for cleaner look
+  const [testing, setTesting] = useState(false);
+  const [testResult, setTestResult] = useState<ProviderTestResult | null>(null);
+  const typeInfo = providerTypes.find(t => t.type === provider.type);
+
+  const runTest = async () => {
+    setTesting(true);
+    setTestResult(null);
+    try {
+      setTestResult(await api.testProvider(provider));
+    } catch (e: any) {
+      setTestResult({ success: false, latency_ms: 0, error: e.message });
+    } finally {
+      setTesting(false);
+    }
+  };
 
   const updateProp = (key: string, val: string) => {
     onChange({ ...provider, properties: { ...provider.properties, [key]: val } });
@@ -41,12 +127,6 @@ const ProviderItem: React.FC<{ provider: IpProvider, onChange: (p: IpProvider) =
 
   const renderFields = () => {
     switch (provider.type) {
-      case 'stun':
-        return <InputGroup label="STUN Server"><StyledInput value={provider.properties.server || ''} onChange={e => updateProp('server', e.target.value)} placeholder="stun.l.google.com:19302" /></InputGroup>;
-      case 'http':
-        return <InputGroup label="URL"><StyledInput value={provider.properties.url || ''} onChange={e => updateProp('url', e.target.value)} placeholder="https://api.ipify.org" /></InputGroup>;
-      case 'interface':
-        return <InputGroup label={isZh ? "接口名称" : "Interface Name"}><StyledInput value={provider.properties.name || ''} onChange={e => updateProp('name', e.target.value)} placeholder="eth0" /></InputGroup>;
       case 'router_ssh':
         return (
           <div className="space-y-4">
@@ -185,7 +265,8 @@ const ProviderItem: React.FC<{ provider: IpProvider, onChange: (p: IpProvider) =
             </div>
           </div>
         );
-      default: return null;
+      default:
+        return typeInfo ? <SchemaFields info={typeInfo} properties={provider.properties} onChange={updateProp} /> : null;
     }
   };
 
@@ -230,22 +311,37 @@ const ProviderItem: React.FC<{ provider: IpProvider, onChange: (p: IpProvider) =
                 <select
                   value={provider.type}
                   onChange={e => {
-                    const newType = e.target.value as any;
-                    let defaultProps = {};
-                    if (newType === 'router_ssh') {
-                      defaultProps = { type: 'routeros', port: '22', user: 'admin', interface: 'wan' };
-                    }
-                    onChange({ ...provider, type: newType, properties: defaultProps });
+                    const newType = e.target.value;
+                    onChange({ ...provider, type: newType, properties: defaultProperties(providerTypes.find(t => t.type === newType)) });
                   }}
                   className="w-full bg-surface-hover rounded-lg px-4 py-2.5 text-sm text-content focus:ring-1 focus:ring-primary outline-none cursor-pointer border-none"
                 >
-                  <option value="stun">STUN Server ({isZh ? '推荐' : 'Recommended'})</option>
-                  <option value="router_ssh">Router SSH</option>
-                  <option value="http" disabled>HTTP API ({isZh ? '即将推出' : 'Coming Soon'})</option>
-                  <option value="interface" disabled>Network Interface ({isZh ? '即将推出' : 'Coming Soon'})</option>
+                  {!typeInfo && <option value={provider.type}>{provider.type}</option>}
+                  {providerTypes.map(t => (
+                    <option key={t.type} value={t.type}>{t.name}{t.type === 'stun' ? ` (${isZh ? '推荐' : 'Recommended'})` : ''}</option>
+                  ))}
                 </select>
+                {typeInfo?.description && <div className="text-xs text-muted mt-1">{typeInfo.description}</div>}
               </InputGroup>
               {renderFields()}
+              <div className="flex items-center gap-3">
+                <button
+                  onClick={runTest}
+                  disabled={testing}
+                  className="text-xs flex items-center gap-1 text-primary hover:text-primary/80 font-bold px-3 py-1.5 rounded bg-primary/10 hover:bg-primary/20 transition-colors disabled:opacity-50"
+                >
+                  {testing ? <RefreshCw size={12} className="animate-spin" /> : <Play size={12} />}
+                  {isZh ? '测试' : 'TEST'}
+                </button>
+                {testResult && (
+                  <span className={`text-xs font-mono ${testResult.success ? 'text-emerald-500' : 'text-red-500'}`}>
+                    {testResult.success ? `${testResult.ip} (${testResult.source}, ${testResult.latency_ms}ms)` : testResult.error}
+                  </span>
+                )}
+              </div>
+              {testResult?.trace && testResult.trace.length > 0 && (
+                <pre className="text-xs text-muted bg-surface-hover rounded-lg p-3 overflow-x-auto whitespace-pre-wrap">{testResult.trace.join('\n')}</pre>
+              )}
             </div>
           </motion.div>
         )}
@@ -254,8 +350,107 @@ const ProviderItem: React.FC<{ provider: IpProvider, onChange: (p: IpProvider) =
   );
 };
 
-// --- Cloudflare Form ---
-const AccountItem: React.FC<{ account: CloudflareAccount, onChange: (a: CloudflareAccount) => void, onRemove: () => void, isZh: boolean }> = ({ account, onChange, onRemove, isZh }) => {
+// --- DNS Account Form ---
+// AdoptPanel lists existing records of a zone that point at the current IP or match a name pattern,
+// and adds the selected ones to the zone config with their current settings
+const AdoptPanel: React.FC<{ account: CloudflareAccount, findCandidates: (q: AdoptQuery) => Promise<AdoptCandidate[]>, onAdopt: (zone: string, records: RecordConfig[]) => void, isZh: boolean }> = ({ account, findCandidates, onAdopt, isZh }) => {
+  const [zone, setZone] = useState(account.zones[0]?.zone_name || '');
+  const [pattern, setPattern] = useState('');
+  const [loading, setLoading] = useState(false);
+  const [error, setError] = useState('');
+  const [candidates, setCandidates] = useState<AdoptCandidate[] | null>(null);
+  const [selected, setSelected] = useState<string[]>([]);
+
+  const search = async () => {
+    setLoading(true);
+    setError('');
+    try {
+      const found = await findCandidates({ account: account.name, zone, pattern: pattern || undefined });
+      setCandidates(found);
+      setSelected(found.filter(c => !c.configured).map(c => c.id));
+    } catch (e: any) {
+      setError(e.message);
+      setCandidates(null);
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const adopt = () => {
+    // one record config per name, the first selected record wins
+    const records: RecordConfig[] = [];
+    candidates?.filter(c => selected.includes(c.id) && !c.configured).forEach(c => {
+      if (!records.some(r => r.name === c.record)) records.push(c.config);
+    });
+    onAdopt(zone, records);
+    setCandidates(null);
+  };
+
+  return (
+    <div className="bg-surface-hover/30 rounded-lg p-4 space-y-3">
+      <div className="text-xs font-bold text-muted uppercase">{isZh ? '接管已有记录' : 'Adopt Existing Records'}</div>
+      <div className="grid grid-cols-1 sm:grid-cols-[1fr_1fr_auto] gap-2">
+        <StyledInput value={zone} onChange={e => setZone(e.target.value)} placeholder="example.com" className="bg-surface" list={`adopt-zones-${account.name}`} />
+        <datalist id={`adopt-zones-${account.name}`}>
+          {account.zones.map(z => <option key={z.zone_name} value={z.zone_name} />)}
+        </datalist>
+        <StyledInput value={pattern} onChange={e => setPattern(e.target.value)} placeholder={isZh ? '名称模式，如 *.home（留空匹配当前 IP）' : 'Name pattern, e.g. *.home (empty = current IP)'} className="bg-surface" />
+        <button
+          onClick={search}
+          disabled={loading || !zone || !account.name}
+          className="text-xs flex items-center justify-center gap-1 text-primary hover:text-primary/80 font-bold px-3 py-2 rounded bg-primary/10 hover:bg-primary/20 transition-colors disabled:opacity-50"
+        >
+          {loading ? <RefreshCw size={12} className="animate-spin" /> : <Eye size={12} />}
+          {isZh ? '查找' : 'FIND'}
+        </button>
+      </div>
+      {error && <div className="text-xs text-red-500">{error}</div>}
+      {candidates && (
+        <div className="space-y-1 max-h-64 overflow-y-auto custom-scrollbar">
+          {candidates.length === 0 && <div className="text-xs text-muted italic">{isZh ? '没有匹配的记录' : 'No matching records'}</div>}
+          {candidates.map(c => (
+            <label key={c.id} className={`flex items-center gap-2 text-xs font-mono p-1.5 rounded hover:bg-surface-hover/50 ${c.configured ? 'opacity-50' : 'cursor-pointer'}`}>
+              <input
+                type="checkbox"
+                disabled={c.configured}
+                checked={c.configured || selected.includes(c.id)}
+                onChange={e => setSelected(e.target.checked ? [...selected, c.id] : selected.filter(id => id !== c.id))}
+                className="accent-primary"
+              />
+              <span className="text-content">{c.record}</span>
+              <span className="text-muted">{c.type} {c.content}</span>
+              {c.proxied && <span className="text-orange-500">proxied</span>}
+              {c.ttl ? <span className="text-muted">TTL {c.ttl === 1 ? 'Auto' : c.ttl}</span> : null}
+              {c.comment && <span className="text-muted truncate" title={c.comment}>"{c.comment}"</span>}
+              {c.owner && <span className="text-amber-500 flex items-center gap-1"><AlertTriangle size={12} /> {isZh ? `属于 ${c.owner}` : `owned by ${c.owner}`}</span>}
+              {c.configured && <span className="text-muted">{isZh ? '已配置' : 'configured'}</span>}
+            </label>
+          ))}
+          {candidates.some(c => !c.configured) && (
+            <button
+              onClick={adopt}
+              disabled={selected.length === 0}
+              className="mt-2 text-xs flex items-center gap-1 text-primary hover:text-primary/80 font-bold px-3 py-1.5 rounded bg-primary/10 hover:bg-primary/20 transition-colors disabled:opacity-50"
+            >
+              <Plus size={12} /> {isZh ? `接管 ${selected.length} 条记录` : `ADOPT ${selected.length} RECORDS`}
+            </button>
+          )}
+        </div>
+      )}
+    </div>
+  );
+};
+
+const AccountItem: React.FC<{ account: CloudflareAccount, dnsProviders: DNSProviderInfo[], onChange: (a: CloudflareAccount) => void, onRemove: () => void, findCandidates: (q: AdoptQuery) => Promise<AdoptCandidate[]>, isZh: boolean }> = ({ account, dnsProviders, onChange, onRemove, findCandidates, isZh }) => {
+  const provider = account.provider || 'cloudflare';
+  const providerInfo = dnsProviders.find(p => p.provider === provider);
+  // Cloudflare Global API Key authentication puts the key in api_token
+  const tokenLabel = provider === 'cloudflare' && account.properties?.auth_type === 'api_key' ? 'Global API Key' : providerInfo?.token_label || 'API Token';
+
+  const updateProp = (key: string, val: string) => {
+    onChange({ ...account, properties: { ...(account.properties || {}), [key]: val } });
+  };
+
   const addZone = () => {
     onChange({ ...account, zones: [...account.zones, { zone_name: '', records: [] }] });
   };
@@ -263,7 +458,9 @@ const AccountItem: React.FC<{ account: CloudflareAccount, onChange: (a: Cloudfla
   const updateZone = (idx: number, field: keyof Zone, val: any) => {
     const newZones = [...account.zones];
     if (field === 'records') {
-      newZones[idx] = { ...newZones[idx], records: val.split(',').map((s: string) => s.trim()) };
+      // keep per-record settings of records whose names are still listed
+      const existing = newZones[idx].records;
+      newZones[idx] = { ...newZones[idx], records: val.split(',').map((s: string) => s.trim()).map((name: string) => existing.find(r => recordName(r) === name) ?? name) };
     } else {
       newZones[idx] = { ...newZones[idx], [field]: val };
     }
@@ -275,6 +472,53 @@ const AccountItem: React.FC<{ account: CloudflareAccount, onChange: (a: Cloudfla
     onChange({ ...account, zones: newZones });
   };
 
+  const [verifying, setVerifying] = useState(false);
+  const [verification, setVerification] = useState<AccountVerification | null>(null);
+
+  const runVerify = async () => {
+    setVerifying(true);
+    try {
+      setVerification(await api.verifyDNSAccount(account));
+    } catch (e: any) {
+      setVerification({ provider, account: account.name, valid: false, error: e.message, discovered: false, zones: [], latency_ms: 0 });
+    } finally {
+      setVerifying(false);
+    }
+  };
+
+  const zoneIndex = (zoneName: string) => account.zones.findIndex(z => z.zone_name.toLowerCase() === zoneName.toLowerCase());
+  const isPicked = (zoneName: string, rec: DiscoveredRecord) => {
+    const idx = zoneIndex(zoneName);
+    return idx >= 0 && account.zones[idx].records.some(r => recordName(r) === rec.record);
+  };
+
+  // toggle a discovered record in the config, adding the zone when it is not configured yet
+  const toggleRecord = (zoneName: string, rec: DiscoveredRecord) => {
+    const idx = zoneIndex(zoneName);
+    if (idx < 0) {
+      onChange({ ...account, zones: [...account.zones, { zone_name: zoneName, records: [rec.record] }] });
+      return;
+    }
+    const zone = account.zones[idx];
+    const records = isPicked(zoneName, rec) ? zone.records.filter(r => recordName(r) !== rec.record) : [...zone.records, rec.record];
+    const newZones = [...account.zones];
+    newZones[idx] = { ...zone, records };
+    onChange({ ...account, zones: newZones });
+  };
+
+  // add adopted records to the zone, creating it when it is not configured yet
+  const adoptRecords = (zoneName: string, records: RecordConfig[]) => {
+    const idx = zoneIndex(zoneName);
+    if (idx < 0) {
+      onChange({ ...account, zones: [...account.zones, { zone_name: zoneName, records }] });
+      return;
+    }
+    const zone = account.zones[idx];
+    const newZones = [...account.zones];
+    newZones[idx] = { ...zone, records: [...zone.records, ...records.filter(r => !zone.records.some(e => recordName(e) === r.name))] };
+    onChange({ ...account, zones: newZones });
+  };
+
   return (
     <motion.div
       layout
@@ -284,19 +528,107 @@ const AccountItem: React.FC<{ account: CloudflareAccount, onChange: (a: Cloudfla
       className="bg-surface rounded-xl p-5 space-y-5 relative group hover:shadow-md transition-shadow"
     >
       <button onClick={onRemove} className="absolute top-4 right-4 text-muted hover:text-red-500 opacity-0 group-hover:opacity-100 transition-all p-2 rounded-full hover:bg-red-500/10"><Trash2 size={16} /></button>
-      <div className="grid grid-cols-1 md:grid-cols-2 gap-4">
+      <div className="grid grid-cols-1 md:grid-cols-3 gap-4">
         <InputGroup label={isZh ? "账户名称" : "Account Name"}>
           <StyledInput value={account.name} onChange={e => onChange({ ...account, name: e.target.value })} placeholder="My Account" />
         </InputGroup>
-        <InputGroup label="API Token">
+        <InputGroup label={isZh ? "服务商" : "Provider"}>
+          <select
+            value={provider}
+            onChange={e => onChange({ ...account, provider: e.target.value, properties: defaultProperties(dnsProviders.find(p => p.provider === e.target.value)) })}
+            className="w-full bg-surface-hover rounded-lg px-4 py-2.5 text-sm text-content focus:ring-1 focus:ring-primary outline-none cursor-pointer"
+          >
+            {dnsProviders.map(p => <option key={p.provider} value={p.provider}>{p.name}</option>)}
+            {!providerInfo && <option value={provider}>{provider}</option>}
+          </select>
+        </InputGroup>
+        <InputGroup label={tokenLabel}>
           <StyledInput
             type="password"
             value={account.api_token}
             onChange={e => onChange({ ...account, api_token: e.target.value })}
-            placeholder={isZh ? "输入 Cloudflare API Token" : "Enter Cloudflare API Token"}
+            placeholder={isZh ? `输入 ${tokenLabel}` : `Enter ${tokenLabel}`}
           />
         </InputGroup>
       </div>
+      {providerInfo && providerInfo.properties.length > 0 && (
+        <SchemaFields info={providerInfo} properties={account.properties || {}} onChange={updateProp} />
+      )}
+      <div className="grid grid-cols-1 md:grid-cols-3 gap-4">
+        <InputGroup label={isZh ? "并发 Zone 数" : "Zone Concurrency"}>
+          <StyledInput
+            type="number"
+            min={0}
+            max={16}
+            value={account.concurrency || ''}
+            onChange={e => onChange({ ...account, concurrency: parseInt(e.target.value) || undefined })}
+            placeholder={String(Math.min(4, providerInfo?.max_concurrency || 4))}
+          />
+        </InputGroup>
+      </div>
+
+      <div className="space-y-3">
+        <div className="flex items-center gap-3">
+          <button
+            onClick={runVerify}
+            disabled={verifying}
+            className="text-xs flex items-center gap-1 text-primary hover:text-primary/80 font-bold px-3 py-1.5 rounded bg-primary/10 hover:bg-primary/20 transition-colors disabled:opacity-50"
+          >
+            {verifying ? <RefreshCw size={12} className="animate-spin" /> : <Shield size={12} />}
+            {isZh ? '验证并发现记录' : 'VERIFY & DISCOVER'}
+          </button>
+          {verification && (
+            <span className={`text-xs font-mono ${verification.valid && !verification.error ? 'text-emerald-500' : 'text-red-500'}`}>
+              {verification.error || (isZh ? `凭据有效，${verification.zones.length} 个 zone (${verification.latency_ms}ms)` : `Credentials valid, ${verification.zones.length} zones (${verification.latency_ms}ms)`)}
+            </span>
+          )}
+        </div>
+        {verification && verification.zones.length > 0 && (
+          <div className="bg-surface-hover/30 rounded-lg p-4 space-y-3 max-h-80 overflow-y-auto custom-scrollbar">
+            <div className="text-xs text-muted">
+              {isZh ? '点击记录加入或移出配置' : 'Click a record to add it to or remove it from the config'}
+              {!verification.discovered && (isZh ? '（该服务商不支持列出 zone，只检查已配置的 zone）' : ' (this provider cannot list zones, only configured zones are checked)')}
+            </div>
+            {verification.zones.map(z => (
+              <div key={z.name} className="space-y-1.5">
+                <div className="flex items-center gap-2 text-sm font-mono">
+                  <span className="font-bold text-content">{z.name}</span>
+                  {z.status && z.status !== 'active' && <span className="text-xs text-amber-500">{z.status}</span>}
+                  {z.configured && <Check size={12} className="text-emerald-500" />}
+                  {z.missing && z.missing.length > 0 && (
+                    <span className="text-xs text-amber-500 flex items-center gap-1" title={z.error}>
+                      <AlertTriangle size={12} /> {isZh ? '缺少权限' : 'missing'} {z.missing.join(', ')}
+                    </span>
+                  )}
+                  {z.permissions_unknown && !(z.missing && z.missing.length > 0) && (
+                    <span
+                      className="text-xs text-muted flex items-center gap-1"
+                      title={isZh ? '无法读取 Token 的权限策略，请确认 Token 具有 Zone:DNS:Edit 权限' : 'Could not read the token policies; make sure the token has Zone:DNS:Edit'}
+                    >
+                      <AlertTriangle size={12} /> {isZh ? '权限未知' : 'permissions unknown'}
+                    </span>
+                  )}
+                  {z.error && !(z.missing && z.missing.length > 0) && <span className="text-xs text-red-500 truncate" title={z.error}>{z.error}</span>}
+                </div>
+                <div className="flex flex-wrap gap-1.5">
+                  {z.records.map(r => (
+                    <button
+                      key={`${r.name}-${r.type}-${r.content}`}
+                      onClick={() => toggleRecord(z.name, r)}
+                      disabled={z.editable === false}
+                      title={`${r.name} ${r.type} ${r.content}${r.proxied ? ' (proxied)' : ''}`}
+                      className={`text-xs font-mono px-2 py-1 rounded transition-colors disabled:opacity-50 disabled:cursor-not-allowed ${isPicked(z.name, r) ? 'bg-primary/20 text-primary' : 'bg-surface text-muted hover:text-content'}`}
+                    >
+                      {r.record} <span className="opacity-60">{r.type} {r.content}</span>
+                    </button>
+                  ))}
+                  {z.records.length === 0 && !z.error && <span className="text-xs text-muted italic">{isZh ? '没有 A/AAAA 记录' : 'No A/AAAA records'}</span>}
+                </div>
+              </div>
+            ))}
+          </div>
+        )}
+      </div>
 
       <div className="bg-surface-hover/30 rounded-lg p-4">
         <div className="flex justify-between items-center mb-3">
@@ -313,7 +645,7 @@ const AccountItem: React.FC<{ account: CloudflareAccount, onChange: (a: Cloudfla
                 exit={{ opacity: 0, height: 0 }}
                 className="flex gap-2 items-start"
               >
-                <div className="flex-1 grid grid-cols-1 sm:grid-cols-2 gap-2">
+                <div className="flex-1 grid grid-cols-1 sm:grid-cols-[1fr_1fr_auto_auto] gap-2">
                   <StyledInput
                     value={zone.zone_name}
                     onChange={e => updateZone(idx, 'zone_name', e.target.value)}
@@ -322,11 +654,25 @@ const AccountItem: React.FC<{ account: CloudflareAccount, onChange: (a: Cloudfla
                   />
                   <input
                     type="text"
-                    value={zone.records.join(', ')}
+                    value={zone.records.map(recordName).join(', ')}
                     onChange={e => updateZone(idx, 'records', e.target.value)}
                     placeholder="Records (e.g., @, www, vpn)"
                     className="w-full bg-surface rounded-lg px-4 py-2.5 text-sm text-content placeholder-muted focus:ring-1 focus:ring-primary outline-none transition-all font-mono"
                   />
+                  <input
+                    type="number"
+                    value={zone.ttl || ''}
+                    onChange={e => updateZone(idx, 'ttl', parseInt(e.target.value) || undefined)}
+                    placeholder="TTL"
+                    title={isZh ? 'zone 默认 TTL（秒），Cloudflare 中 1 表示 Auto' : 'Zone default TTL in seconds, 1 = Auto on Cloudflare'}
+                    className="w-24 bg-surface rounded-lg px-3 py-2.5 text-sm text-content placeholder-muted focus:ring-1 focus:ring-primary outline-none transition-all font-mono"
+                  />
+                  {(account.provider || 'cloudflare') === 'cloudflare' && (
+                    <label className="flex items-center gap-1 text-xs text-muted px-2 cursor-pointer" title={isZh ? 'zone 内记录默认经 Cloudflare 代理' : 'Proxy records in this zone through Cloudflare by default'}>
+                      <input type="checkbox" checked={!!zone.proxied} onChange={e => updateZone(idx, 'proxied', e.target.checked || undefined)} className="accent-primary" />
+                      Proxied
+                    </label>
+                  )}
                 </div>
                 <button onClick={() => removeZone(idx)} className="p-3 text-muted hover:text-red-500 mt-0"><Trash2 size={16} /></button>
               </motion.div>
@@ -335,22 +681,34 @@ const AccountItem: React.FC<{ account: CloudflareAccount, onChange: (a: Cloudfla
           {account.zones.length === 0 && <div className="text-xs text-muted text-center italic py-2">No zones configured</div>}
         </div>
       </div>
+
+      <AdoptPanel account={account} findCandidates={findCandidates} onAdopt={adoptRecords} isZh={isZh} />
     </motion.div>
   );
 };
 
+const recordName = (r: string | RecordConfig) => typeof r === 'string' ? r : r.name;
+
 // --- Main Page ---
 const ConfigPage = () => {
   const { lang, showToast, isAuthenticated, setAuthenticated } = useContext(AppContext);
   const isZh = lang === 'zh';
   const [config, setConfig] = useState<Config | null>(null);
+  const [providerTypes, setProviderTypes] = useState<ProviderTypeInfo[]>([]);
+  const [dnsProviders, setDNSProviders] = useState<DNSProviderInfo[]>([]);
   const [saving, setSaving] = useState(false);
+  const [plan, setPlan] = useState<DNSPlan | null>(null);
+  const [planning, setPlanning] = useState(false);
+  const [orphans, setOrphans] = useState<ManagedRecord[]>([]);
   const [showAuth, setShowAuth] = useState(!isAuthenticated);
 
   const loadConfig = async () => {
     try {
       const data = await api.getConfig();
       setConfig(data);
+      setProviderTypes(await api.getProviderTypes());
+      setDNSProviders(await api.getDNSProviders());
+      setOrphans(await api.getOrphans());
     } catch (e: any) {
       if (e.message === 'UNAUTHORIZED') {
         setAuthenticated(false);
@@ -389,6 +747,39 @@ const ConfigPage = () => {
     }
   };
 
+  const handlePlan = async () => {
+    if (!config) return;
+    setPlanning(true);
+    try {
+      setPlan(await api.planDNS(config));
+    } catch (e: any) {
+      showToast(e.message, 'error');
+    } finally {
+      setPlanning(false);
+    }
+  };
+
+  const handleDeleteOrphans = async (records: ManagedRecord[]) => {
+    const names = records.map(r => `${r.domain} (${r.record_type} ${r.content.join(', ')})`).join('\n');
+    if (!window.confirm((isZh ? '将从 DNS 服务商删除以下记录：\n' : 'Delete these records at the DNS provider?\n') + names)) return;
+    try {
+      const res = await api.deleteOrphans(records.map(r => r.id));
+      showToast(res.error || (isZh ? `已删除 ${res.deleted} 条记录` : `Deleted ${res.deleted} record(s)`), res.error ? 'error' : undefined);
+    } catch (e: any) {
+      showToast(e.message, 'error');
+    }
+    setOrphans(await api.getOrphans());
+  };
+
+  const handleForgetOrphans = async (records: ManagedRecord[]) => {
+    try {
+      await api.forgetOrphans(records.map(r => r.id));
+    } catch (e: any) {
+      showToast(e.message, 'error');
+    }
+    setOrphans(await api.getOrphans());
+  };
+
   if (showAuth) return <AuthModal onLogin={handleLogin} />;
 
   if (!config) return (
@@ -398,6 +789,10 @@ const ConfigPage = () => {
     </div>
   );
 
+  const dyndns = config.dyndns_server || { enabled: false, devices: [] };
+  const setDynDNS = (d: typeof dyndns) => setConfig({ ...config, dyndns_server: d });
+  const setDevice = (idx: number, d: DynDNSDevice) => { const devices = [...dyndns.devices]; devices[idx] = d; setDynDNS({ ...dyndns, devices }); };
+
   return (
     <div className="max-w-4xl mx-auto space-y-8 pb-20">
       <motion.div
@@ -409,6 +804,17 @@ const ConfigPage = () => {
           {isZh ? '系统配置' : 'Configuration'}
         </h2>
         <div className="flex gap-3 w-full sm:w-auto">
+          <motion.button
+            whileHover={{ scale: 1.05 }} whileTap={{ scale: 0.95 }}
+            onClick={handlePlan}
+            disabled={planning}
+            title={isZh ? '预览保存后将执行的 DNS 变更（不修改任何记录）' : 'Preview the DNS changes this config would make (read-only)'}
+            className="px-4 py-2 rounded-lg text-sm font-bold transition-all flex items-center gap-2 bg-surface text-content hover:bg-primary/10 shadow-sm"
+          >
+            {planning ? <RefreshCw className="animate-spin" size={16} /> : <Eye size={16} />}
+            {isZh ? '预览' : 'PLAN'}
+          </motion.button>
+
           {/* Global Apply Button */}
           <motion.button
             whileHover={{ scale: 1.05 }} whileTap={{ scale: 0.95 }}
@@ -438,6 +844,65 @@ const ConfigPage = () => {
         </div>
       </motion.div>
 
+      {plan && (
+        <motion.div
+          initial={{ opacity: 0, y: -10 }} animate={{ opacity: 1, y: 0 }}
+          className="bg-surface rounded-2xl p-6 shadow-sm"
+        >
+          <div className="flex justify-between items-center mb-4">
+            <h3 className="font-bold text-content">
+              {isZh ? `DNS 变更预览 (${plan.ip})` : `DNS Change Plan (${plan.ip})`}
+              <span className="ml-3 text-xs text-muted font-normal">
+                {Object.entries(plan.summary).map(([action, n]) => `${action}: ${n}`).join(' · ')}
+              </span>
+            </h3>
+            <button onClick={() => setPlan(null)} className="text-muted hover:text-content"><X size={16} /></button>
+          </div>
+          <div className="space-y-1 max-h-80 overflow-y-auto font-mono text-xs">
+            {plan.changes.length === 0 && <div className="text-muted italic">{isZh ? '没有配置任何记录' : 'No records configured'}</div>}
+            {plan.changes.map((ch, i) => (
+              <div key={i} className="flex gap-3 items-baseline py-1">
+                <span className={`w-16 font-bold uppercase ${ch.action === 'noop' || ch.action === 'orphan' ? 'text-muted' : ch.action === 'create' || ch.action === 'update' ? 'text-primary' : 'text-red-500'}`}>{ch.action}</span>
+                <span className="text-content">{ch.domain}</span>
+                <span className="text-muted">{ch.type} {ch.current || '∅'} → {ch.desired || '∅'}</span>
+                {ch.drift && ch.action === 'update' && <span className="text-muted">({ch.drift.join('; ')})</span>}
+                {ch.error && <span className="text-red-500">{ch.error}</span>}
+              </div>
+            ))}
+          </div>
+        </motion.div>
+      )}
+
+      {orphans.length > 0 && (
+        <motion.div
+          initial={{ opacity: 0, y: -10 }} animate={{ opacity: 1, y: 0 }}
+          className="bg-surface rounded-2xl p-6 shadow-sm"
+        >
+          <div className="flex justify-between items-center mb-4">
+            <h3 className="font-bold text-content">
+              {isZh ? `已移除的记录 (${orphans.length})` : `Removed Records (${orphans.length})`}
+              <span className="ml-3 text-xs text-muted font-normal">
+                {isZh ? '这些记录已从配置中移除，但仍指向 idrd 写入的地址' : 'No longer in the config but still pointing at addresses idrd wrote'}
+              </span>
+            </h3>
+            <div className="flex gap-2">
+              <button onClick={() => handleForgetOrphans(orphans)} className="text-xs font-bold px-2 py-1.5 rounded bg-surface-hover text-content hover:bg-primary/10">{isZh ? '全部保留' : 'KEEP ALL'}</button>
+              <button onClick={() => handleDeleteOrphans(orphans)} className="text-xs font-bold px-2 py-1.5 rounded bg-red-500/10 text-red-500 hover:bg-red-500/20">{isZh ? '全部删除' : 'DELETE ALL'}</button>
+            </div>
+          </div>
+          <div className="space-y-1 max-h-80 overflow-y-auto font-mono text-xs">
+            {orphans.map(r => (
+              <div key={r.id} className="flex gap-3 items-center py-1">
+                <span className="text-content">{r.domain}</span>
+                <span className="text-muted flex-1">{r.record_type} {r.content.join(', ')} · {r.account_name}</span>
+                <button onClick={() => handleForgetOrphans([r])} title={isZh ? '保留记录并停止跟踪' : 'Keep the record and stop tracking it'} className="text-muted hover:text-content"><X size={14} /></button>
+                <button onClick={() => handleDeleteOrphans([r])} title={isZh ? '从服务商删除' : 'Delete at the provider'} className="text-muted hover:text-red-500"><Trash2 size={14} /></button>
+              </div>
+            ))}
+          </div>
+        </motion.div>
+      )}
+
       {/* General Settings */}
       <motion.div
         initial={{ opacity: 0, y: 20 }} animate={{ opacity: 1, y: 0 }} transition={{ delay: 0.1 }}
@@ -483,9 +948,61 @@ const ConfigPage = () => {
               placeholder="1m, 5m, 10m"
             />
             <div className="text-xs text-muted mt-1">
-              {isZh ? '格式示例: 30s, 1m, 5m, 1h。仅在 IP 变化时执行' : 'Format: 30s, 1m, 5m, 1h. Only executes when IP changes'}
+              {isZh ? '格式示例: 30s, 1m, 5m, 1h。定期校准 DNS 记录，修复在服务商处被修改或删除的记录' : 'Format: 30s, 1m, 5m, 1h. Periodically reconciles DNS records and repairs ones changed or deleted at the provider'}
             </div>
           </InputGroup>
+          <InputGroup label={isZh ? "演练模式" : "Dry Run"}>
+            <label className="flex items-center gap-2 text-sm cursor-pointer py-2.5">
+              <input type="checkbox" checked={!!config.dry_run} onChange={e => setConfig({ ...config, dry_run: e.target.checked })} className="accent-primary" />
+              {isZh ? '只记录计划的变更，不修改 DNS' : 'Record planned changes without touching DNS'}
+            </label>
+          </InputGroup>
+          <InputGroup label={isZh ? "记录所有权" : "Record Ownership"}>
+            <label className="flex items-center gap-2 text-sm cursor-pointer py-2.5" title={isZh ? '用 Cloudflare 备注或 _idrd TXT 记录标记 idrd 创建的记录，不修改没有标记的记录（记录或 zone 设置 adopt 可接管）' : 'Mark records created by idrd with a Cloudflare comment or an _idrd TXT record and leave unmarked records alone (set adopt on a record or zone to take over)'}>
+              <input type="checkbox" checked={!!config.ownership?.enabled} onChange={e => setConfig({ ...config, ownership: { ...config.ownership, enabled: e.target.checked } })} className="accent-primary" />
+              {isZh ? '只修改 idrd 创建的记录' : 'Only modify records owned by idrd'}
+            </label>
+          </InputGroup>
+          <InputGroup label={isZh ? "已移除记录的处理" : "Removed Records"}>
+            <select
+              value={config.orphan_policy || 'ask'}
+              onChange={e => setConfig({ ...config, orphan_policy: e.target.value as OrphanPolicy })}
+              className="w-full bg-surface-hover rounded-lg px-4 py-2.5 text-sm text-content focus:ring-1 focus:ring-primary outline-none cursor-pointer"
+            >
+              <option value="ask">{isZh ? '确认后删除' : 'Ask before deleting'}</option>
+              <option value="delete">{isZh ? '自动删除' : 'Delete automatically'}</option>
+              <option value="keep">{isZh ? '保留（停止跟踪）' : 'Keep (stop tracking)'}</option>
+            </select>
+          </InputGroup>
+          {config.ownership?.enabled && (
+            <InputGroup label="Owner ID">
+              <StyledInput value={config.ownership.owner_id || ''} onChange={e => setConfig({ ...config, ownership: { enabled: true, owner_id: e.target.value } })} placeholder="idrd" />
+            </InputGroup>
+          )}
+          <InputGroup label={isZh ? "传播检查" : "Propagation Check"}>
+            <label className="flex items-center gap-2 text-sm cursor-pointer py-2.5" title={isZh ? '更新后查询 zone 的权威服务器，确认所有服务器都返回新地址，并提示仍缓存旧地址的公共解析器' : 'After an update, query the zone\'s authoritative nameservers until all return the new address, then report public resolvers still caching the old one'}>
+              <input type="checkbox" checked={config.propagation?.enabled ?? false} onChange={e => setConfig({ ...config, propagation: { ...config.propagation, enabled: e.target.checked } })} className="accent-primary" />
+              {isZh ? '更新后检查记录是否在权威服务器生效' : 'Verify updates on authoritative nameservers'}
+            </label>
+          </InputGroup>
+          {(config.propagation?.enabled ?? false) && (
+            <>
+              <InputGroup label={isZh ? "公共解析器" : "Public Resolvers"}>
+                <StyledInput
+                  value={(config.propagation?.resolvers || []).join(', ')}
+                  onChange={e => setConfig({ ...config, propagation: { enabled: true, ...config.propagation, resolvers: e.target.value.split(',').map(r => r.trim()).filter(Boolean) } })}
+                  placeholder="1.1.1.1, 8.8.8.8"
+                />
+              </InputGroup>
+              <InputGroup label={isZh ? "传播超时" : "Propagation Timeout"}>
+                <StyledInput
+                  value={config.propagation?.timeout || ''}
+                  onChange={e => setConfig({ ...config, propagation: { enabled: true, ...config.propagation, timeout: e.target.value } })}
+                  placeholder="5m"
+                />
+              </InputGroup>
+            </>
+          )}
         </div>
       </motion.div>
 
@@ -499,7 +1016,7 @@ const ConfigPage = () => {
             icon={Globe}
             title={isZh ? "IP 提供商" : "IP Providers"}
             action={
-              <button onClick={() => setConfig({ ...config, ip_providers: [...config.ip_providers, { type: 'stun', enabled: true, properties: {} }] })} className="text-primary hover:text-primary/80 text-xs font-bold flex items-center gap-1 bg-primary/10 px-2 py-1.5 rounded hover:bg-primary/20 transition-colors"><Plus size={12} /> {isZh ? "添加" : "ADD"}</button>
+              <button onClick={() => setConfig({ ...config, ip_providers: [...config.ip_providers, { type: 'stun', enabled: true, properties: defaultProperties(providerTypes.find(t => t.type === 'stun')) }] })} className="text-primary hover:text-primary/80 text-xs font-bold flex items-center gap-1 bg-primary/10 px-2 py-1.5 rounded hover:bg-primary/20 transition-colors"><Plus size={12} /> {isZh ? "添加" : "ADD"}</button>
             }
           />
           <div className="space-y-4">
@@ -508,6 +1025,7 @@ const ConfigPage = () => {
                 <ProviderItem
                   key={idx}
                   provider={p}
+                  providerTypes={providerTypes}
                   isZh={isZh}
                   onChange={newP => { const newArr = [...config.ip_providers]; newArr[idx] = newP; setConfig({ ...config, ip_providers: newArr }); }}
                   onRemove={() => { const newArr = config.ip_providers.filter((_, i) => i !== idx); setConfig({ ...config, ip_providers: newArr }); }}
@@ -524,9 +1042,9 @@ const ConfigPage = () => {
         >
           <SectionHeader
             icon={Cloud}
-            title="Cloudflare Accounts"
+            title={isZh ? "DNS 账户" : "DNS Accounts"}
             action={
-              <button onClick={() => setConfig({ ...config, cloudflare_accounts: [...config.cloudflare_accounts, { name: '', api_token: '', zones: [] }] })} className="text-primary hover:text-primary/80 text-xs font-bold flex items-center gap-1 bg-primary/10 px-2 py-1.5 rounded hover:bg-primary/20 transition-colors"><Plus size={12} /> {isZh ? "添加" : "ADD"}</button>
+              <button onClick={() => setConfig({ ...config, cloudflare_accounts: [...config.cloudflare_accounts, { name: '', provider: 'cloudflare', api_token: '', properties: {}, zones: [] }] })} className="text-primary hover:text-primary/80 text-xs font-bold flex items-center gap-1 bg-primary/10 px-2 py-1.5 rounded hover:bg-primary/20 transition-colors"><Plus size={12} /> {isZh ? "添加" : "ADD"}</button>
             }
           />
           <div className="space-y-6">
@@ -535,15 +1053,57 @@ const ConfigPage = () => {
                 <AccountItem
                   key={idx}
                   account={acc}
+                  dnsProviders={dnsProviders}
                   isZh={isZh}
                   onChange={newAcc => { const newArr = [...config.cloudflare_accounts]; newArr[idx] = newAcc; setConfig({ ...config, cloudflare_accounts: newArr }); }}
                   onRemove={() => { const newArr = config.cloudflare_accounts.filter((_, i) => i !== idx); setConfig({ ...config, cloudflare_accounts: newArr }); }}
+                  findCandidates={q => api.adoptCandidates(config, q)}
                 />
               ))}
             </AnimatePresence>
           </div>
         </motion.div>
       </div>
+
+      {/* DynDNS Server */}
+      <motion.div
+        initial={{ opacity: 0, y: 20 }} animate={{ opacity: 1, y: 0 }} transition={{ delay: 0.4 }}
+        className="bg-surface rounded-2xl p-6 shadow-sm"
+      >
+        <SectionHeader
+          icon={Router}
+          title={isZh ? "DynDNS 服务端" : "DynDNS Server"}
+          action={
+            <button onClick={() => setDynDNS({ ...dyndns, devices: [...dyndns.devices, { name: '', username: '', password: '', hostnames: [] }] })} className="text-primary hover:text-primary/80 text-xs font-bold flex items-center gap-1 bg-primary/10 px-2 py-1.5 rounded hover:bg-primary/20 transition-colors"><Plus size={12} /> {isZh ? "添加设备" : "ADD DEVICE"}</button>
+          }
+        />
+        <label className="flex items-center gap-2 text-sm mb-2 cursor-pointer">
+          <input type="checkbox" checked={dyndns.enabled} onChange={e => setDynDNS({ ...dyndns, enabled: e.target.checked })} className="accent-primary" />
+          {isZh ? "启用 /nic/update 接口" : "Enable /nic/update endpoint"}
+        </label>
+        <div className="text-xs text-muted mb-4">
+          {isZh ? '路由器使用 dyndns2 协议推送 IP，主机名留空表示允许所有已配置的记录' : 'Routers push their IP via the dyndns2 protocol. Leave hostnames empty to allow all configured records'}
+        </div>
+        <div className="space-y-3">
+          {dyndns.devices.map((d, idx) => (
+            <div key={idx} className="grid grid-cols-1 md:grid-cols-[1fr_1fr_1fr_2fr_auto] gap-3 items-end">
+              <InputGroup label={isZh ? "名称" : "Name"}>
+                <StyledInput value={d.name} onChange={e => setDevice(idx, { ...d, name: e.target.value })} />
+              </InputGroup>
+              <InputGroup label={isZh ? "用户名" : "Username"}>
+                <StyledInput value={d.username} onChange={e => setDevice(idx, { ...d, username: e.target.value })} />
+              </InputGroup>
+              <InputGroup label={isZh ? "密码" : "Password"}>
+                <StyledInput type="password" value={d.password} onChange={e => setDevice(idx, { ...d, password: e.target.value })} />
+              </InputGroup>
+              <InputGroup label={isZh ? "主机名" : "Hostnames"}>
+                <StyledInput value={(d.hostnames || []).join(', ')} onChange={e => setDevice(idx, { ...d, hostnames: e.target.value.split(',').map(s => s.trim()).filter(Boolean) })} placeholder="home.example.com, vpn.example.com" />
+              </InputGroup>
+              <button onClick={() => setDynDNS({ ...dyndns, devices: dyndns.devices.filter((_, i) => i !== idx) })} className="text-muted hover:text-red-500 p-2 mb-4"><Trash2 size={16} /></button>
+            </div>
+          ))}
+        </div>
+      </motion.div>
     </div>
   );
 };
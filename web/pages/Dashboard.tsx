@@ -1,7 +1,7 @@
 import React, { useEffect, useState, useContext, useMemo, useRef } from 'react';
 import { AppContext } from '../App';
 import { api } from '../services/api';
-import { StatusResponse, StatsResponse, EventLog } from '../types';
+import { StatusResponse, StatsResponse, EventLog, DNSConflict, DNSQuota } from '../types';
 import { Globe, Activity, Clock, Server, ArrowUpRight, Copy, AlertTriangle, Maximize2, X, CheckCircle, Info, AlertCircle, Timer, RefreshCw, Zap, Settings } from 'lucide-react';
 import { AreaChart, Area, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer } from 'recharts';
 import { motion, AnimatePresence } from 'framer-motion';
@@ -149,7 +149,7 @@ const DNSModal = ({ data, onClose }: { data: string[], onClose: () => void }) =>
   </motion.div>
 );
 
-const DNSDetailCard = ({ records, isZh }: { records: string[], isZh: boolean }) => (
+const DNSDetailCard = ({ records, conflicts = [], quota = [], isZh }: { records: string[], conflicts?: DNSConflict[], quota?: DNSQuota[], isZh: boolean }) => (
   <motion.div
     layout
     className="bg-surface rounded-2xl p-6 shadow-sm flex flex-col h-full hover:shadow-md transition-shadow"
@@ -167,12 +167,31 @@ const DNSDetailCard = ({ records, isZh }: { records: string[], isZh: boolean })
       ) : (
         records.map((r, i) => (
           <div key={i} className="flex items-center gap-2 p-2 rounded text-sm font-mono truncate hover:bg-surface-hover/50 transition-colors">
-            <div className={`w-1.5 h-1.5 rounded-full flex-shrink-0 ${r.includes('failed') ? 'bg-red-500' : 'bg-emerald-500'}`}></div>
+            <div className={`w-1.5 h-1.5 rounded-full flex-shrink-0 ${r.includes('failed') ? 'bg-red-500' : r.includes('stale') ? 'bg-amber-500' : r.includes('propagating') ? 'bg-sky-500 animate-pulse' : 'bg-emerald-500'}`}></div>
             <span className="truncate text-content/90">{r}</span>
           </div>
         ))
       )}
+      {conflicts.map((c, i) => (
+        <div key={`conflict-${i}`} className="flex items-center gap-2 p-2 rounded text-sm font-mono truncate text-amber-500" title={c.error}>
+          <AlertTriangle size={12} className="flex-shrink-0" />
+          <span className="truncate">{c.domain} ({c.type}) - {c.owner ? (isZh ? `属于 ${c.owner}` : `owned by ${c.owner}`) : (isZh ? '未被 idrd 管理' : 'not owned by idrd')}</span>
+        </div>
+      ))}
     </div>
+    {quota.length > 0 && (
+      <div className="mt-3 pt-3 space-y-1 text-xs text-muted font-mono">
+        {quota.map(q => (
+          <div key={`${q.provider}-${q.account}`} className={`flex justify-between ${q.throttled_until ? 'text-amber-500' : ''}`}>
+            <span className="truncate">{q.account} ({q.provider})</span>
+            <span>
+              {q.used}/{q.limit} {isZh ? `每 ${q.window_seconds / 60} 分钟` : `per ${q.window_seconds / 60} min`}
+              {q.throttled_until && (isZh ? ' - 已限流' : ' - throttled')}
+            </span>
+          </div>
+        ))}
+      </div>
+    )}
   </motion.div>
 );
 
@@ -220,7 +239,7 @@ const Dashboard = () => {
             time: f.time,
             type: 'error',
             category: 'DNS',
-            message: `DNS Update Failed: ${f.domain} (${f.error})`
+            message: `DNS Update Failed: ${f.domain} (${f.error_code ? `[${f.error_code}] ` : ''}${f.error})`
           });
         });
       }
@@ -296,6 +315,9 @@ const Dashboard = () => {
             if (msg.type === 'ip_change') {
               console.log('🔄 收到 IP 变化推送:', msg.data);
               fetchData(); // 立即刷新数据
+            } else if (msg.type === 'dns_update') {
+              console.log('🔄 收到 DNS 同步结果:', msg.data);
+              fetchData();
             }
           } catch (e) {
             console.warn('WebSocket 消息解析失败', e);
@@ -644,7 +666,7 @@ const Dashboard = () => {
           </div>
         </motion.div>
 
-        <DNSDetailCard records={status?.dns_status?.records || []} isZh={isZh} />
+        <DNSDetailCard records={status?.dns_status?.records || []} conflicts={status?.dns_status?.conflicts} quota={status?.dns_status?.quota} isZh={isZh} />
       </div>
 
       {/* Full Width Recent Log - Row 3 */}
@@ -1,4 +1,4 @@
-import { Config, StatusResponse, StatsResponse, EventLog } from '../types';
+import { Config, StatusResponse, StatsResponse, EventLog, ProviderTypeInfo, DNSProviderInfo, IpProvider, ProviderTestResult, DNSPlan, ManagedRecord, CloudflareAccount, AccountVerification, AdoptCandidate, AdoptQuery } from '../types';
 
 const API_BASE = '/api';
 
@@ -117,6 +117,29 @@ const mockConfig: Config = {
   ]
 };
 
+const mockDNSProviders: DNSProviderInfo[] = [
+  {
+    provider: 'cloudflare',
+    name: 'Cloudflare',
+    token_label: 'API Token',
+    properties: [
+      { name: 'auth_type', label: 'Auth Type', type: 'select', required: true, secret: false, default: 'api_token', options: ['api_token', 'api_key', 'account_token'] },
+      { name: 'email', label: 'Email', type: 'string', required: false, secret: false },
+      { name: 'account_id', label: 'Account ID', type: 'string', required: false, secret: false },
+    ]
+  },
+];
+
+const mockProviderTypes: ProviderTypeInfo[] = [
+  {
+    type: 'stun',
+    name: 'STUN Server',
+    properties: [
+      { name: 'server', label: 'STUN Server', type: 'string', required: true, secret: false, default: 'stun.l.google.com:19302' }
+    ]
+  }
+];
+
 // --- Helper for safe fetching ---
 async function fetchWithFallback<T>(url: string, mockData: T | (() => T), errorMessage: string): Promise<T> {
   try {
@@ -151,6 +174,100 @@ export const api = {
     );
   },
 
+  getProviderTypes: async (): Promise<ProviderTypeInfo[]> => {
+    return fetchWithFallback<ProviderTypeInfo[]>(
+      `${API_BASE}/providers/types`,
+      mockProviderTypes,
+      'Failed to fetch provider types'
+    );
+  },
+
+  getDNSProviders: async (): Promise<DNSProviderInfo[]> => {
+    return fetchWithFallback<DNSProviderInfo[]>(
+      `${API_BASE}/dns/providers`,
+      mockDNSProviders,
+      'Failed to fetch DNS providers'
+    );
+  },
+
+  planDNS: async (config?: Config, ip?: string): Promise<DNSPlan> => {
+    const res = await fetch(`${API_BASE}/dns/plan`, {
+      method: 'POST',
+      headers: getHeaders(),
+      body: JSON.stringify({ config, ip }),
+    });
+    if (res.status === 401) throw new Error('UNAUTHORIZED');
+    const data = await res.json();
+    if (!res.ok) throw new Error(data.error || 'Failed to plan DNS changes');
+    return data;
+  },
+
+  getOrphans: async (): Promise<ManagedRecord[]> => {
+    const res = await fetch(`${API_BASE}/dns/orphans`, { headers: getHeaders() });
+    if (res.status === 401) throw new Error('UNAUTHORIZED');
+    const data = await res.json();
+    if (!res.ok) throw new Error(data.error || 'Failed to fetch orphaned records');
+    return data.orphans;
+  },
+
+  deleteOrphans: async (ids: number[]): Promise<{ deleted: number; error?: string }> => {
+    const res = await fetch(`${API_BASE}/dns/orphans/delete`, {
+      method: 'POST',
+      headers: getHeaders(),
+      body: JSON.stringify({ ids }),
+    });
+    if (res.status === 401) throw new Error('UNAUTHORIZED');
+    const data = await res.json();
+    if (!res.ok) throw new Error(data.error || 'Failed to delete orphaned records');
+    return data;
+  },
+
+  forgetOrphans: async (ids: number[]): Promise<void> => {
+    const res = await fetch(`${API_BASE}/dns/orphans/forget`, {
+      method: 'POST',
+      headers: getHeaders(),
+      body: JSON.stringify({ ids }),
+    });
+    if (res.status === 401) throw new Error('UNAUTHORIZED');
+    if (!res.ok) throw new Error((await res.json()).error || 'Failed to forget orphaned records');
+  },
+
+  testProvider: async (provider: IpProvider): Promise<ProviderTestResult> => {
+    const res = await fetch(`${API_BASE}/providers/test`, {
+      method: 'POST',
+      headers: getHeaders(),
+      body: JSON.stringify({ ...provider, enabled: true }),
+    });
+    if (res.status === 401) throw new Error('UNAUTHORIZED');
+    const data = await res.json();
+    if (!res.ok) throw new Error(data.error || 'Failed to test provider');
+    return data;
+  },
+
+  verifyDNSAccount: async (account: CloudflareAccount): Promise<AccountVerification> => {
+    const res = await fetch(`${API_BASE}/dns/accounts/verify`, {
+      method: 'POST',
+      headers: getHeaders(),
+      body: JSON.stringify(account),
+    });
+    if (res.status === 401) throw new Error('UNAUTHORIZED');
+    const data = await res.json();
+    if (!res.ok) throw new Error(data.error || 'Failed to verify account');
+    return data;
+  },
+
+  adoptCandidates: async (config: Config, query: AdoptQuery): Promise<AdoptCandidate[]> => {
+    const res = await fetch(`${API_BASE}/dns/adopt/candidates`, {
+      method: 'POST',
+      headers: getHeaders(),
+      body: JSON.stringify({ ...query, config }),
+    });
+    if (res.status === 401) throw new Error('UNAUTHORIZED');
+    const data = await res.json();
+    if (!res.ok) throw new Error(data.error || 'Failed to list existing records');
+    return data.candidates;
+  },
+
   getConfig: async (): Promise<Config> => {
     try {
       const res = await fetch(`${API_BASE}/config`, {
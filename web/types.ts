@@ -7,6 +7,10 @@ export interface StatusResponse {
   dns_status: {
     synced: boolean;
     records: string[];
+    dry_run?: boolean;
+    conflicts?: DNSConflict[];
+    quota?: DNSQuota[];
+    propagation?: DNSPropagation[];
   };
   // Extended fields from actual API
   uptime_seconds?: number;
@@ -21,6 +25,24 @@ export interface StatusResponse {
     dns?: CheckStats;
   };
   recent_checks?: CheckLog[];
+  nat?: NATStatus;
+}
+
+export interface NATStatus {
+  cgnat: boolean;
+  symmetric?: boolean;
+  behavior?: {
+    server: string;
+    local_addr: string;
+    mapped_addr: string;
+    other_addr?: string;
+    mapping_behavior: string;
+    filtering_behavior: string;
+    symmetric: boolean;
+    cgnat: boolean;
+    error?: string;
+    checked_at: string;
+  };
 }
 
 export interface CheckStats {
@@ -56,9 +78,121 @@ export interface DNSUpdate {
   ip: string;
   success: boolean;
   error?: string;
+  error_code?: string; // provider error code, e.g. DNSPod "RequestLimitExceeded"
+  action?: DNSAction;
+  dry_run?: boolean; // planned in dry-run mode, not applied
+  old_content?: string; // content before the change
+  duration_ms?: number;
+  attempts?: number; // provider calls including retries
   timestamp: string;
 }
 
+// result of a single record in a DNS sync, pushed as "dns_update" over WebSocket
+export interface DNSRecordReport {
+  account: string;
+  zone: string;
+  domain: string;
+  type: string;
+  action: DNSAction;
+  old?: string;
+  new: string;
+  duration_ms: number;
+  attempts: number;
+  error?: string;
+  error_code?: string;
+  error_class?: 'auth' | 'not_found' | 'validation' | 'rate_limit' | 'transient';
+}
+
+// API requests made by this instance within the provider's rate-limit window
+export interface DNSQuota {
+  provider: string;
+  account: string;
+  limit: number;
+  window_seconds: number;
+  used: number;
+  remaining: number;
+  throttled: number; // 429 responses received
+  throttled_until?: string; // paused until Retry-After expires
+}
+
+// Answer from one authoritative nameserver or public resolver
+export interface DNSServerAnswer {
+  server: string;
+  answer: string[];
+  ttl?: number; // remaining cache TTL (resolvers)
+  ok: boolean; // answer matches the expected content
+  error?: string;
+}
+
+// Propagation check started after a record was created or updated
+export interface DNSPropagation {
+  account: string;
+  domain: string;
+  type: string;
+  expected: string[];
+  status: 'pending' | 'propagated' | 'stale';
+  servers: DNSServerAnswer[];
+  resolvers?: DNSServerAnswer[];
+  started_at: string;
+  duration_ms?: number; // time until all authoritative servers agreed
+  error?: string;
+}
+
+export interface DNSReport {
+  ip: string;
+  dry_run?: boolean;
+  reconcile?: boolean;
+  started_at: string;
+  duration_ms: number;
+  total: number;
+  changed: number;
+  failed: number;
+  records: DNSRecordReport[];
+}
+
+export interface DNSConflict {
+  account: string;
+  domain: string;
+  type: string;
+  owner?: string; // owner id of another idrd instance, empty when the record has no marker
+  error: string;
+  since: string;
+}
+
+export type DNSAction = 'create' | 'update' | 'noop' | 'conflict' | 'error' | 'delete' | 'orphan';
+
+export interface PlannedChange {
+  account: string;
+  zone: string;
+  domain: string;
+  type: string;
+  action: DNSAction;
+  current?: string;
+  desired: string;
+  drift?: string[];
+  error?: string;
+}
+
+// A record idrd created or managed that is no longer in the config
+export interface ManagedRecord {
+  id: number;
+  account_name: string;
+  zone: string;
+  domain: string;
+  record_type: string;
+  record_ids: string[];
+  content: string[];
+  updated_at: string;
+}
+
+export type OrphanPolicy = 'ask' | 'delete' | 'keep';
+
+export interface DNSPlan {
+  ip: string;
+  changes: PlannedChange[];
+  summary: Partial<Record<DNSAction, number>>;
+}
+
 export interface ErrorLog {
   level: string;
   message: string;
@@ -103,23 +237,163 @@ export interface Config {
   };
   ip_providers: IpProvider[];
   cloudflare_accounts: CloudflareAccount[];
+  dyndns_server?: DynDNSServerConfig;
+  dry_run?: boolean;
+  ownership?: { enabled: boolean; owner_id?: string };
+  orphan_policy?: OrphanPolicy;
+  propagation?: PropagationConfig;
+}
+
+export interface PropagationConfig {
+  enabled: boolean;
+  resolvers?: string[]; // public resolvers checked for cached answers, default 1.1.1.1 and 8.8.8.8
+  timeout?: string; // e.g. "5m"
+}
+
+export interface DynDNSDevice {
+  name: string;
+  username: string;
+  password: string;
+  hostnames: string[];
+}
+
+export interface DynDNSServerConfig {
+  enabled: boolean;
+  devices: DynDNSDevice[];
 }
 
 export interface IpProvider {
-  type: 'stun' | 'router_ssh' | 'http' | 'interface';
+  type: string; // registered provider type, see ProviderTypeInfo
   enabled: boolean;
   properties: Record<string, string>;
 }
 
+export interface PropertySchema {
+  name: string;
+  label: string;
+  type: 'string' | 'number' | 'bool' | 'select' | 'text';
+  required: boolean;
+  secret: boolean;
+  default?: string;
+  options?: string[];
+  help?: string;
+}
+
+export interface ProviderTestResult {
+  success: boolean;
+  ip?: string;
+  source?: string;
+  latency_ms: number;
+  error?: string;
+  trace?: string[];
+}
+
+// Existing A/AAAA record found while verifying an account
+export interface DiscoveredRecord {
+  name: string; // FQDN
+  record: string; // name relative to the zone, @ for the apex
+  type: 'A' | 'AAAA';
+  content: string;
+  ttl?: number;
+  proxied?: boolean;
+}
+
+export interface ZoneAccess {
+  name: string;
+  status?: string; // provider zone status, e.g. active / pending
+  editable?: boolean; // unset when the provider does not report permissions
+  missing?: string[]; // missing permissions, e.g. Zone:DNS:Edit
+  permissions_unknown?: boolean; // the provider checks permissions but could not determine them for this zone
+  configured?: boolean; // zone is already in the account config
+  records: DiscoveredRecord[];
+  error?: string;
+}
+
+// Result of POST /api/dns/accounts/verify
+export interface AccountVerification {
+  provider: string;
+  account: string;
+  valid: boolean;
+  error?: string;
+  error_class?: string;
+  discovered: boolean; // zones listed by the provider, otherwise only configured zones are checked
+  zones: ZoneAccess[];
+  latency_ms: number;
+}
+
+// Existing record that can be adopted into the config (POST /api/dns/adopt/candidates)
+export interface AdoptCandidate {
+  id: string;
+  domain: string;
+  record: string; // name relative to the zone, @ for the apex
+  type: 'A' | 'AAAA';
+  content: string;
+  ttl?: number;
+  proxied?: boolean;
+  comment?: string; // without idrd ownership tags
+  tags?: string[];
+  matches_ip: boolean;
+  configured: boolean; // name already in the zone config
+  owner?: string; // another idrd instance owning the record
+  config: RecordConfig; // record config keeping the existing proxied/TTL/comment/tags
+}
+
+export interface AdoptQuery {
+  account: string;
+  zone: string;
+  ip?: string; // defaults to the current IP when pattern is empty as well
+  pattern?: string; // glob on record names, e.g. *.home
+}
+
+export interface ProviderTypeInfo {
+  type: string;
+  name: string;
+  description?: string;
+  properties: PropertySchema[];
+}
+
+export interface DNSProviderInfo {
+  provider: string;
+  name: string;
+  token_label?: string;
+  properties: PropertySchema[];
+  max_concurrency?: number; // provider rate limit on zones synced in parallel
+}
+
 export interface CloudflareAccount {
   name: string;
+  provider?: string; // registered DNS provider, defaults to cloudflare
   api_token: string;
+  properties?: Record<string, string>;
   zones: Zone[];
+  concurrency?: number; // zones synced in parallel, 0/unset = default (4), capped by the provider
+}
+
+export interface RecordConfig {
+  name: string;
+  proxied?: boolean;
+  ttl?: number;
+  comment?: string;
+  tags?: string[];
+  adopt?: boolean;
+  select?: RecordSelector;
+  rrset?: boolean;
+}
+
+export interface RecordSelector {
+  id?: string;
+  comment?: string;
+  tag?: string;
 }
 
 export interface Zone {
   zone_name: string;
-  records: string[]; // API expects string array, UI handles comma-separated
+  records: (string | RecordConfig)[]; // plain names or objects with per-record settings, UI handles comma-separated names
+  proxied?: boolean;
+  ttl?: number;
+  comment?: string;
+  tags?: string[];
+  adopt?: boolean;
 }
 
 // UI Types